@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config is everything that can be set in the config file. Flags are bound to
+// the same fields, so after loading the file the command line is parsed again
+// to let flags override it.
+type Config struct {
+	Global          GlobalConfig          `json:"global"`
+	Daemon          DaemonConfig          `json:"daemon"`
+	Lock            LockConfig            `json:"lock"`
+	Overlay         OverlayConfig         `json:"overlay"`
+	Lightning       LightningConfig       `json:"lightning"`
+	Matrix          MatrixConfig          `json:"matrix"`
+	MissileDefender MissileDefenderConfig `json:"missiledefender"`
+	Replay          ReplayConfig          `json:"replay"`
+	Snake           SnakeConfig           `json:"snake"`
+	Snowflakes      SnowflakesConfig      `json:"snowflakes"`
+	TowerDefense    TowerDefenseConfig    `json:"towerdefense"`
+	WaterRipple     WaterRippleConfig     `json:"waterripple"`
+
+	path string   // File the config was loaded from, for watching
+	args []string // Command line it was parsed from, for reloading
+	lock bool     // Running as "termsaver lock"
+}
+
+// GlobalConfig holds the settings that apply to every mode.
+type GlobalConfig struct {
+	Mode        string  `json:"mode"`
+	Interactive bool    `json:"interactive"`
+	Theme       string  `json:"theme"`
+	Grayscale   bool    `json:"grayscale"` // Same as the grayscale theme
+	Seed        int64   `json:"seed"`      // 0 picks one at startup
+	FPS         int     `json:"fps"`
+	Speed       float64 `json:"speed"`
+	WatchConfig bool    `json:"watch_config"` // Reload when the config file changes
+
+	Playlist Playlist `json:"playlist"` // Empty = every mode, advanced with space
+	Shuffle  bool     `json:"shuffle"`
+
+	Transition     string  `json:"transition"`      // Effect between modes
+	TransitionTime float64 `json:"transition_time"` // Seconds
+
+	Record string `json:"-"` // Command line only: asciicast file to record to
+
+	// Command line only: render off-screen to an image file and exit
+	Snapshot       string        `json:"-"`               // .png or .gif
+	SnapshotAfter  time.Duration `json:"-"`               // Simulated time before the picture is taken
+	SnapshotSize   string        `json:"-"`               // Screen size in cells, as WxH
+	SnapshotFrames int           `json:"snapshot_frames"` // Frames in a GIF, from -snapshot or the S key
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Global: GlobalConfig{
+			Mode:  "random",
+			Theme: "default",
+			FPS:   defaultFPS,
+			Speed: 1,
+
+			Transition:     "dissolve",
+			TransitionTime: 1,
+
+			SnapshotAfter:  10 * time.Second,
+			SnapshotSize:   "80x24",
+			SnapshotFrames: 30,
+		},
+		Daemon: DaemonConfig{Idle: 300},
+		Overlay: OverlayConfig{
+			DateFormat: "Monday, January 2 2006",
+			Position:   "center",
+			Color:      "accent",
+			TextColor:  "text",
+		},
+		Lightning:       LightningConfig{MaxClouds: 3, MaxLightnings: 2},
+		Matrix:          MatrixConfig{MinSpeed: 1, MaxSpeed: 2},
+		MissileDefender: MissileDefenderConfig{SpawnInterval: 2.5, MaxMissiles: 8, Ammo: 10, Cities: 6},
+		Replay:          ReplayConfig{Fit: "crop", Loop: true},
+		Snake:           SnakeConfig{Size: 0, Scale: 2},
+		Snowflakes:      SnowflakesConfig{WindChangeTime: 3.0, WindStrength: 0.8},
+		TowerDefense:    TowerDefenseConfig{Lives: 20, WavePause: 3, Gold: 150},
+		WaterRipple:     WaterRippleConfig{MaxRipples: 6},
+	}
+}
+
+// bindFlags defines the command line flags that override config settings.
+func (c *Config) bindFlags(flags *flag.FlagSet) {
+	flags.StringVar(&c.Global.Mode, "mode", c.Global.Mode, fmt.Sprintf("Visualization mode: %s, or random", strings.Join(modeNames(), ", ")))
+	flags.BoolVar(&c.Global.Interactive, "interactive", c.Global.Interactive, "Enable interactive mode (for snake: use arrow keys to play; for towerdefense: build towers with the arrow keys or mouse; for missiledefender: aim and fire with the arrow keys or mouse)")
+	flags.StringVar(&c.Global.Theme, "theme", c.Global.Theme, fmt.Sprintf("Color theme: %s", strings.Join(themeNames(), ", ")))
+	flags.BoolVar(&c.Global.Grayscale, "grayscale", c.Global.Grayscale, "Use grayscale colors instead of colors (same as -theme grayscale)")
+	flags.Int64Var(&c.Global.Seed, "seed", c.Global.Seed, "Random seed (0 = pick one); the seed in use is printed on exit so a run can be replayed")
+	flags.IntVar(&c.Global.FPS, "fps", c.Global.FPS, "Frames drawn per second (1-120); doesn't change animation speed")
+	flags.Float64Var(&c.Global.Speed, "speed", c.Global.Speed, "Animation speed multiplier (0.1-10); adjust live with + and -")
+	flags.Var(&c.Global.Playlist, "playlist", `Modes to rotate through as "name[:duration[:weight]],...", e.g. "matrix:2m,lightning:90s,snake:5m"`)
+	flags.BoolVar(&c.Global.Shuffle, "shuffle", c.Global.Shuffle, "Play the playlist in random order, picking modes by weight")
+	flags.StringVar(&c.Global.Transition, "transition", c.Global.Transition, fmt.Sprintf("Effect when moving to the next mode: %s", strings.Join(transitionNames(), ", ")))
+	flags.Float64Var(&c.Global.TransitionTime, "transition-time", c.Global.TransitionTime, "Length of the transition between modes in seconds")
+	flags.StringVar(&c.Global.Record, "record", c.Global.Record, "Record the session to an asciicast v2 file (e.g. out.cast) for asciinema")
+	flags.StringVar(&c.Global.Snapshot, "snapshot", c.Global.Snapshot, "Render the mode off-screen to an image (out.png, or out.gif for an animation) and exit")
+	flags.DurationVar(&c.Global.SnapshotAfter, "snapshot-after", c.Global.SnapshotAfter, "How long the mode runs before -snapshot takes the picture")
+	flags.StringVar(&c.Global.SnapshotSize, "snapshot-size", c.Global.SnapshotSize, "Screen size in cells for -snapshot, as WxH")
+	flags.IntVar(&c.Global.SnapshotFrames, "snapshot-frames", c.Global.SnapshotFrames, "Frames in an animated GIF snapshot (-snapshot or the S key)")
+	flags.BoolVar(&c.Global.WatchConfig, "watch-config", c.Global.WatchConfig, "Reload the config file when it changes (it is always reloaded on SIGHUP)")
+	flags.Float64Var(&c.Daemon.Idle, "idle", c.Daemon.Idle, "Seconds without input or output before termsaver daemon starts the screensaver, or before tmux or screen locks with lock-config")
+	flags.StringVar(&c.Daemon.Shell, "shell", c.Daemon.Shell, "Program termsaver daemon runs (default $SHELL)")
+	flags.StringVar(&c.Lock.Password, "lock-password", c.Lock.Password, "bcrypt hash of the password that unlocks termsaver lock, from termsaver hash-password (better kept in the config file)")
+	flags.BoolVar(&c.Overlay.Clock, "clock", c.Overlay.Clock, "Show a large clock on top of the mode")
+	flags.BoolVar(&c.Overlay.Date, "date", c.Overlay.Date, "Show the date on top of the mode")
+	flags.BoolVar(&c.Overlay.Hostname, "hostname", c.Overlay.Hostname, "Show the hostname on top of the mode")
+	flags.BoolVar(&c.Overlay.Load, "load", c.Overlay.Load, "Show the load averages on top of the mode (Linux)")
+	flags.StringVar(&c.Overlay.Message, "message", c.Overlay.Message, "Show a message on top of the mode")
+	flags.StringVar(&c.Overlay.Position, "overlay-position", c.Overlay.Position, fmt.Sprintf("Where the clock, date and message go: %s", strings.Join(overlayPositions, ", ")))
+	flags.Float64Var(&c.Snowflakes.WindChangeTime, "wind-change-time", c.Snowflakes.WindChangeTime, "Time in seconds between wind direction changes (snowflakes mode)")
+	flags.Float64Var(&c.Snowflakes.WindStrength, "wind-strength", c.Snowflakes.WindStrength, "Baseline wind strength (-1.0 to 1.0, snowflakes mode)")
+	flags.StringVar(&c.Replay.File, "replay-file", c.Replay.File, "Recording to play in replay mode (asciicast v2, e.g. from -record)")
+	flags.StringVar(&c.Replay.Fit, "replay-fit", c.Replay.Fit, `How replay mode fits the recording to the terminal: "crop" or "scale"`)
+	flags.BoolVar(&c.Replay.Loop, "replay-loop", c.Replay.Loop, "Start the recording over when it ends (replay mode)")
+	flags.IntVar(&c.Snake.Size, "snake-size", c.Snake.Size, "Snake game grid size (0 = auto based on terminal, max 50)")
+	flags.IntVar(&c.Snake.Scale, "snake-scale", c.Snake.Scale, "Snake cell scale factor (1-4, affects how large each cell appears)")
+}
+
+// parseConfig builds the effective config from the defaults, the config file
+// and the command line, in increasing order of precedence. It also reports
+// whether -print-config was given.
+func parseConfig(flags *flag.FlagSet, args []string) (*Config, bool, error) {
+	cfg := defaultConfig()
+	configPath := flags.String("config", defaultConfigPath(), "Path to the JSON config file; flags override settings from it")
+	printConfig := flags.Bool("print-config", false, "Print the effective configuration and exit")
+	cfg.bindFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	// Load the config file, then parse the flags again so the ones given on
+	// the command line win over the file
+	configSet := false
+	flags.Visit(func(f *flag.Flag) {
+		configSet = configSet || f.Name == "config"
+	})
+	if err := cfg.loadFile(*configPath, configSet); err != nil {
+		return nil, false, err
+	}
+	cfg.path = *configPath
+	cfg.args = args
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, false, err
+	}
+	return cfg, *printConfig, nil
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/termsaver/config.json, falling
+// back to ~/.config when XDG_CONFIG_HOME isn't set.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "termsaver", "config.json")
+}
+
+// loadFile reads the JSON config file at path over the current settings.
+// Settings missing from the file are left alone, and an empty file has none.
+// A missing file is only an error if required is set.
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading config: %v", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("Error parsing config %s: %v", path, err)
+	}
+	return nil
+}
+
+// validate checks settings that would otherwise fail once the screen has
+// been taken over.
+func (c *Config) validate() error {
+	g := c.Global
+	if _, ok := lookupMode(g.Mode); !ok && g.Mode != "random" {
+		return fmt.Errorf("Unknown mode: %s. Use: %s, or random", g.Mode, strings.Join(modeNames(), ", "))
+	}
+	if len(g.Playlist) > 0 && g.Mode != "random" && !g.Playlist.contains(g.Mode) {
+		return fmt.Errorf("Mode %s is not in the playlist", g.Mode)
+	}
+	if _, ok := themeSpecs[g.Theme]; !ok {
+		return fmt.Errorf("Unknown theme: %s. Use: %s", g.Theme, strings.Join(themeNames(), ", "))
+	}
+	if g.FPS < 1 || g.FPS > 120 {
+		return fmt.Errorf("Invalid fps %d: must be between 1 and 120", g.FPS)
+	}
+	if min, max := speeds[0], speeds[len(speeds)-1]; g.Speed < min || g.Speed > max {
+		return fmt.Errorf("Invalid speed %g: must be between %g and %g", g.Speed, min, max)
+	}
+	if _, ok := transitions[g.Transition]; !ok && g.Transition != "random" {
+		return fmt.Errorf("Unknown transition: %s. Use: %s", g.Transition, strings.Join(transitionNames(), ", "))
+	}
+	if g.TransitionTime < 0 {
+		return fmt.Errorf("Invalid transition time %g: must not be negative", g.TransitionTime)
+	}
+	if g.Snapshot != "" {
+		if ext := strings.ToLower(filepath.Ext(g.Snapshot)); ext != ".png" && ext != ".gif" {
+			return fmt.Errorf("Invalid snapshot file %s: must end in .png or .gif", g.Snapshot)
+		}
+		if _, _, err := parseSize(g.SnapshotSize); err != nil {
+			return err
+		}
+		if g.SnapshotAfter < 0 {
+			return fmt.Errorf("Invalid snapshot-after %v: must not be negative", g.SnapshotAfter)
+		}
+	}
+	if g.SnapshotFrames < 1 || g.SnapshotFrames > 1000 {
+		return fmt.Errorf("Invalid snapshot frames %d: must be between 1 and 1000", g.SnapshotFrames)
+	}
+	if c.Daemon.Idle < 1 {
+		return fmt.Errorf("Invalid daemon idle time %g: must be at least 1 second", c.Daemon.Idle)
+	}
+	if p := c.Lock.Password; p != "" {
+		if _, err := bcrypt.Cost([]byte(p)); err != nil {
+			return fmt.Errorf("Invalid lock password: want a bcrypt hash, from termsaver hash-password")
+		}
+	}
+	if err := c.Overlay.validate(); err != nil {
+		return err
+	}
+	if m := c.Matrix; m.MinSpeed < 1 || m.MaxSpeed < m.MinSpeed {
+		return fmt.Errorf("Invalid matrix speeds %d-%d: need 1 <= min_speed <= max_speed", m.MinSpeed, m.MaxSpeed)
+	}
+	if r := c.Replay; r.Fit != "crop" && r.Fit != "scale" {
+		return fmt.Errorf("Invalid replay fit %q: use crop or scale", r.Fit)
+	}
+	if (g.Mode == "replay" || g.Playlist.contains("replay")) && c.Replay.File == "" {
+		return fmt.Errorf("Mode replay needs a recording: set -replay-file or file in the replay section")
+	}
+	if c.MissileDefender.SpawnInterval <= 0 {
+		return fmt.Errorf("Invalid missiledefender spawn_interval %g: must be positive", c.MissileDefender.SpawnInterval)
+	}
+	if m := c.MissileDefender; m.Ammo < 1 || m.Cities < 1 {
+		return fmt.Errorf("Invalid missiledefender settings: need ammo >= 1 and cities >= 1")
+	}
+	if t := c.TowerDefense; t.Lives < 1 || t.WavePause < 0 || t.Gold < 0 {
+		return fmt.Errorf("Invalid towerdefense settings: need lives >= 1, wave_pause >= 0 and gold >= 0")
+	}
+	return nil
+}
+
+// themeName returns the theme to use, taking -grayscale into account.
+func (c *Config) themeName() string {
+	if c.Global.Grayscale {
+		return "grayscale"
+	}
+	return c.Global.Theme
+}
+
+// String returns the config as it would appear in the config file.
+func (c *Config) String() string {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
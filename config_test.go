@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parseTestConfig(args ...string) (*Config, bool, error) {
+	flags := flag.NewFlagSet("termsaver", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return parseConfig(flags, args)
+}
+
+func TestConfigFlagsOverrideFile(t *testing.T) {
+	path := writeConfig(t, `{
+		"global": {"mode": "snake", "fps": 20},
+		"snake": {"scale": 3},
+		"lightning": {"max_clouds": 5}
+	}`)
+
+	cfg, _, err := parseTestConfig("-config", path, "-snake-scale", "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Global.Mode != "snake" || cfg.Global.FPS != 20 {
+		t.Errorf("global section not loaded: %+v", cfg.Global)
+	}
+	if cfg.Snake.Scale != 4 {
+		t.Errorf("snake scale = %d, want the flag's 4", cfg.Snake.Scale)
+	}
+	if cfg.Lightning.MaxClouds != 5 || cfg.Lightning.MaxLightnings != 2 {
+		t.Errorf("lightning section = %+v, want max_clouds from the file and the default max_lightnings", cfg.Lightning)
+	}
+}
+
+func TestConfigMissingFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if _, _, err := parseTestConfig(); err != nil {
+		t.Errorf("missing default config file: %v", err)
+	}
+	if _, _, err := parseTestConfig("-config", filepath.Join(t.TempDir(), "nope.json")); err == nil {
+		t.Errorf("missing -config file was not an error")
+	}
+}
+
+func TestConfigEmptyFile(t *testing.T) {
+	for _, contents := range []string{"", " \n\t\n"} {
+		cfg, _, err := parseTestConfig("-config", writeConfig(t, contents))
+		if err != nil {
+			t.Errorf("config %q: %v", contents, err)
+			continue
+		}
+		if cfg.String() != defaultConfig().String() {
+			t.Errorf("config %q changed the settings:\n%s", contents, cfg)
+		}
+	}
+}
+
+func TestConfigRejectsBadSettings(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":  `{"snake": {"sise": 3}}`,
+		"unknown mode": `{"global": {"mode": "nope"}}`,
+		"fps":          `{"global": {"fps": 0}}`,
+		"speed":        `{"global": {"speed": 50}}`,
+		"matrix":       `{"matrix": {"min_speed": 3, "max_speed": 1}}`,
+		"replay file":  `{"global": {"mode": "replay"}}`,
+		"replay fit":   `{"replay": {"fit": "stretch"}}`,
+		"gif frames":   `{"global": {"snapshot_frames": 0}}`,
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := parseTestConfig("-config", writeConfig(t, contents)); err == nil {
+				t.Errorf("config %s was accepted", contents)
+			}
+		})
+	}
+}
+
+func TestPrintedConfigLoadsBack(t *testing.T) {
+	cfg, printConfig, err := parseTestConfig("-print-config", "-mode", "matrix", "-speed", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !printConfig {
+		t.Errorf("-print-config not reported")
+	}
+
+	loaded, _, err := parseTestConfig("-config", writeConfig(t, cfg.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.String() != cfg.String() {
+		t.Errorf("config changed after a round trip:\n%s\nwant:\n%s", loaded, cfg)
+	}
+	if !strings.Contains(cfg.String(), `"max_ripples": 6`) {
+		t.Errorf("printed config is missing mode sections:\n%s", cfg)
+	}
+}
+
+func TestWatchFileNoticesChanges(t *testing.T) {
+	path := writeConfig(t, `{}`)
+	stop := make(chan struct{})
+	defer close(stop)
+	changed := watchFile(path, 10*time.Millisecond, stop)
+
+	select {
+	case <-changed:
+		t.Fatalf("change reported before the file was touched")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte(`{"global": {"speed": 2}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Errorf("change to %s not reported", path)
+	}
+}
+
+func TestReloadAppliesToRunningMode(t *testing.T) {
+	h := newHarness(t, "lightning", defaultEnv(), 60, 20, 1)
+	h.Step(5)
+
+	cfg := defaultConfig()
+	cfg.Global.Speed = 2
+	cfg.Global.FPS = 10
+	cfg.Lightning.MaxClouds = 1
+	h.session.loadConfig = func() (*Config, error) { return cfg, nil }
+
+	env := defaultEnv()
+	ticker := time.NewTicker(h.sched.frame)
+	defer ticker.Stop()
+	h.session.reloadConfig(env, h.mode, h.sched, ticker)
+
+	if env.Config != cfg {
+		t.Errorf("env still has the old config")
+	}
+	if h.sched.speed != 2 || h.sched.frame != 100*time.Millisecond {
+		t.Errorf("scheduler speed %g, frame %v; want 2 and 100ms", h.sched.speed, h.sched.frame)
+	}
+	if got := h.mode.(*LightningStorm).maxClouds; got != 1 {
+		t.Errorf("lightning maxClouds = %d after reload, want 1", got)
+	}
+	h.Run(time.Second)
+	if !strings.Contains(h.Frame(), "config reloaded") {
+		t.Errorf("reload not reported on screen")
+	}
+}
+
+func TestReloadKeepsConfigOnError(t *testing.T) {
+	h := newHarness(t, "matrix", defaultEnv(), 60, 20, 1)
+	h.session.loadConfig = func() (*Config, error) { return nil, errors.New("bad config") }
+
+	env := defaultEnv()
+	old := env.Config
+	ticker := time.NewTicker(h.sched.frame)
+	defer ticker.Stop()
+	h.session.reloadConfig(env, h.mode, h.sched, ticker)
+
+	if env.Config != old {
+		t.Errorf("config replaced by a failed reload")
+	}
+	h.Run(time.Second)
+	if !strings.Contains(h.Frame(), "config not reloaded: bad config") {
+		t.Errorf("failed reload not reported on screen")
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// DaemonConfig is the daemon section of the config file, for
+// "termsaver daemon".
+type DaemonConfig struct {
+	Idle  float64 `json:"idle"`  // Seconds without input or output before termsaver starts
+	Shell string  `json:"shell"` // Program to run; empty for $SHELL
+}
+
+// daemon passes a shell's pseudo-terminal through to the real terminal and
+// keeps track of activity. Once nothing has been typed or printed for the
+// idle timeout termsaver takes over the screen, drawing through a saverTty,
+// and the next key hands the screen back. The shell keeps running all along;
+// its output is fed to a terminal emulator so its screen can be put back the
+// way it would have been.
+type daemon struct {
+	idle  time.Duration
+	clock Clock
+	out   io.Writer // The real terminal
+	shell io.Writer // Input to the shell
+
+	term    *vterm // The shell's screen
+	partial []byte // Output ending in the middle of a UTF-8 sequence
+	last    time.Time
+	saver   *saverTty // Set while termsaver has the screen
+}
+
+func newDaemon(idle time.Duration, clock Clock, out, shell io.Writer, w, h int) *daemon {
+	return &daemon{idle: idle, clock: clock, out: out, shell: shell, term: newVterm(w, h), last: clock.Now()}
+}
+
+// input handles what was typed. It goes to the shell, or wakes the screen
+// if termsaver is running, in which case the key is dropped.
+func (d *daemon) input(data []byte) {
+	d.last = d.clock.Now()
+	if d.saver != nil {
+		d.saver.detach()
+		return
+	}
+	d.shell.Write(data)
+}
+
+// output handles what the shell printed, showing it unless termsaver is
+// running.
+func (d *daemon) output(data []byte) {
+	d.last = d.clock.Now()
+	if d.saver == nil {
+		d.out.Write(data)
+	}
+
+	// The emulator takes whole runes; keep a split one for next time
+	data = append(d.partial, data...)
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	d.term.Write(string(data[:end]))
+	d.partial = append([]byte(nil), data[end:]...)
+}
+
+// resize follows a change in the real terminal's size.
+func (d *daemon) resize(w, h int) {
+	d.term.Resize(w, h)
+	if d.saver != nil {
+		d.saver.resized()
+	}
+}
+
+// due reports whether it's time to start termsaver.
+func (d *daemon) due() bool {
+	return d.saver == nil && d.clock.Now().Sub(d.last) >= d.idle
+}
+
+// start hands the screen to termsaver, returning the tty for it to use.
+func (d *daemon) start(size func() (int, int)) *saverTty {
+	d.saver = newSaverTty(d.out, size)
+	return d.saver
+}
+
+// stop takes the screen back once termsaver has finished with it, putting
+// the shell's screen back as it is now.
+func (d *daemon) stop() {
+	d.saver = nil
+	d.last = d.clock.Now()
+	io.WriteString(d.out, d.term.repaint())
+}
+
+// saverTty is the terminal termsaver draws on while the daemon runs it.
+// Output goes straight to the real terminal. There's no input: termsaver
+// only needs to know when to stop, and for that reading ends, as if the
+// terminal had gone away.
+type saverTty struct {
+	out  io.Writer
+	size func() (int, int)
+
+	mu       sync.Mutex
+	detached chan struct{} // Closed when the daemon takes the screen back
+	drained  chan struct{} // Closed by Drain to wake a blocked Read
+	resize   func()
+}
+
+func newSaverTty(out io.Writer, size func() (int, int)) *saverTty {
+	return &saverTty{out: out, size: size, detached: make(chan struct{}), drained: make(chan struct{})}
+}
+
+func (t *saverTty) Start() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drained = make(chan struct{})
+	return nil
+}
+
+func (t *saverTty) Stop() error {
+	return nil
+}
+
+func (t *saverTty) Drain() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.drained:
+	default:
+		close(t.drained)
+	}
+	return nil
+}
+
+func (t *saverTty) NotifyResize(cb func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resize = cb
+}
+
+func (t *saverTty) WindowSize() (tcell.WindowSize, error) {
+	w, h := t.size()
+	return tcell.WindowSize{Width: w, Height: h}, nil
+}
+
+func (t *saverTty) Read(p []byte) (int, error) {
+	t.mu.Lock()
+	drained := t.drained
+	t.mu.Unlock()
+	select {
+	case <-t.detached:
+		return 0, io.EOF
+	case <-drained:
+		return 0, nil
+	}
+}
+
+func (t *saverTty) Write(p []byte) (int, error) {
+	return t.out.Write(p)
+}
+
+func (t *saverTty) Close() error {
+	return nil
+}
+
+// detach ends termsaver's input, which makes it exit.
+func (t *saverTty) detach() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.detached:
+	default:
+		close(t.detached)
+	}
+}
+
+// resized tells termsaver the terminal has changed size.
+func (t *saverTty) resized() {
+	t.mu.Lock()
+	cb := t.resize
+	t.mu.Unlock()
+	if cb != nil {
+		cb()
+	}
+}
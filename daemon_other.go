@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// runDaemon needs pseudo-terminals, which are only supported on Linux and
+// macOS.
+func runDaemon(env *Env) error {
+	return fmt.Errorf("termsaver daemon isn't supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from termsaver's goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDaemon(t *testing.T) {
+	clock := newManualClock()
+	var out, shell bytes.Buffer
+	d := newDaemon(time.Minute, clock, &out, &shell, 10, 3)
+	size := func() (int, int) { return 10, 3 }
+
+	// Until the terminal is idle everything passes through
+	d.input([]byte("ls\r"))
+	d.output([]byte("ls\r\nfile\r\n$ "))
+	clock.Advance(59 * time.Second)
+	if d.due() {
+		t.Fatalf("started after 59s")
+	}
+	if shell.String() != "ls\r" || out.String() != "ls\r\nfile\r\n$ " {
+		t.Errorf("shell got %q and the terminal %q", shell.String(), out.String())
+	}
+
+	clock.Advance(time.Second)
+	if !d.due() {
+		t.Fatalf("not started after a minute")
+	}
+	tty := d.start(size)
+	out.Reset()
+
+	// Output while termsaver runs is kept off the screen, split runes and all
+	d.output([]byte("\r\nworld \xe2\x9c"))
+	d.output([]byte("\x93"))
+	if out.Len() != 0 {
+		t.Errorf("output %q reached the screen under termsaver", out.String())
+	}
+
+	// A key ends termsaver without reaching the shell
+	d.input([]byte("q"))
+	if n, err := tty.Read(make([]byte, 8)); n != 0 || err == nil {
+		t.Errorf("termsaver can still read after a key")
+	}
+	if shell.String() != "ls\r" {
+		t.Errorf("the key reached the shell: %q", shell.String())
+	}
+	d.stop()
+	if d.due() {
+		t.Errorf("due again straight after stopping")
+	}
+
+	// The shell's screen comes back, with what it printed meanwhile
+	screen := newVterm(10, 3)
+	screen.Write(out.String())
+	if got, want := vtermText(screen), "file      |$         |world ✓   "; got != want {
+		t.Errorf("screen after waking is %q, want %q", got, want)
+	}
+}
+
+// TestDaemonStopsTermsaver runs termsaver on a saverTty and checks it draws
+// through it and exits when the daemon takes the screen back.
+func TestDaemonStopsTermsaver(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	var out syncBuffer
+	tty := newSaverTty(&out, func() (int, int) { return 40, 12 })
+	env := &Env{Config: defaultConfig()}
+	env.Config.Global.Mode = "matrix"
+
+	done := make(chan error, 1)
+	go func() {
+		done <- run(env, 1, func() (tcell.Screen, error) { return tcell.NewTerminfoScreenFromTty(tty) })
+	}()
+	time.Sleep(300 * time.Millisecond)
+	tty.detach()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("termsaver kept running after the daemon took the screen back")
+	}
+	if s := out.String(); !strings.Contains(s, "\x1b[?1049h") || !strings.Contains(s, "\x1b[?1049l") {
+		t.Errorf("termsaver didn't switch to and back from the alternate screen: %q", s)
+	}
+}
@@ -0,0 +1,189 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// daemonEnv is set in the shell's environment, so a daemon started inside
+// one can refuse to nest.
+const daemonEnv = "TERMSAVER_DAEMON"
+
+// runDaemon runs the user's shell in a pseudo-terminal and starts termsaver
+// whenever the terminal has been idle for the configured time, until the
+// shell exits.
+func runDaemon(env *Env) error {
+	if os.Getenv(daemonEnv) != "" {
+		return fmt.Errorf("Already running inside termsaver daemon")
+	}
+	in := int(os.Stdin.Fd())
+	if !term.IsTerminal(in) {
+		return fmt.Errorf("termsaver daemon needs to run in a terminal")
+	}
+	size := func() (int, int) {
+		w, h, err := term.GetSize(in)
+		if err != nil || w < 1 || h < 1 {
+			return 80, 24
+		}
+		return w, h
+	}
+
+	ptm, pts, err := openPTY()
+	if err != nil {
+		return fmt.Errorf("Error opening a pseudo-terminal: %v", err)
+	}
+	defer ptm.Close()
+	w, h := size()
+	setWinsize(ptm, w, h)
+
+	shell := env.Config.Daemon.Shell
+	if shell == "" {
+		shell = os.Getenv("SHELL")
+	}
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = pts, pts, pts
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err := cmd.Start(); err != nil {
+		pts.Close()
+		return fmt.Errorf("Error starting %s: %v", shell, err)
+	}
+	pts.Close()
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		cmd.Process.Signal(syscall.SIGHUP)
+		return fmt.Errorf("Error setting up the terminal: %v", err)
+	}
+	defer term.Restore(in, state)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGWINCH, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	// Start the shell on a clear screen, which is what the emulator in the
+	// daemon assumes it's on
+	idle := time.Duration(env.Config.Daemon.Idle * float64(time.Second))
+	d := newDaemon(idle, realClock{}, os.Stdout, ptm, w, h)
+	os.Stdout.WriteString("\x1b[H\x1b[2J")
+	input := readChunks(os.Stdin)
+	output := readChunks(ptm)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var done chan error
+	var quit <-chan time.Time
+
+	// Once the shell is gone, take the screen back and stop
+	finish := func() {
+		if d.saver != nil {
+			d.saver.detach()
+			<-done
+			d.stop()
+		}
+	}
+
+	for {
+		select {
+		case data, ok := <-input:
+			if !ok {
+				input = nil
+				continue
+			}
+			d.input(data)
+		case data, ok := <-output:
+			if !ok {
+				// The shell and everything it started have gone
+				finish()
+				return nil
+			}
+			d.output(data)
+		case sig := <-sigChan:
+			if sig == syscall.SIGTERM {
+				cmd.Process.Signal(syscall.SIGHUP)
+				continue
+			}
+			w, h := size()
+			setWinsize(ptm, w, h)
+			d.resize(w, h)
+		case <-ticker.C:
+			if !d.due() {
+				continue
+			}
+			tty := d.start(size)
+			done = make(chan error, 1)
+			go func() {
+				done <- run(env, saverSeed(env), func() (tcell.Screen, error) {
+					return tcell.NewTerminfoScreenFromTty(tty)
+				})
+			}()
+		case err := <-done:
+			done = nil
+			d.stop()
+			if err != nil {
+				return err
+			}
+		case <-exited:
+			// Give the shell's last output a moment to arrive, unless
+			// something it started keeps the terminal open
+			exited = nil
+			quit = time.After(500 * time.Millisecond)
+		case <-quit:
+			finish()
+			return nil
+		}
+	}
+}
+
+// saverSeed returns the seed for one run of termsaver by the daemon: the
+// configured one, or a new one each time.
+func saverSeed(env *Env) int64 {
+	if seed := env.Config.Global.Seed; seed != 0 {
+		return seed
+	}
+	return time.Now().UnixNano()
+}
+
+// readChunks reads from f until it fails, sending what it reads on the
+// returned channel, which is closed at the end.
+func readChunks(f *os.File) <-chan []byte {
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		for {
+			buf := make([]byte, 4096)
+			n, err := f.Read(buf)
+			if n > 0 {
+				ch <- buf[:n]
+			}
+			if err != nil && !errors.Is(err, syscall.EINTR) {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// setWinsize tells the pseudo-terminal its size, which the shell is signalled
+// about.
+func setWinsize(ptm *os.File, w, h int) {
+	unix.IoctlSetWinsize(int(ptm.Fd()), unix.TIOCSWINSZ, &unix.Winsize{Col: uint16(w), Row: uint16(h)})
+}
@@ -0,0 +1,69 @@
+package drawing
+
+import "github.com/gdamore/tcell/v2"
+
+// BigTextHeight is the height in cells of text drawn with BigText.
+const BigTextHeight = 5
+
+// bigGlyphs is a 3x5 pixel font for clocks and counters, one string per row
+// with # for a lit pixel. Each pixel is drawn two cells wide so the glyphs
+// come out roughly square.
+var bigGlyphs = map[rune][BigTextHeight]string{
+	'0': {"###", "# #", "# #", "# #", "###"},
+	'1': {"  #", "  #", "  #", "  #", "  #"},
+	'2': {"###", "  #", "###", "#  ", "###"},
+	'3': {"###", "  #", "###", "  #", "###"},
+	'4': {"# #", "# #", "###", "  #", "  #"},
+	'5': {"###", "#  ", "###", "  #", "###"},
+	'6': {"###", "#  ", "###", "# #", "###"},
+	'7': {"###", "  #", "  #", "  #", "  #"},
+	'8': {"###", "# #", "###", "# #", "###"},
+	'9': {"###", "# #", "###", "  #", "###"},
+	':': {" ", "#", " ", "#", " "},
+	'.': {" ", " ", " ", " ", "#"},
+	'-': {"   ", "   ", "###", "   ", "   "},
+	' ': {"   ", "   ", "   ", "   ", "   "},
+}
+
+// bigGlyph returns the glyph for r, with characters the font doesn't have
+// shown as spaces.
+func bigGlyph(r rune) [BigTextHeight]string {
+	if g, ok := bigGlyphs[r]; ok {
+		return g
+	}
+	return bigGlyphs[' ']
+}
+
+// BigTextWidth returns the width in cells of s drawn with BigText.
+func BigTextWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if w > 0 {
+			w++
+		}
+		w += 2 * len(bigGlyph(r)[0])
+	}
+	return w
+}
+
+// BigText draws s in large block digits, BigTextHeight cells high, with its
+// top left corner at x, y. The font has digits, ':', '.', '-' and space.
+// Unlit pixels are left alone. It returns the column after the text.
+func BigText(screen tcell.Screen, x, y int, s string, style tcell.Style) int {
+	for i, r := range []rune(s) {
+		if i > 0 {
+			x++
+		}
+		g := bigGlyph(r)
+		for dy, row := range g {
+			for dx, p := range row {
+				if p == '#' {
+					Set(screen, x+2*dx, y+dy, '█', style)
+					Set(screen, x+2*dx+1, y+dy, '█', style)
+				}
+			}
+		}
+		x += 2 * len(g[0])
+	}
+	return x
+}
@@ -0,0 +1,271 @@
+package drawing
+
+import (
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// CanvasKind selects how canvas pixels map onto terminal cells.
+type CanvasKind int
+
+const (
+	// HalfBlock gives each cell two pixels stacked vertically, drawn with ▀
+	// and ▄. Both pixels keep their own color.
+	HalfBlock CanvasKind = iota
+	// Braille gives each cell 2x4 dots using the Braille patterns. All dots
+	// in a cell share one color.
+	Braille
+)
+
+// brailleDots maps a dot's position within a cell to its bit in the Braille
+// pattern block, indexed [y][x].
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Canvas is a grid of pixels finer than the terminal's cells, for drawing
+// smooth lines and curves. Pixels are addressed from the top left; a pixel
+// that was never set is transparent, and cells without any set pixels are
+// left alone when the canvas is drawn.
+type Canvas struct {
+	kind       CanvasKind
+	cols, rows int // Size in cells
+	w, h       int // Size in pixels
+	pix        []tcell.Color
+	set        []bool
+}
+
+// NewCanvas returns an empty canvas covering cols x rows cells.
+func NewCanvas(kind CanvasKind, cols, rows int) *Canvas {
+	c := &Canvas{kind: kind}
+	c.Resize(cols, rows)
+	return c
+}
+
+// Resize changes the canvas to cover cols x rows cells, clearing it.
+func (c *Canvas) Resize(cols, rows int) {
+	if cols < 0 {
+		cols = 0
+	}
+	if rows < 0 {
+		rows = 0
+	}
+	sx, sy := c.Scale()
+	c.cols, c.rows = cols, rows
+	c.w, c.h = cols*sx, rows*sy
+	c.pix = make([]tcell.Color, c.w*c.h)
+	c.set = make([]bool, c.w*c.h)
+}
+
+// Scale returns how many pixels make up one cell across and down.
+func (c *Canvas) Scale() (int, int) {
+	if c.kind == Braille {
+		return 2, 4
+	}
+	return 1, 2
+}
+
+// Size returns the canvas size in pixels.
+func (c *Canvas) Size() (int, int) {
+	return c.w, c.h
+}
+
+// Clear makes every pixel transparent again.
+func (c *Canvas) Clear() {
+	for i := range c.set {
+		c.set[i] = false
+	}
+}
+
+// Set colors the pixel at x, y. Pixels off the canvas are ignored.
+func (c *Canvas) Set(x, y int, color tcell.Color) {
+	if x < 0 || y < 0 || x >= c.w || y >= c.h {
+		return
+	}
+	c.pix[y*c.w+x] = color
+	c.set[y*c.w+x] = true
+}
+
+// Line draws a line between two points given in pixels.
+func (c *Canvas) Line(x0, y0, x1, y1 float64, color tcell.Color) {
+	dx, dy := x1-x0, y1-y0
+	steps := int(math.Ceil(math.Max(math.Abs(dx), math.Abs(dy))))
+	if steps == 0 {
+		c.Set(int(math.Floor(x0)), int(math.Floor(y0)), color)
+		return
+	}
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		c.Set(int(math.Floor(x0+dx*t)), int(math.Floor(y0+dy*t)), color)
+	}
+}
+
+// LineAA draws an anti-aliased line between two points given in pixels,
+// blending fg over what is already on the canvas, or bg where nothing is.
+// Braille dots can't be partly lit, so on a Braille canvas only pixels the
+// line covers at least half of are set.
+func (c *Canvas) LineAA(x0, y0, x1, y1 float64, fg, bg tcell.Color) {
+	// Xiaolin Wu's algorithm, walking along the longer axis. Pixel centers
+	// are at +0.5.
+	steep := math.Abs(y1-y0) > math.Abs(x1-x0)
+	if steep {
+		x0, y0, x1, y1 = y0, x0, y1, x1
+	}
+	if x0 > x1 {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	gradient := 0.0
+	if x1 > x0 {
+		gradient = (y1 - y0) / (x1 - x0)
+	}
+	for x := int(math.Floor(x0)); x <= int(math.Floor(x1)); x++ {
+		cx := math.Min(math.Max(float64(x)+0.5, x0), x1)
+		y := y0 + gradient*(cx-x0) - 0.5
+		iy := int(math.Floor(y))
+		frac := y - float64(iy)
+		if steep {
+			c.blend(iy, x, fg, bg, 1-frac)
+			c.blend(iy+1, x, fg, bg, frac)
+		} else {
+			c.blend(x, iy, fg, bg, 1-frac)
+			c.blend(x, iy+1, fg, bg, frac)
+		}
+	}
+}
+
+// blend mixes fg into the pixel at x, y by coverage (0 to 1).
+func (c *Canvas) blend(x, y int, fg, bg tcell.Color, coverage float64) {
+	if x < 0 || y < 0 || x >= c.w || y >= c.h || coverage <= 0 {
+		return
+	}
+	if c.kind == Braille {
+		if coverage >= 0.5 {
+			c.Set(x, y, fg)
+		}
+		return
+	}
+	if i := y*c.w + x; c.set[i] {
+		bg = c.pix[i]
+	}
+	c.Set(x, y, mix(bg, fg, coverage))
+}
+
+// mix returns the color t (0 to 1) of the way from a to b.
+func mix(a, b tcell.Color, t float64) tcell.Color {
+	r1, g1, b1 := a.RGB()
+	r2, g2, b2 := b.RGB()
+	if r1 < 0 || r2 < 0 {
+		// One of them is the terminal's default color, which can't be mixed
+		if t >= 0.5 {
+			return b
+		}
+		return a
+	}
+	m := func(x, y int32) int32 {
+		return x + int32(float64(y-x)*t+0.5)
+	}
+	return tcell.NewRGBColor(m(r1, r2), m(g1, g2), m(b1, b2))
+}
+
+// Circle draws the outline of a circle given in pixels.
+func (c *Canvas) Circle(cx, cy, r float64, color tcell.Color) {
+	if r <= 0 {
+		c.Set(int(math.Floor(cx)), int(math.Floor(cy)), color)
+		return
+	}
+	// Enough steps that neighbouring points are never more than a pixel apart
+	steps := int(math.Ceil(2 * math.Pi * r))
+	for i := 0; i < steps; i++ {
+		a := 2 * math.Pi * float64(i) / float64(steps)
+		c.Set(int(math.Floor(cx+r*math.Cos(a))), int(math.Floor(cy+r*math.Sin(a))), color)
+	}
+}
+
+// Disk fills a circle given in pixels: every pixel whose center is within
+// r of cx, cy.
+func (c *Canvas) Disk(cx, cy, r float64, color tcell.Color) {
+	for y := int(math.Floor(cy - r)); y <= int(math.Ceil(cy+r)); y++ {
+		for x := int(math.Floor(cx - r)); x <= int(math.Ceil(cx+r)); x++ {
+			if dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy; dx*dx+dy*dy <= r*r {
+				c.Set(x, y, color)
+			}
+		}
+	}
+}
+
+// Draw composites the canvas onto the screen with its top left corner at
+// cell x, y, with bg behind the pixels.
+func (c *Canvas) Draw(screen tcell.Screen, x, y int, bg tcell.Color) {
+	for row := 0; row < c.rows; row++ {
+		for col := 0; col < c.cols; col++ {
+			var r rune
+			var style tcell.Style
+			var ok bool
+			if c.kind == Braille {
+				r, style, ok = c.brailleCell(col, row, bg)
+			} else {
+				r, style, ok = c.halfBlockCell(col, row, bg)
+			}
+			if ok {
+				screen.SetContent(x+col, y+row, r, nil, style)
+			}
+		}
+	}
+}
+
+func (c *Canvas) halfBlockCell(col, row int, bg tcell.Color) (rune, tcell.Style, bool) {
+	top, bottom := row*2*c.w+col, (row*2+1)*c.w+col
+	switch {
+	case c.set[top] && c.set[bottom]:
+		if c.pix[top] == c.pix[bottom] {
+			return '█', tcell.StyleDefault.Foreground(c.pix[top]).Background(bg), true
+		}
+		return '▀', tcell.StyleDefault.Foreground(c.pix[top]).Background(c.pix[bottom]), true
+	case c.set[top]:
+		return '▀', tcell.StyleDefault.Foreground(c.pix[top]).Background(bg), true
+	case c.set[bottom]:
+		return '▄', tcell.StyleDefault.Foreground(c.pix[bottom]).Background(bg), true
+	}
+	return 0, tcell.StyleDefault, false
+}
+
+// brailleCell returns the pattern for a cell, colored with whichever color
+// most of its dots have.
+func (c *Canvas) brailleCell(col, row int, bg tcell.Color) (rune, tcell.Style, bool) {
+	var pattern rune
+	var colors [8]tcell.Color
+	var counts [8]int
+	n := 0
+	for dy := 0; dy < 4; dy++ {
+		for dx := 0; dx < 2; dx++ {
+			i := (row*4+dy)*c.w + col*2 + dx
+			if !c.set[i] {
+				continue
+			}
+			pattern |= brailleDots[dy][dx]
+			j := 0
+			for j < n && colors[j] != c.pix[i] {
+				j++
+			}
+			if j == n {
+				colors[n] = c.pix[i]
+				n++
+			}
+			counts[j]++
+		}
+	}
+	if pattern == 0 {
+		return 0, tcell.StyleDefault, false
+	}
+	best := 0
+	for j := 1; j < n; j++ {
+		if counts[j] > counts[best] {
+			best = j
+		}
+	}
+	return 0x2800 + pattern, tcell.StyleDefault.Foreground(colors[best]).Background(bg), true
+}
@@ -0,0 +1,147 @@
+package drawing
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func drawCanvas(t *testing.T, c *Canvas) tcell.SimulationScreen {
+	t.Helper()
+	screen := newScreen(t, c.cols, c.rows)
+	c.Draw(screen, 0, 0, tcell.ColorBlack)
+	return screen
+}
+
+func TestHalfBlockCanvas(t *testing.T) {
+	red, blue := tcell.NewHexColor(0xff0000), tcell.NewHexColor(0x0000ff)
+	c := NewCanvas(HalfBlock, 4, 1)
+	c.Set(0, 0, red) // Top only
+	c.Set(1, 1, red) // Bottom only
+	c.Set(2, 0, red) // Both, same color
+	c.Set(2, 1, red)
+	c.Set(3, 0, red) // Both, different colors
+	c.Set(3, 1, blue)
+	screen := drawCanvas(t, c)
+
+	tests := []struct {
+		r      rune
+		fg, bg tcell.Color
+	}{
+		{'▀', red, tcell.ColorBlack},
+		{'▄', red, tcell.ColorBlack},
+		{'█', red, tcell.ColorBlack},
+		{'▀', red, blue},
+	}
+	for x, want := range tests {
+		r, _, style, _ := screen.GetContent(x, 0)
+		fg, bg, _ := style.Decompose()
+		if r != want.r || fg != want.fg || bg != want.bg {
+			t.Errorf("cell %d = %q fg %v bg %v, want %q fg %v bg %v", x, r, fg, bg, want.r, want.fg, want.bg)
+		}
+	}
+}
+
+func TestBrailleCanvas(t *testing.T) {
+	red, blue := tcell.NewHexColor(0xff0000), tcell.NewHexColor(0x0000ff)
+	c := NewCanvas(Braille, 3, 1)
+	// Left column of the first cell, mostly red
+	c.Set(0, 0, red)
+	c.Set(0, 1, red)
+	c.Set(0, 2, blue)
+	c.Set(0, 3, red)
+	// Bottom right dot of the second cell
+	c.Set(3, 3, blue)
+	screen := drawCanvas(t, c)
+
+	r, _, style, _ := screen.GetContent(0, 0)
+	if fg, _, _ := style.Decompose(); r != '⡇' || fg != red {
+		t.Errorf("first cell = %q in %v, want ⡇ in red", r, fg)
+	}
+	if r, _, _, _ := screen.GetContent(1, 0); r != '⢀' {
+		t.Errorf("second cell = %q, want ⢀", r)
+	}
+	// Cells without any dots are left as they were
+	if r, _, _, _ := screen.GetContent(2, 0); r != '.' {
+		t.Errorf("empty cell = %q, want it untouched", r)
+	}
+}
+
+func TestCanvasLineAndCircle(t *testing.T) {
+	c := NewCanvas(Braille, 10, 5)
+	w, h := c.Size()
+	if w != 20 || h != 20 {
+		t.Fatalf("canvas size = %dx%d pixels, want 20x20", w, h)
+	}
+
+	c.Line(0, 0, 19, 19, tcell.ColorWhite)
+	for i := 0; i < 20; i++ {
+		if !c.set[i*w+i] {
+			t.Errorf("diagonal line misses pixel %d,%d", i, i)
+		}
+	}
+
+	c.Clear()
+	c.Circle(10.5, 10.5, 8, tcell.ColorWhite)
+	for _, p := range [][2]int{{18, 10}, {2, 10}, {10, 18}, {10, 2}} {
+		if !c.set[p[1]*w+p[0]] {
+			t.Errorf("circle misses pixel %d,%d", p[0], p[1])
+		}
+	}
+	if c.set[10*w+10] {
+		t.Errorf("circle outline filled in its center")
+	}
+
+	c.Clear()
+	c.Disk(10, 10, 3, tcell.ColorWhite)
+	for _, p := range [][2]int{{10, 10}, {12, 10}, {7, 9}, {10, 7}} {
+		if !c.set[p[1]*w+p[0]] {
+			t.Errorf("disk misses pixel %d,%d", p[0], p[1])
+		}
+	}
+	for _, p := range [][2]int{{13, 10}, {12, 12}, {10, 13}} {
+		if c.set[p[1]*w+p[0]] {
+			t.Errorf("disk spills onto pixel %d,%d", p[0], p[1])
+		}
+	}
+
+	// Drawing off the canvas is ignored
+	c.Line(-5, -5, 30, 30, tcell.ColorWhite)
+	c.Disk(0, 0, 5, tcell.ColorWhite)
+}
+
+func TestCanvasLineAA(t *testing.T) {
+	white := tcell.NewHexColor(0xffffff)
+	c := NewCanvas(HalfBlock, 8, 4)
+	w, _ := c.Size()
+
+	// A horizontal line along pixel centers covers them fully
+	c.LineAA(0.5, 2.5, 7.5, 2.5, white, tcell.ColorBlack)
+	for x := 0; x < 8; x++ {
+		if !c.set[2*w+x] || c.pix[2*w+x] != white {
+			t.Errorf("pixel %d,2 = %v, want full white", x, c.pix[2*w+x])
+		}
+		if c.set[1*w+x] || c.set[3*w+x] {
+			t.Errorf("line spilled next to pixel %d,2", x)
+		}
+	}
+
+	// Halfway between two rows both get half the color
+	c.Clear()
+	c.LineAA(0.5, 5, 7.5, 5, white, tcell.ColorBlack)
+	for _, y := range []int{4, 5} {
+		if r, _, _ := c.pix[y*w+3].RGB(); !c.set[y*w+3] || r < 120 || r > 135 {
+			t.Errorf("pixel 3,%d red = %d, want about half", y, r)
+		}
+	}
+
+	// Braille dots are either on or off
+	c = NewCanvas(Braille, 4, 2)
+	w, _ = c.Size()
+	c.LineAA(0.5, 5.2, 7.5, 5.2, white, tcell.ColorBlack)
+	for x := 0; x < 8; x++ {
+		if c.set[4*w+x] || !c.set[5*w+x] {
+			t.Errorf("braille line at column %d not on row 5 only", x)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package drawing has the primitives modes draw with: lines, rectangles,
+// text, banners and sprites on a tcell screen, and a Canvas for shapes finer
+// than a cell. Everything is clipped to the screen, so callers don't need to
+// check bounds themselves.
+package drawing
+
+import "github.com/gdamore/tcell/v2"
+
+// Rect is an area of the screen in cells.
+type Rect struct {
+	X, Y, W, H int
+}
+
+// Bounds returns the whole screen as a Rect.
+func Bounds(screen tcell.Screen) Rect {
+	w, h := screen.Size()
+	return Rect{W: w, H: h}
+}
+
+// Contains reports whether the cell x, y is inside r.
+func (r Rect) Contains(x, y int) bool {
+	return x >= r.X && y >= r.Y && x < r.X+r.W && y < r.Y+r.H
+}
+
+// Intersect returns the part of r that is also inside o.
+func (r Rect) Intersect(o Rect) Rect {
+	x0, y0 := max(r.X, o.X), max(r.Y, o.Y)
+	x1, y1 := min(r.X+r.W, o.X+o.W), min(r.Y+r.H, o.Y+o.H)
+	if x1 <= x0 || y1 <= y0 {
+		return Rect{}
+	}
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
+// Set draws r at x, y if that cell is on the screen.
+func Set(screen tcell.Screen, x, y int, r rune, style tcell.Style) {
+	w, h := screen.Size()
+	if x >= 0 && y >= 0 && x < w && y < h {
+		screen.SetContent(x, y, r, nil, style)
+	}
+}
+
+// FillRect fills r with ch.
+func FillRect(screen tcell.Screen, r Rect, ch rune, style tcell.Style) {
+	r = r.Intersect(Bounds(screen))
+	for y := r.Y; y < r.Y+r.H; y++ {
+		for x := r.X; x < r.X+r.W; x++ {
+			screen.SetContent(x, y, ch, nil, style)
+		}
+	}
+}
+
+// Box draws a single line border just inside the edges of r.
+func Box(screen tcell.Screen, r Rect, style tcell.Style) {
+	if r.W < 1 || r.H < 1 {
+		return
+	}
+	right, bottom := r.X+r.W-1, r.Y+r.H-1
+	for x := r.X; x <= right; x++ {
+		Set(screen, x, r.Y, '─', style)
+		Set(screen, x, bottom, '─', style)
+	}
+	for y := r.Y; y <= bottom; y++ {
+		Set(screen, r.X, y, '│', style)
+		Set(screen, right, y, '│', style)
+	}
+	Set(screen, r.X, r.Y, '┌', style)
+	Set(screen, right, r.Y, '┐', style)
+	Set(screen, r.X, bottom, '└', style)
+	Set(screen, right, bottom, '┘', style)
+}
+
+// Line draws a line of box drawing characters between two cells, picking
+// ─, │, / or \ to follow its slope.
+func Line(screen tcell.Screen, x0, y0, x1, y1 int, style tcell.Style) {
+	dx, dy := abs(x1-x0), abs(y1-y0)
+	var ch rune
+	switch {
+	case dy*2 < dx:
+		ch = '─'
+	case dx*2 < dy:
+		ch = '│'
+	case (x1 > x0) == (y1 > y0):
+		ch = '\\'
+	default:
+		ch = '/'
+	}
+
+	// Bresenham's algorithm
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx - dy
+	x, y := x0, y0
+	for {
+		Set(screen, x, y, ch, style)
+		if x == x1 && y == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 > -dy {
+			err -= dy
+			x += sx
+		}
+		if e2 < dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+// Text draws s starting at x, y, one cell per rune, cutting off whatever
+// falls outside the screen. It returns the column after the text.
+func Text(screen tcell.Screen, x, y int, s string, style tcell.Style) int {
+	for _, r := range s {
+		Set(screen, x, y, r, style)
+		x++
+	}
+	return x
+}
+
+// TextIn draws s like Text, but cut off at the edges of clip instead of the
+// screen.
+func TextIn(screen tcell.Screen, clip Rect, x, y int, s string, style tcell.Style) int {
+	for _, r := range s {
+		if clip.Contains(x, y) {
+			Set(screen, x, y, r, style)
+		}
+		x++
+	}
+	return x
+}
+
+// CenterText draws s centered in the w columns starting at x. Text wider
+// than that starts at x and is cut off.
+func CenterText(screen tcell.Screen, x, y, w int, s string, style tcell.Style) {
+	clip := Rect{X: x, Y: y, W: w, H: 1}
+	if n := len([]rune(s)); n < w {
+		x += (w - n) / 2
+	}
+	TextIn(screen, clip, x, y, s, style)
+}
+
+// Banner draws lines as a block in the middle of the screen, each line
+// centered, inside a box with a one cell margin. The box is filled with
+// style, so the banner stays readable over anything drawn before it.
+func Banner(screen tcell.Screen, lines []string, style tcell.Style) Rect {
+	width := 0
+	for _, line := range lines {
+		width = max(width, len([]rune(line)))
+	}
+	sw, sh := screen.Size()
+	box := Rect{W: width + 4, H: len(lines) + 2}
+	box.X, box.Y = (sw-box.W)/2, (sh-box.H)/2
+	FillRect(screen, box, ' ', style)
+	Box(screen, box, style)
+	for i, line := range lines {
+		CenterText(screen, box.X+2, box.Y+1+i, width, line, style)
+	}
+	return box
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,160 @@
+package drawing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// newScreen returns a w x h simulation screen filled with dots, so untouched
+// cells stand out.
+func newScreen(t *testing.T, w, h int) tcell.SimulationScreen {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(screen.Fini)
+	screen.SetSize(w, h)
+	screen.Fill('.', tcell.StyleDefault)
+	return screen
+}
+
+// rows returns the screen's contents as text.
+func rows(screen tcell.Screen) []string {
+	w, h := screen.Size()
+	lines := make([]string, h)
+	for y := 0; y < h; y++ {
+		var b strings.Builder
+		for x := 0; x < w; x++ {
+			r, _, _, _ := screen.GetContent(x, y)
+			b.WriteRune(r)
+		}
+		lines[y] = b.String()
+	}
+	return lines
+}
+
+func checkRows(t *testing.T, screen tcell.Screen, want ...string) {
+	t.Helper()
+	got := rows(screen)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("screen is\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		x0, y0, x1, y1 int
+		want           []string
+	}{
+		{0, 1, 5, 1, []string{"......", "──────", "......"}},
+		{2, 0, 2, 2, []string{"..│...", "..│...", "..│..."}},
+		{0, 0, 2, 2, []string{"\\.....", ".\\....", "..\\..."}},
+		{5, 0, 3, 2, []string{"...../", "..../.", ".../.."}},
+		// Clipped at the screen edges
+		{-3, 1, 9, 1, []string{"......", "──────", "......"}},
+	}
+	for _, tt := range tests {
+		screen := newScreen(t, 6, 3)
+		Line(screen, tt.x0, tt.y0, tt.x1, tt.y1, tcell.StyleDefault)
+		checkRows(t, screen, tt.want...)
+	}
+}
+
+func TestText(t *testing.T) {
+	screen := newScreen(t, 8, 3)
+	if end := Text(screen, 5, 0, "hello", tcell.StyleDefault); end != 10 {
+		t.Errorf("Text returned %d, want 10", end)
+	}
+	Text(screen, -2, 1, "hello", tcell.StyleDefault)
+	TextIn(screen, Rect{X: 1, Y: 2, W: 3, H: 1}, 0, 2, "hello", tcell.StyleDefault)
+	checkRows(t, screen,
+		".....hel",
+		"llo.....",
+		".ell....",
+	)
+}
+
+func TestCenterText(t *testing.T) {
+	screen := newScreen(t, 10, 2)
+	CenterText(screen, 0, 0, 10, "abcd", tcell.StyleDefault)
+	CenterText(screen, 2, 1, 4, "abcdefg", tcell.StyleDefault)
+	checkRows(t, screen,
+		"...abcd...",
+		"..abcd....",
+	)
+}
+
+func TestFillRectAndBox(t *testing.T) {
+	screen := newScreen(t, 6, 4)
+	FillRect(screen, Rect{X: 4, Y: -1, W: 5, H: 3}, '#', tcell.StyleDefault)
+	Box(screen, Rect{X: 0, Y: 1, W: 4, H: 3}, tcell.StyleDefault)
+	checkRows(t, screen,
+		"....##",
+		"┌──┐##",
+		"│..│..",
+		"└──┘..",
+	)
+}
+
+func TestBanner(t *testing.T) {
+	screen := newScreen(t, 12, 5)
+	box := Banner(screen, []string{"GAME", "OVER!"}, tcell.StyleDefault)
+	if box != (Rect{X: 1, Y: 0, W: 9, H: 4}) {
+		t.Errorf("banner box = %+v", box)
+	}
+	checkRows(t, screen,
+		".┌───────┐..",
+		".│ GAME  │..",
+		".│ OVER! │..",
+		".└───────┘..",
+		"............",
+	)
+}
+
+func TestSprite(t *testing.T) {
+	s := NewSprite(
+		[]string{" /\\ ", "(o o)", " ~x~"},
+		[]string{" /\\ ", "(- -)", " ~x~"},
+	)
+	s.Transparent = 'x'
+	if w, h := s.Size(); w != 5 || h != 3 {
+		t.Errorf("sprite size = %dx%d, want 5x3", w, h)
+	}
+
+	screen := newScreen(t, 7, 3)
+	s.Draw(screen, 1, 0, 0, tcell.StyleDefault)
+	checkRows(t, screen,
+		"../\\...",
+		".(o o).",
+		"..~.~..",
+	)
+
+	// Frames wrap around, and the sprite is clipped at the screen edge
+	screen = newScreen(t, 7, 3)
+	s.Draw(screen, 4, 0, 3, tcell.StyleDefault)
+	checkRows(t, screen,
+		"...../\\",
+		"....(- ",
+		".....~.",
+	)
+}
+
+func TestBigText(t *testing.T) {
+	if w := BigTextWidth("1:2"); w != 16 {
+		t.Errorf("BigTextWidth = %d, want 16", w)
+	}
+	screen := newScreen(t, 17, 5)
+	if end := BigText(screen, 0, 0, "1:2", tcell.StyleDefault); end != 16 {
+		t.Errorf("BigText returned %d, want 16", end)
+	}
+	checkRows(t, screen,
+		"....██....██████.",
+		"....██.██.....██.",
+		"....██....██████.",
+		"....██.██.██.....",
+		"....██....██████.",
+	)
+}
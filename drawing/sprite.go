@@ -0,0 +1,58 @@
+package drawing
+
+import "github.com/gdamore/tcell/v2"
+
+// Sprite is a piece of ASCII art with one or more animation frames, each a
+// list of lines. Spaces before the first and after the last character of a
+// line are transparent, so whatever is behind the sprite shows around its
+// outline; spaces inside it are drawn. Transparent, when set, marks more
+// cells to leave alone.
+type Sprite struct {
+	Frames      [][]string
+	Transparent rune
+}
+
+// NewSprite returns a sprite with the given frames.
+func NewSprite(frames ...[]string) *Sprite {
+	return &Sprite{Frames: frames}
+}
+
+// Size returns the width and height of the largest frame.
+func (s *Sprite) Size() (int, int) {
+	w, h := 0, 0
+	for _, frame := range s.Frames {
+		h = max(h, len(frame))
+		for _, line := range frame {
+			w = max(w, len([]rune(line)))
+		}
+	}
+	return w, h
+}
+
+// Draw draws frame n, wrapping around after the last one, with its top left
+// corner at x, y.
+func (s *Sprite) Draw(screen tcell.Screen, x, y, n int, style tcell.Style) {
+	if len(s.Frames) == 0 {
+		return
+	}
+	n %= len(s.Frames)
+	if n < 0 {
+		n += len(s.Frames)
+	}
+	for dy, line := range s.Frames[n] {
+		runes := []rune(line)
+		first, last := 0, len(runes)-1
+		for first <= last && runes[first] == ' ' {
+			first++
+		}
+		for last >= first && runes[last] == ' ' {
+			last--
+		}
+		for dx := first; dx <= last; dx++ {
+			if s.Transparent != 0 && runes[dx] == s.Transparent {
+				continue
+			}
+			Set(screen, x+dx, y+dy, runes[dx], style)
+		}
+	}
+}
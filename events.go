@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// eventPump is the only reader of the screen's event queue. main starts it
+// once and every mode receives its input through Events, so cycling through
+// modes never leaves an old reader behind competing for keys and resizes.
+type eventPump struct {
+	events chan tcell.Event
+	quit   chan struct{}
+}
+
+func newEventPump(screen tcell.Screen) *eventPump {
+	p := &eventPump{
+		events: make(chan tcell.Event, 10),
+		quit:   make(chan struct{}),
+	}
+	go screen.ChannelEvents(p.events, p.quit)
+	return p
+}
+
+// Events returns the channel the active mode reads from. It is closed once
+// the pump has stopped.
+func (p *eventPump) Events() <-chan tcell.Event {
+	return p.events
+}
+
+// Stop shuts the pump down and waits for it to exit.
+func (p *eventPump) Stop() {
+	close(p.quit)
+	for range p.events {
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newEventScreen(t *testing.T) tcell.SimulationScreen {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("initializing simulation screen: %v", err)
+	}
+	t.Cleanup(screen.Fini)
+	return screen
+}
+
+// nextKey returns the rune of the next key event from events, skipping
+// anything else the screen sends.
+func nextKey(t *testing.T, events <-chan tcell.Event) rune {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed while waiting for a key")
+			}
+			if key, ok := ev.(*tcell.EventKey); ok {
+				return key.Rune()
+			}
+		case <-timeout:
+			t.Fatalf("no key event")
+		}
+	}
+}
+
+func TestEventPumpStop(t *testing.T) {
+	screen := newEventScreen(t)
+	p := newEventPump(screen)
+	for _, r := range "abc" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop didn't return with events still pending")
+	}
+	select {
+	case <-p.quit:
+	default:
+		t.Errorf("quit channel still open")
+	}
+	if _, ok := <-p.Events(); ok {
+		t.Errorf("events still coming after Stop")
+	}
+}
+
+// TestEventPumpRestarts starts and stops a pump the way cycling through
+// modes would, and checks none of the old pumps is left taking events.
+func TestEventPumpRestarts(t *testing.T) {
+	screen := newEventScreen(t)
+	for i := 0; i < 5; i++ {
+		p := newEventPump(screen)
+		screen.InjectKey(tcell.KeyRune, 'x', tcell.ModNone)
+		if r := nextKey(t, p.Events()); r != 'x' {
+			t.Fatalf("pump %d got %q, want 'x'", i, r)
+		}
+		p.Stop()
+	}
+
+	p := newEventPump(screen)
+	defer p.Stop()
+	keys := "abcdefgh"
+	for _, r := range keys {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	var got []rune
+	for range keys {
+		got = append(got, nextKey(t, p.Events()))
+	}
+	if string(got) != keys {
+		t.Errorf("last pump got %q, want every key %q", string(got), keys)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+// A 5x7 bitmap font for drawing the screen into images. Each glyph is five
+// columns, left to right, with bit 0 the top row.
+
+// fontWidth and fontHeight are the size of a glyph in the font.
+const (
+	fontWidth  = 5
+	fontHeight = 7
+)
+
+// fontASCII holds the printable ASCII characters from ' ' to '~'.
+var fontASCII = [95][fontWidth]byte{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5f, 0x00, 0x00}, // !
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // "
+	{0x14, 0x7f, 0x14, 0x7f, 0x14}, // #
+	{0x24, 0x2a, 0x7f, 0x2a, 0x12}, // $
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // %
+	{0x36, 0x49, 0x55, 0x22, 0x50}, // &
+	{0x00, 0x05, 0x03, 0x00, 0x00}, // '
+	{0x00, 0x1c, 0x22, 0x41, 0x00}, // (
+	{0x00, 0x41, 0x22, 0x1c, 0x00}, // )
+	{0x08, 0x2a, 0x1c, 0x2a, 0x08}, // *
+	{0x08, 0x08, 0x3e, 0x08, 0x08}, // +
+	{0x00, 0x50, 0x30, 0x00, 0x00}, // ,
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // -
+	{0x00, 0x60, 0x60, 0x00, 0x00}, // .
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // /
+	{0x3e, 0x51, 0x49, 0x45, 0x3e}, // 0
+	{0x00, 0x42, 0x7f, 0x40, 0x00}, // 1
+	{0x42, 0x61, 0x51, 0x49, 0x46}, // 2
+	{0x21, 0x41, 0x45, 0x4b, 0x31}, // 3
+	{0x18, 0x14, 0x12, 0x7f, 0x10}, // 4
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // 5
+	{0x3c, 0x4a, 0x49, 0x49, 0x30}, // 6
+	{0x01, 0x71, 0x09, 0x05, 0x03}, // 7
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // 8
+	{0x06, 0x49, 0x49, 0x29, 0x1e}, // 9
+	{0x00, 0x36, 0x36, 0x00, 0x00}, // :
+	{0x00, 0x56, 0x36, 0x00, 0x00}, // ;
+	{0x08, 0x14, 0x22, 0x41, 0x00}, // <
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // =
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // >
+	{0x02, 0x01, 0x51, 0x09, 0x06}, // ?
+	{0x32, 0x49, 0x79, 0x41, 0x3e}, // @
+	{0x7e, 0x11, 0x11, 0x11, 0x7e}, // A
+	{0x7f, 0x49, 0x49, 0x49, 0x36}, // B
+	{0x3e, 0x41, 0x41, 0x41, 0x22}, // C
+	{0x7f, 0x41, 0x41, 0x22, 0x1c}, // D
+	{0x7f, 0x49, 0x49, 0x49, 0x41}, // E
+	{0x7f, 0x09, 0x09, 0x09, 0x01}, // F
+	{0x3e, 0x41, 0x49, 0x49, 0x7a}, // G
+	{0x7f, 0x08, 0x08, 0x08, 0x7f}, // H
+	{0x00, 0x41, 0x7f, 0x41, 0x00}, // I
+	{0x20, 0x40, 0x41, 0x3f, 0x01}, // J
+	{0x7f, 0x08, 0x14, 0x22, 0x41}, // K
+	{0x7f, 0x40, 0x40, 0x40, 0x40}, // L
+	{0x7f, 0x02, 0x0c, 0x02, 0x7f}, // M
+	{0x7f, 0x04, 0x08, 0x10, 0x7f}, // N
+	{0x3e, 0x41, 0x41, 0x41, 0x3e}, // O
+	{0x7f, 0x09, 0x09, 0x09, 0x06}, // P
+	{0x3e, 0x41, 0x51, 0x21, 0x5e}, // Q
+	{0x7f, 0x09, 0x19, 0x29, 0x46}, // R
+	{0x46, 0x49, 0x49, 0x49, 0x31}, // S
+	{0x01, 0x01, 0x7f, 0x01, 0x01}, // T
+	{0x3f, 0x40, 0x40, 0x40, 0x3f}, // U
+	{0x1f, 0x20, 0x40, 0x20, 0x1f}, // V
+	{0x3f, 0x40, 0x38, 0x40, 0x3f}, // W
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // X
+	{0x07, 0x08, 0x70, 0x08, 0x07}, // Y
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // Z
+	{0x00, 0x7f, 0x41, 0x41, 0x00}, // [
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // \
+	{0x00, 0x41, 0x41, 0x7f, 0x00}, // ]
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // ^
+	{0x40, 0x40, 0x40, 0x40, 0x40}, // _
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // `
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // a
+	{0x7f, 0x48, 0x44, 0x44, 0x38}, // b
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // c
+	{0x38, 0x44, 0x44, 0x48, 0x7f}, // d
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // e
+	{0x08, 0x7e, 0x09, 0x01, 0x02}, // f
+	{0x0c, 0x52, 0x52, 0x52, 0x3e}, // g
+	{0x7f, 0x08, 0x04, 0x04, 0x78}, // h
+	{0x00, 0x44, 0x7d, 0x40, 0x00}, // i
+	{0x20, 0x40, 0x44, 0x3d, 0x00}, // j
+	{0x7f, 0x10, 0x28, 0x44, 0x00}, // k
+	{0x00, 0x41, 0x7f, 0x40, 0x00}, // l
+	{0x7c, 0x04, 0x18, 0x04, 0x78}, // m
+	{0x7c, 0x08, 0x04, 0x04, 0x78}, // n
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // o
+	{0x7c, 0x14, 0x14, 0x14, 0x08}, // p
+	{0x08, 0x14, 0x14, 0x18, 0x7c}, // q
+	{0x7c, 0x08, 0x04, 0x04, 0x08}, // r
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // s
+	{0x04, 0x3f, 0x44, 0x40, 0x20}, // t
+	{0x3c, 0x40, 0x40, 0x20, 0x7c}, // u
+	{0x1c, 0x20, 0x40, 0x20, 0x1c}, // v
+	{0x3c, 0x40, 0x30, 0x40, 0x3c}, // w
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // x
+	{0x0c, 0x50, 0x50, 0x50, 0x3c}, // y
+	{0x44, 0x64, 0x54, 0x4c, 0x44}, // z
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // {
+	{0x00, 0x00, 0x7f, 0x00, 0x00}, // |
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // }
+	{0x08, 0x04, 0x08, 0x10, 0x08}, // ~
+}
+
+// fontExtra holds the other characters the modes draw as text.
+var fontExtra = map[rune][fontWidth]byte{
+	'·': {0x00, 0x00, 0x08, 0x00, 0x00},
+	'±': {0x48, 0x48, 0x7e, 0x48, 0x48},
+	'°': {0x00, 0x06, 0x09, 0x09, 0x06},
+	'•': {0x00, 0x1c, 0x1c, 0x1c, 0x00},
+	'▲': {0x40, 0x70, 0x7c, 0x70, 0x40},
+	'▼': {0x01, 0x07, 0x1f, 0x07, 0x01},
+	'◆': {0x08, 0x1c, 0x3e, 0x1c, 0x08},
+	'♥': {0x0c, 0x1e, 0x3c, 0x1e, 0x0c},
+}
+
+// glyph returns the font's columns for r. Characters missing from the font
+// get a made-up glyph built from the code point, so different ones still
+// look different (the katakana in matrix, say).
+func glyph(r rune) [fontWidth]byte {
+	if r >= ' ' && r <= '~' {
+		return fontASCII[r-' ']
+	}
+	if g, ok := fontExtra[r]; ok {
+		return g
+	}
+	var g [fontWidth]byte
+	h := uint32(r) * 2654435761
+	for i := range g {
+		g[i] = byte(h>>(i*6)) & 0x3e
+	}
+	return g
+}
+
+// Lines out from the middle of a cell, for box drawing characters.
+const (
+	boxLeft = 1 << iota
+	boxRight
+	boxUp
+	boxDown
+)
+
+// boxLines maps box drawing characters to the lines they draw. Heavy, double
+// and rounded ones are drawn like the light ones.
+var boxLines = map[rune]int{
+	'─': boxLeft | boxRight, '━': boxLeft | boxRight, '═': boxLeft | boxRight,
+	'│': boxUp | boxDown, '┃': boxUp | boxDown, '║': boxUp | boxDown,
+	'┌': boxRight | boxDown, '┏': boxRight | boxDown, '╔': boxRight | boxDown, '╭': boxRight | boxDown,
+	'┐': boxLeft | boxDown, '┓': boxLeft | boxDown, '╗': boxLeft | boxDown, '╮': boxLeft | boxDown,
+	'└': boxRight | boxUp, '┗': boxRight | boxUp, '╚': boxRight | boxUp, '╰': boxRight | boxUp,
+	'┘': boxLeft | boxUp, '┛': boxLeft | boxUp, '╝': boxLeft | boxUp, '╯': boxLeft | boxUp,
+	'├': boxUp | boxDown | boxRight, '┣': boxUp | boxDown | boxRight, '╠': boxUp | boxDown | boxRight,
+	'┤': boxUp | boxDown | boxLeft, '┫': boxUp | boxDown | boxLeft, '╣': boxUp | boxDown | boxLeft,
+	'┬': boxLeft | boxRight | boxDown, '┳': boxLeft | boxRight | boxDown, '╦': boxLeft | boxRight | boxDown,
+	'┴': boxLeft | boxRight | boxUp, '┻': boxLeft | boxRight | boxUp, '╩': boxLeft | boxRight | boxUp,
+	'┼': boxLeft | boxRight | boxUp | boxDown, '╋': boxLeft | boxRight | boxUp | boxDown, '╬': boxLeft | boxRight | boxUp | boxDown,
+}
+
+// quadrants maps the quadrant block characters from ▖ to ▟ to the quarters
+// they fill: upper left, upper right, lower left and lower right as bits 0-3.
+var quadrants = [10]int{0x4, 0x8, 0x1, 0xd, 0x9, 0x7, 0xb, 0x2, 0x6, 0xe}
+
+// fontPixel returns the size of a font pixel in a cell h pixels tall.
+func fontPixel(h int) int {
+	return max(h/(2*(fontWidth+1)), 1)
+}
+
+// cellPixels reports which pixels of a w x h cell are foreground when it
+// shows r. Block, shade, Braille and box drawing characters fill the cell the
+// way a terminal draws them; everything else comes from the font. Cells are
+// expected to be about twice as tall as they are wide, with room for a glyph
+// and a pixel of space around it.
+func cellPixels(r rune, w, h int) func(x, y int) bool {
+	px := fontPixel(h)
+	switch {
+	case r == ' ' || r == 0:
+		return func(x, y int) bool { return false }
+	case r == '█':
+		return func(x, y int) bool { return true }
+	case r == '▀':
+		return func(x, y int) bool { return y < h/2 }
+	case r == '▔':
+		return func(x, y int) bool { return y < h/8+1 }
+	case r >= '▁' && r <= '▇':
+		// Lower eighths
+		n := int(r-'▁') + 1
+		return func(x, y int) bool { return y >= h-h*n/8 }
+	case r >= '▉' && r <= '▏':
+		// Left eighths, from seven down to one
+		n := 7 - int(r-'▉')
+		return func(x, y int) bool { return x < max(w*n/8, 1) }
+	case r == '▐':
+		return func(x, y int) bool { return x >= w/2 }
+	case r == '░':
+		return func(x, y int) bool { return (x/px)%2 == 0 && (y/px)%2 == 0 }
+	case r == '▒':
+		return func(x, y int) bool { return (x/px+y/px)%2 == 0 }
+	case r == '▓':
+		return func(x, y int) bool { return (x/px)%2 == 0 || (y/px)%2 == 0 }
+	case r >= '▖' && r <= '▟':
+		q := quadrants[r-'▖']
+		return func(x, y int) bool {
+			bit := 0
+			if x >= w/2 {
+				bit++
+			}
+			if y >= h/2 {
+				bit += 2
+			}
+			return q&(1<<bit) != 0
+		}
+	case r >= 0x2800 && r <= 0x28ff:
+		return braillePixels(int(r-0x2800), w, h)
+	}
+	if lines, ok := boxLines[r]; ok {
+		// Lines a font pixel thick through the middle of the cell
+		cx, cy := (w-px)/2, (h-px)/2
+		return func(x, y int) bool {
+			horizontal := y >= cy && y < cy+px && (lines&boxLeft != 0 && x < cx+px || lines&boxRight != 0 && x >= cx)
+			vertical := x >= cx && x < cx+px && (lines&boxUp != 0 && y < cy+px || lines&boxDown != 0 && y >= cy)
+			return horizontal || vertical
+		}
+	}
+
+	// A glyph from the font, stretched across the cell's width (for wide
+	// runes) and centered vertically
+	g := glyph(r)
+	pw := max(w/(fontWidth+1), 1)
+	left, top := (w-fontWidth*pw)/2, (h-fontHeight*px)/2
+	return func(x, y int) bool {
+		if x < left || y < top {
+			return false
+		}
+		col, row := (x-left)/pw, (y-top)/px
+		if col >= fontWidth || row >= fontHeight {
+			return false
+		}
+		return g[col]&(1<<row) != 0
+	}
+}
+
+// braillePixels draws the dots of a Braille pattern as blocks on a 2x4 grid.
+func braillePixels(pattern, w, h int) func(x, y int) bool {
+	// Dot numbering runs down the left column, then the right, with the
+	// bottom row (dots 7 and 8) added last
+	bits := [4][2]int{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+	dw, dh := max(w/2, 1), max(h/4, 1)
+	return func(x, y int) bool {
+		col, row := x/dw, y/dh
+		if col > 1 || row > 3 {
+			return false
+		}
+		// Leave a gap around each dot so neighbouring dots stay apart
+		if dw > 1 && x%dw == dw-1 || dh > 1 && y%dh == dh-1 {
+			return false
+		}
+		return pattern&bits[row][col] != 0
+	}
+}
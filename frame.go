@@ -0,0 +1,57 @@
+package main
+
+import "github.com/gdamore/tcell/v2"
+
+// cell is the content of one screen cell.
+type cell struct {
+	r     rune
+	comb  []rune
+	style tcell.Style
+	width int // 2 for a wide rune, which also covers the cell to its right
+}
+
+// frame is a copy of everything on screen, so a drawn frame can be kept
+// around after the screen has moved on.
+type frame struct {
+	w, h  int
+	cells []cell
+}
+
+// captureFrame copies the current contents of the screen.
+func captureFrame(screen tcell.Screen) *frame {
+	w, h := screen.Size()
+	f := &frame{w: w, h: h, cells: make([]cell, w*h)}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, comb, style, width := screen.GetContent(x, y)
+			f.cells[y*w+x] = cell{r: r, comb: comb, style: style, width: width}
+		}
+	}
+	return f
+}
+
+// at returns the cell at x, y, or a blank cell outside the frame.
+func (f *frame) at(x, y int) cell {
+	if x < 0 || y < 0 || x >= f.w || y >= f.h {
+		return cell{r: ' ', style: tcell.StyleDefault, width: 1}
+	}
+	return f.cells[y*f.w+x]
+}
+
+// equal reports whether c and o look the same.
+func (c cell) equal(o cell) bool {
+	if c.r != o.r || c.style != o.style || c.width != o.width || len(c.comb) != len(o.comb) {
+		return false
+	}
+	for i := range c.comb {
+		if c.comb[i] != o.comb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// set draws c at x, y on the screen.
+func (c cell) set(screen tcell.Screen, x, y int) {
+	screen.SetContent(x, y, c.r, c.comb, c.style)
+}
@@ -2,14 +2,17 @@ module github.com/chorankates/termsaver
 
 go 1.21
 
-require github.com/gdamore/tcell/v2 v2.7.1
+require (
+	github.com/gdamore/tcell/v2 v2.7.1
+	github.com/mattn/go-runewidth v0.0.15
+	golang.org/x/crypto v0.21.0
+	golang.org/x/sys v0.18.0
+	golang.org/x/term v0.18.0
+)
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/rivo/uniseg v0.4.3 // indirect
-	golang.org/x/sys v0.17.0 // indirect
-	golang.org/x/term v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
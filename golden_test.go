@@ -0,0 +1,138 @@
+package main
+
+import "testing"
+
+func defaultEnv() *Env {
+	return &Env{Config: defaultConfig(), Theme: mustTheme("default", 1<<24)}
+}
+
+func mustTheme(name string, colors int) *Theme {
+	theme, ok := newTheme(name, colors)
+	if !ok {
+		panic("no theme " + name)
+	}
+	return theme
+}
+
+func TestGoldenFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		env    func() *Env
+		frames int
+	}{
+		{name: "matrix", mode: "matrix", frames: 30},
+		{name: "nyancat", mode: "nyancat", frames: 30},
+		{name: "snake", mode: "snake", frames: 60},
+		{name: "snake-interactive", mode: "snake", frames: 10, env: func() *Env {
+			env := defaultEnv()
+			env.Interactive = true
+			return env
+		}},
+		{name: "missiledefender", mode: "missiledefender", frames: 60},
+		{name: "towerdefense", mode: "towerdefense", frames: 80},
+		{name: "spectrograph", mode: "spectrograph", frames: 50},
+		{name: "snowflakes", mode: "snowflakes", frames: 80},
+		{name: "waterripple", mode: "waterripple", frames: 40},
+		{name: "lightning", mode: "lightning", frames: 32},
+		{name: "grayscale", mode: "spectrograph", frames: 20, env: func() *Env {
+			env := defaultEnv()
+			env.Theme = mustTheme("grayscale", 1<<24)
+			return env
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := defaultEnv()
+			if tt.env != nil {
+				env = tt.env()
+			}
+			h := newHarness(t, tt.mode, env, 60, 20, 1)
+			h.Step(tt.frames)
+			checkGolden(t, tt.name, h.Frame())
+		})
+	}
+}
+
+func TestGoldenFramesAfterResize(t *testing.T) {
+	for _, mode := range modeNames() {
+		t.Run(mode, func(t *testing.T) {
+			h := newHarness(t, mode, defaultEnv(), 60, 20, 1)
+			h.Step(10)
+			h.Resize(40, 12)
+			h.Step(10)
+			checkGolden(t, "resize-"+mode, h.Frame())
+		})
+	}
+}
+
+func TestGoldenFramesAreDeterministic(t *testing.T) {
+	for _, mode := range modeNames() {
+		t.Run(mode, func(t *testing.T) {
+			first := newHarness(t, mode, defaultEnv(), 40, 15, 7)
+			first.Step(25)
+			second := newHarness(t, mode, defaultEnv(), 40, 15, 7)
+			second.Step(25)
+			if first.Frame() != second.Frame() {
+				t.Errorf("two runs with the same seed produced different frames")
+			}
+		})
+	}
+}
+
+// TestRedrawsDontChangeFrames checks that drawing a frame more often, as a
+// status message, transition or overlay makes the runner do, leaves the
+// run the seed gives alone.
+func TestRedrawsDontChangeFrames(t *testing.T) {
+	for _, mode := range modeNames() {
+		t.Run(mode, func(t *testing.T) {
+			for _, interactive := range []bool{false, true} {
+				env := defaultEnv()
+				env.Interactive = interactive
+				first := newHarness(t, mode, env, 40, 15, 7)
+				env = defaultEnv()
+				env.Interactive = interactive
+				second := newHarness(t, mode, env, 40, 15, 7)
+				for i := 0; i < 100; i++ {
+					first.Step(1)
+					second.Step(1)
+					second.session.dirty = true
+					second.session.renderFrame(second.mode, second.sched)
+				}
+				if first.Frame() != second.Frame() {
+					t.Errorf("extra redraws changed the frame (interactive %v)", interactive)
+				}
+			}
+		})
+	}
+}
+
+func TestSeedChangesFrames(t *testing.T) {
+	first := newHarness(t, "matrix", defaultEnv(), 40, 15, 1)
+	first.Step(20)
+	second := newHarness(t, "matrix", defaultEnv(), 40, 15, 2)
+	second.Step(20)
+	if first.Frame() == second.Frame() {
+		t.Errorf("different seeds produced the same frame")
+	}
+}
+
+// TestModesSurviveSmallScreens checks no mode panics on a screen too small to
+// show it properly, which a lock screen would otherwise end on.
+func TestModesSurviveSmallScreens(t *testing.T) {
+	for _, mode := range modeNames() {
+		t.Run(mode, func(t *testing.T) {
+			for _, size := range []Point{{0, 0}, {1, 1}, {3, 3}, {80, 3}, {3, 24}} {
+				for _, interactive := range []bool{false, true} {
+					env := defaultEnv()
+					env.Interactive = interactive
+					h := newHarness(t, mode, env, size.X, size.Y, 1)
+					h.Step(300)
+					h.Resize(size.Y, size.X)
+					h.Step(50)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden frames in testdata/golden")
+
+// harness runs a single mode against a simulation screen with a fixed seed,
+// driving the same scheduler the runner uses from a fake clock.
+type harness struct {
+	t       *testing.T
+	screen  tcell.SimulationScreen
+	clock   *manualClock
+	session *session
+	sched   *scheduler
+	info    modeInfo
+	mode    Mode
+}
+
+func newHarness(t *testing.T, name string, env *Env, w, h int, seed int64) *harness {
+	t.Helper()
+	info, ok := lookupModeInfo(name)
+	if !ok {
+		t.Fatalf("mode %q is not registered", name)
+	}
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("initializing simulation screen: %v", err)
+	}
+	t.Cleanup(screen.Fini)
+	screen.SetSize(w, h)
+
+	clock := newManualClock()
+	env.Rand = rand.New(rand.NewSource(seed))
+
+	mode := info.new()
+	mode.Init(env, w, h)
+	return &harness{
+		t:       t,
+		screen:  screen,
+		clock:   clock,
+		session: &session{screen: screen, clock: clock, fps: defaultFPS, speed: 1, env: env},
+		sched:   newScheduler(clock, info.step, defaultFPS, 1),
+		info:    info,
+		mode:    mode,
+	}
+}
+
+func lookupModeInfo(name string) (modeInfo, bool) {
+	i, ok := lookupMode(name)
+	if !ok {
+		return modeInfo{}, false
+	}
+	return registry[i], true
+}
+
+// Step runs n simulation steps of the mode, rendering a frame after each.
+func (h *harness) Step(n int) {
+	for i := 0; i < n; i++ {
+		h.clock.Advance(h.info.step)
+		h.session.renderFrame(h.mode, h.sched)
+	}
+}
+
+// Run advances the fake clock by d one rendered frame at a time and returns
+// how many frames were drawn.
+func (h *harness) Run(d time.Duration) int {
+	frames := 0
+	for end := h.clock.Now().Add(d); h.clock.Now().Before(end); {
+		h.clock.Advance(h.sched.frame)
+		if h.session.renderFrame(h.mode, h.sched) {
+			frames++
+		}
+	}
+	return frames
+}
+
+// Resize changes the simulated terminal size and tells the mode about it.
+func (h *harness) Resize(w, ht int) {
+	h.screen.SetSize(w, ht)
+	h.mode.Resize(w, ht)
+}
+
+// Frame renders the current cell grid as text: one line per row followed by
+// a grid of style keys and a legend mapping each key to its colors.
+func (h *harness) Frame() string {
+	w, ht := h.screen.Size()
+	keys := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	styleKeys := map[tcell.Style]byte{}
+	var legend []string
+
+	var text, styles strings.Builder
+	for y := 0; y < ht; y++ {
+		for x := 0; x < w; x++ {
+			r, _, style, width := h.screen.GetContent(x, y)
+			if r == 0 {
+				r = ' '
+			}
+			key, ok := styleKeys[style]
+			if !ok {
+				key = '?'
+				if len(styleKeys) < len(keys) {
+					key = keys[len(styleKeys)]
+				}
+				styleKeys[style] = key
+				legend = append(legend, fmt.Sprintf("%c %s", key, describeStyle(style)))
+			}
+			text.WriteRune(r)
+			styles.WriteByte(key)
+			if width == 2 {
+				styles.WriteByte(key)
+				x++
+			}
+		}
+		text.WriteByte('\n')
+		styles.WriteByte('\n')
+	}
+	return text.String() + "\n" + styles.String() + "\n" + strings.Join(legend, "\n") + "\n"
+}
+
+func describeStyle(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+	return fmt.Sprintf("fg=%s bg=%s attrs=%d", describeColor(fg), describeColor(bg), attrs)
+}
+
+func describeColor(c tcell.Color) string {
+	if c == tcell.ColorDefault {
+		return "default"
+	}
+	return fmt.Sprintf("#%06x", c.Hex())
+}
+
+// checkGolden compares got against testdata/golden/<name>.golden, or rewrites
+// the file when the test is run with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden frame (run go test -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("frame does not match %s (run go test -update to accept)\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
@@ -3,12 +3,27 @@ package main
 import (
 	"math"
 	"math/rand"
-	"os"
 	"time"
 
+	"github.com/chorankates/termsaver/drawing"
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	registerMode(modeInfo{
+		name:        "lightning",
+		description: "drifting storm clouds throwing fractal lightning bolts",
+		step:        100 * time.Millisecond,
+		new:         func() Mode { return &LightningStorm{} },
+	})
+}
+
+// LightningConfig is the [lightning] section of the config file.
+type LightningConfig struct {
+	MaxClouds     int `json:"max_clouds"`
+	MaxLightnings int `json:"max_lightnings"` // Bolts visible at once
+}
+
 type Cloud struct {
 	x      float64 // X position (center)
 	y      float64 // Y position (top of cloud)
@@ -44,332 +59,348 @@ type LightningBranch struct {
 	segmentOrder int     // Order in the main path (0 = first, increases down)
 }
 
-func runLightning(screen tcell.Screen, sigChan chan os.Signal, interactive bool, grayscale bool) bool {
-	w, h := screen.Size()
+type LightningStorm struct {
+	env  *Env
+	rng  *rand.Rand
+	w, h int
 
-	clouds := make([]Cloud, 0)
-	maxClouds := 3
-	lightnings := make([]Lightning, 0)
-	maxLightnings := 2
+	clouds        []Cloud
+	maxClouds     int
+	lightnings    []Lightning
+	maxLightnings int
 
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+	sinceCloudSpawn    time.Duration
+	cloudSpawnInterval time.Duration
 
-	lastCloudSpawn := time.Now().Add(-3000 * time.Millisecond) // Spawn first cloud immediately
-	cloudSpawnInterval := 500 * time.Millisecond
+	sinceLightning    time.Duration
+	lightningInterval time.Duration
 
-	lastLightningTime := time.Now()
-	lightningInterval := time.Duration(1500+rand.Intn(2000)) * time.Millisecond // 1.5-3.5 seconds
+	canvas *drawing.Canvas // Bolts are drawn at Braille resolution
+	tick   int             // Steps taken, which the flicker is drawn from
+}
 
-	// Event handling for resize and exit
-	eventChan := make(chan tcell.Event, 10)
-	go func() {
-		for {
-			eventChan <- screen.PollEvent()
-		}
-	}()
-
-	rand.Seed(time.Now().UnixNano())
-
-	for {
-		select {
-		case <-sigChan:
-			return false
-		case event := <-eventChan:
-			switch ev := event.(type) {
-			case *tcell.EventResize:
-				w, h = screen.Size()
-				// Remove clouds/lightnings that are out of bounds
-				validClouds := make([]Cloud, 0)
-				for _, cloud := range clouds {
-					if cloud.x+float64(cloud.width) >= 0 && cloud.x < float64(w) && cloud.y < float64(h) {
-						validClouds = append(validClouds, cloud)
-					}
-				}
-				clouds = validClouds
-				screen.Sync()
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
-					return false
-				}
-				// Space cycles to next mode
-				if ev.Rune() == ' ' {
-					return true
-				}
-				// In non-interactive mode, any key exits
-				if !interactive {
-					return false
-				}
-			}
-		case <-ticker.C:
-			// Spawn new clouds
-			if len(clouds) < maxClouds && time.Since(lastCloudSpawn) >= cloudSpawnInterval {
-				// Spawn clouds at random heights in the upper portion of screen
-				cloudY := float64(rand.Intn(h / 4)) // Top quarter of screen
-				cloudWidth := 30 + rand.Intn(40)    // 30-70 characters wide (much wider)
-				cloudHeight := 4 + rand.Intn(4)     // 4-8 layers
-				speed := 0.2 + rand.Float64()*0.3   // 0.2-0.5 speed (slower for bigger clouds)
-
-				// Create stacked layers that increase in width (normal cloud orientation)
-				layers := make([]CloudLayer, cloudHeight)
-				for i := 0; i < cloudHeight; i++ {
-					// Each layer is wider as we go down
-					// Top layer (i=0) is narrowest, bottom layer is widest
-					layerPercent := 0.5 + (float64(i) / float64(cloudHeight-1) * 0.5) // 50% to 100%
-					if cloudHeight == 1 {
-						layerPercent = 1.0
-					}
-					layerWidth := int(float64(cloudWidth) * layerPercent)
-					if layerWidth < 5 {
-						layerWidth = 5
-					}
-					// Center the layer
-					offset := (cloudWidth - layerWidth) / 2
-					layers[i] = CloudLayer{
-						width:  layerWidth,
-						offset: offset,
-					}
-				}
+func (l *LightningStorm) Init(env *Env, w, h int) {
+	l.env = env
+	l.rng = env.Rand
+	l.w, l.h = w, h
 
-				// Spawn from left or right edge
-				var startX float64
-				if rand.Float64() < 0.5 {
-					startX = -float64(cloudWidth) // Start off-screen left
-				} else {
-					startX = float64(w) // Start off-screen right
-					speed = -speed      // Move left
-				}
+	l.clouds = make([]Cloud, 0)
+	l.maxClouds = env.Config.Lightning.MaxClouds
+	l.lightnings = make([]Lightning, 0)
+	l.maxLightnings = env.Config.Lightning.MaxLightnings
 
-				clouds = append(clouds, Cloud{
-					x:      startX,
-					y:      cloudY,
-					width:  cloudWidth,
-					height: cloudHeight,
-					speed:  speed,
-					active: true,
-					layers: layers,
-				})
-				lastCloudSpawn = time.Now()
-				cloudSpawnInterval = time.Duration(2000+rand.Intn(3000)) * time.Millisecond
-			}
+	l.sinceCloudSpawn = 3000 * time.Millisecond // Spawn first cloud immediately
+	l.cloudSpawnInterval = 500 * time.Millisecond
 
-			// Update clouds
-			for i := range clouds {
-				if clouds[i].active {
-					clouds[i].x += clouds[i].speed
-					// Deactivate clouds that have moved off-screen
-					// Cloud x is the left edge, width extends to the right
-					if clouds[i].x+float64(clouds[i].width) < 0 || clouds[i].x > float64(w) {
-						clouds[i].active = false
-					}
-				}
-			}
+	l.sinceLightning = 0
+	l.lightningInterval = time.Duration(1500+l.rng.Intn(2000)) * time.Millisecond // 1.5-3.5 seconds
+}
 
-			// Remove inactive clouds
-			activeClouds := make([]Cloud, 0)
-			for _, cloud := range clouds {
-				if cloud.active {
-					activeClouds = append(activeClouds, cloud)
-				}
-			}
-			clouds = activeClouds
-
-			// Spawn lightning from clouds (100% from clouds)
-			if len(lightnings) < maxLightnings && time.Since(lastLightningTime) >= lightningInterval && len(clouds) > 0 {
-				// Pick a random cloud
-				cloudIdx := rand.Intn(len(clouds))
-				cloud := clouds[cloudIdx]
-
-				// Lightning emerges from bottom of cloud, random x within cloud width
-				lightningX := cloud.x + float64(rand.Intn(cloud.width))
-				lightningY := cloud.y + float64(cloud.height) // Bottom of cloud
-
-				// Create lightning with more branches (fractal)
-				branches := generateFractalLightning(lightningX, lightningY, w, h, 0)
-
-				lightnings = append(lightnings, Lightning{
-					x:        lightningX,
-					y:        lightningY,
-					age:      0.0,
-					active:   true,
-					branches: branches,
-					cloudIdx: cloudIdx,
-				})
-				lastLightningTime = time.Now()
-				lightningInterval = time.Duration(1500+rand.Intn(2000)) * time.Millisecond
+func (l *LightningStorm) Reconfigure(env *Env) {
+	l.maxClouds = env.Config.Lightning.MaxClouds
+	l.maxLightnings = env.Config.Lightning.MaxLightnings
+}
+
+func (l *LightningStorm) Resize(w, h int) {
+	l.w, l.h = w, h
+	l.canvas = nil
+	// Remove clouds/lightnings that are out of bounds
+	validClouds := make([]Cloud, 0)
+	for _, cloud := range l.clouds {
+		if cloud.x+float64(cloud.width) >= 0 && cloud.x < float64(w) && cloud.y < float64(h) {
+			validClouds = append(validClouds, cloud)
+		}
+	}
+	l.clouds = validClouds
+}
+
+func (l *LightningStorm) HandleEvent(ev tcell.Event) bool {
+	return false
+}
+
+func (l *LightningStorm) Update(dt time.Duration) {
+	w, h := l.w, l.h
+	l.tick++
+	l.sinceCloudSpawn += dt
+	l.sinceLightning += dt
+
+	// Spawn new clouds
+	if len(l.clouds) < l.maxClouds && l.sinceCloudSpawn >= l.cloudSpawnInterval {
+		l.spawnCloud()
+		l.sinceCloudSpawn = 0
+		l.cloudSpawnInterval = time.Duration(2000+l.rng.Intn(3000)) * time.Millisecond
+	}
+
+	// Update clouds
+	clouds := l.clouds
+	for i := range clouds {
+		if clouds[i].active {
+			clouds[i].x += clouds[i].speed
+			// Deactivate clouds that have moved off-screen
+			// Cloud x is the left edge, width extends to the right
+			if clouds[i].x+float64(clouds[i].width) < 0 || clouds[i].x > float64(w) {
+				clouds[i].active = false
 			}
+		}
+	}
 
-			// Update lightnings
-			for i := range lightnings {
-				if lightnings[i].active {
-					lightnings[i].age += 1.0
-
-					// Update branch progress - lightning travels down at ~15 units per frame
-					lightningSpeed := 15.0
-					for j := range lightnings[i].branches {
-						branch := &lightnings[i].branches[j]
-
-						// Calculate branch length
-						dx := branch.endX - branch.startX
-						dy := branch.endY - branch.startY
-						branchLength := math.Sqrt(dx*dx + dy*dy)
-
-						if branchLength > 0 {
-							// Check if parent branch has reached this branch's start point
-							canProgress := true
-							if branch.parentIdx >= 0 && branch.parentIdx < len(lightnings[i].branches) {
-								parent := lightnings[i].branches[branch.parentIdx]
-								// Side branch can only start when parent has reached at least 0.8 progress
-								// (allowing some overlap)
-								if parent.progress < 0.8 {
-									canProgress = false
-								}
-							}
+	// Remove inactive clouds
+	activeClouds := make([]Cloud, 0)
+	for _, cloud := range clouds {
+		if cloud.active {
+			activeClouds = append(activeClouds, cloud)
+		}
+	}
+	l.clouds = activeClouds
+
+	// Spawn lightning from clouds (100% from clouds)
+	if len(l.lightnings) < l.maxLightnings && l.sinceLightning >= l.lightningInterval && len(l.clouds) > 0 {
+		// Pick a random cloud
+		cloudIdx := l.rng.Intn(len(l.clouds))
+		cloud := l.clouds[cloudIdx]
+
+		// Lightning emerges from bottom of cloud, random x within cloud width
+		lightningX := cloud.x + float64(l.rng.Intn(cloud.width))
+		lightningY := cloud.y + float64(cloud.height) // Bottom of cloud
+
+		// Create lightning with more branches (fractal)
+		branches := generateFractalLightning(l.rng, lightningX, lightningY, w, h, 0)
+
+		l.lightnings = append(l.lightnings, Lightning{
+			x:        lightningX,
+			y:        lightningY,
+			age:      0.0,
+			active:   true,
+			branches: branches,
+			cloudIdx: cloudIdx,
+		})
+		l.sinceLightning = 0
+		l.lightningInterval = time.Duration(1500+l.rng.Intn(2000)) * time.Millisecond
+	}
 
-							if canProgress && branch.progress < 1.0 {
-								// Increment progress based on speed
-								progressIncrement := lightningSpeed / branchLength
-								branch.progress += progressIncrement
-								if branch.progress > 1.0 {
-									branch.progress = 1.0
-								}
-							}
+	// Update lightnings
+	lightnings := l.lightnings
+	for i := range lightnings {
+		if lightnings[i].active {
+			lightnings[i].age += 1.0
+
+			// Update branch progress - lightning travels down at ~15 units per frame
+			lightningSpeed := 15.0
+			for j := range lightnings[i].branches {
+				branch := &lightnings[i].branches[j]
+
+				// Calculate branch length
+				dx := branch.endX - branch.startX
+				dy := branch.endY - branch.startY
+				branchLength := math.Sqrt(dx*dx + dy*dy)
+
+				if branchLength > 0 {
+					// Check if parent branch has reached this branch's start point
+					canProgress := true
+					if branch.parentIdx >= 0 && branch.parentIdx < len(lightnings[i].branches) {
+						parent := lightnings[i].branches[branch.parentIdx]
+						// Side branch can only start when parent has reached at least 0.8 progress
+						// (allowing some overlap)
+						if parent.progress < 0.8 {
+							canProgress = false
 						}
 					}
 
-					// Lightning flashes briefly (2-4 frames)
-					if lightnings[i].age > 2.0+rand.Float64()*2.0 {
-						lightnings[i].active = false
+					if canProgress && branch.progress < 1.0 {
+						// Increment progress based on speed
+						progressIncrement := lightningSpeed / branchLength
+						branch.progress += progressIncrement
+						if branch.progress > 1.0 {
+							branch.progress = 1.0
+						}
 					}
 				}
 			}
 
-			// Remove inactive lightnings
-			activeLightnings := make([]Lightning, 0)
-			for _, lightning := range lightnings {
-				if lightning.active {
-					activeLightnings = append(activeLightnings, lightning)
-				}
+			// Lightning flashes briefly (2-4 frames)
+			if lightnings[i].age > 2.0+l.rng.Float64()*2.0 {
+				lightnings[i].active = false
 			}
-			lightnings = activeLightnings
+		}
+	}
 
-			// Draw
-			screen.Clear()
+	// Remove inactive lightnings
+	activeLightnings := make([]Lightning, 0)
+	for _, lightning := range lightnings {
+		if lightning.active {
+			activeLightnings = append(activeLightnings, lightning)
+		}
+	}
+	l.lightnings = activeLightnings
+}
 
-			// Check which clouds have active lightning
-			activeLightningClouds := make(map[int]bool)
-			for _, lightning := range lightnings {
-				if lightning.active && lightning.cloudIdx >= 0 && lightning.cloudIdx < len(clouds) {
-					activeLightningClouds[lightning.cloudIdx] = true
-				}
-			}
+// spawnCloud adds a cloud at a random height in the upper portion of the
+// screen, entering from the left or right edge.
+func (l *LightningStorm) spawnCloud() {
+	cloudY := float64(l.rng.Intn(max(l.h/4, 1))) // Top quarter of screen
+	cloudWidth := 30 + l.rng.Intn(40)            // 30-70 characters wide (much wider)
+	cloudHeight := 4 + l.rng.Intn(4)             // 4-8 layers
+	speed := 0.2 + l.rng.Float64()*0.3           // 0.2-0.5 speed (slower for bigger clouds)
+
+	// Create stacked layers that increase in width (normal cloud orientation)
+	layers := make([]CloudLayer, cloudHeight)
+	for i := 0; i < cloudHeight; i++ {
+		// Each layer is wider as we go down
+		// Top layer (i=0) is narrowest, bottom layer is widest
+		layerPercent := 0.5 + (float64(i) / float64(cloudHeight-1) * 0.5) // 50% to 100%
+		if cloudHeight == 1 {
+			layerPercent = 1.0
+		}
+		layerWidth := int(float64(cloudWidth) * layerPercent)
+		if layerWidth < 5 {
+			layerWidth = 5
+		}
+		// Center the layer
+		offset := (cloudWidth - layerWidth) / 2
+		layers[i] = CloudLayer{
+			width:  layerWidth,
+			offset: offset,
+		}
+	}
 
-			// Draw clouds
-			cloudStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorGray, grayscale)).Background(tcell.ColorBlack)
-			darkCloudStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorDarkGray, grayscale)).Background(tcell.ColorBlack)
-			litCloudStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-			litCloudMediumStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorLightYellow, grayscale)).Background(tcell.ColorBlack)
-			cloudChars := []rune{'█', '▓', '▒'}
+	// Spawn from left or right edge
+	var startX float64
+	if l.rng.Float64() < 0.5 {
+		startX = -float64(cloudWidth) // Start off-screen left
+	} else {
+		startX = float64(l.w) // Start off-screen right
+		speed = -speed        // Move left
+	}
 
-			for cloudIdx, cloud := range clouds {
-				if !cloud.active {
-					continue
-				}
+	l.clouds = append(l.clouds, Cloud{
+		x:      startX,
+		y:      cloudY,
+		width:  cloudWidth,
+		height: cloudHeight,
+		speed:  speed,
+		active: true,
+		layers: layers,
+	})
+}
 
-				cloudStartX := int(cloud.x)
-				cloudStartY := int(cloud.y)
-				isLit := activeLightningClouds[cloudIdx]
+func (l *LightningStorm) Draw(screen tcell.Screen) {
+	w, h := l.w, l.h
+	theme := l.env.Theme
 
-				// Draw cloud as stacked rectangles
-				for layerIdx, layer := range cloud.layers {
-					y := cloudStartY + layerIdx
-					if y < 0 || y >= h {
-						continue
+	// Check which clouds have active lightning
+	activeLightningClouds := make(map[int]bool)
+	for _, lightning := range l.lightnings {
+		if lightning.active && lightning.cloudIdx >= 0 && lightning.cloudIdx < len(l.clouds) {
+			activeLightningClouds[lightning.cloudIdx] = true
+		}
+	}
+
+	// Draw clouds
+	cloudStyle := theme.Style(RoleDim)
+	darkCloudStyle := theme.Style(RoleMuted)
+	litCloudStyle := theme.Style(RoleBright)
+	litCloudMediumStyle := theme.Style(RoleAccentSoft)
+	cloudChars := []rune{'█', '▓', '▒'}
+
+	for cloudIdx, cloud := range l.clouds {
+		if !cloud.active {
+			continue
+		}
+
+		cloudStartX := int(cloud.x)
+		cloudStartY := int(cloud.y)
+		isLit := activeLightningClouds[cloudIdx]
+
+		// Draw cloud as stacked rectangles
+		for layerIdx, layer := range cloud.layers {
+			y := cloudStartY + layerIdx
+			if y < 0 || y >= h {
+				continue
+			}
+
+			// Draw this layer
+			layerStartX := cloudStartX + layer.offset
+			for dx := 0; dx < layer.width; dx++ {
+				x := layerStartX + dx
+				if x >= 0 && x < w {
+					// Add some texture variation
+					var char rune
+					var style tcell.Style
+
+					randVal := tickNoise(x, y, 2*l.tick)
+					if randVal < 0.6 {
+						char = cloudChars[0] // █ (solid)
+						if isLit {
+							style = litCloudStyle
+						} else {
+							style = cloudStyle
+						}
+					} else if randVal < 0.85 {
+						char = cloudChars[1] // ▓ (medium)
+						if isLit {
+							style = litCloudMediumStyle
+						} else {
+							style = cloudStyle
+						}
+					} else {
+						char = cloudChars[2] // ▒ (light)
+						if isLit {
+							style = litCloudMediumStyle
+						} else {
+							style = darkCloudStyle
+						}
 					}
 
-					// Draw this layer
-					layerStartX := cloudStartX + layer.offset
-					for dx := 0; dx < layer.width; dx++ {
-						x := layerStartX + dx
-						if x >= 0 && x < w {
-							// Add some texture variation
-							var char rune
-							var style tcell.Style
-
-							randVal := rand.Float64()
-							if randVal < 0.6 {
-								char = cloudChars[0] // █ (solid)
-								if isLit {
-									style = litCloudStyle
-								} else {
-									style = cloudStyle
-								}
-							} else if randVal < 0.85 {
-								char = cloudChars[1] // ▓ (medium)
-								if isLit {
-									style = litCloudMediumStyle
-								} else {
-									style = cloudStyle
-								}
+					// Edges are more wispy
+					if dx < 2 || dx >= layer.width-2 {
+						if tickNoise(x, y, 2*l.tick+1) < 0.5 {
+							char = cloudChars[2] // ▒
+							if isLit {
+								style = litCloudMediumStyle
 							} else {
-								char = cloudChars[2] // ▒ (light)
-								if isLit {
-									style = litCloudMediumStyle
-								} else {
-									style = darkCloudStyle
-								}
-							}
-
-							// Edges are more wispy
-							if dx < 2 || dx >= layer.width-2 {
-								if rand.Float64() < 0.5 {
-									char = cloudChars[2] // ▒
-									if isLit {
-										style = litCloudMediumStyle
-									} else {
-										style = darkCloudStyle
-									}
-								}
+								style = darkCloudStyle
 							}
-
-							screen.SetContent(x, y, char, nil, style)
 						}
 					}
+
+					screen.SetContent(x, y, char, nil, style)
 				}
 			}
+		}
+	}
 
-			// Draw lightning with glow
-			lightningStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorYellow, grayscale)).Background(tcell.ColorBlack)
-			brightLightningStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-			glowStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorDarkCyan, grayscale)).Background(tcell.ColorBlack)
-			glowMediumStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorBlue, grayscale)).Background(tcell.ColorBlack)
-
-			for _, lightning := range lightnings {
-				if !lightning.active {
-					continue
-				}
+	// Draw lightning with glow
+	glowStyle := theme.Style(RoleCoolDim)
+	glowMediumStyle := theme.Style(RoleCool)
+	if l.canvas == nil {
+		l.canvas = drawing.NewCanvas(drawing.Braille, w, h)
+	}
+	l.canvas.Clear()
 
-				// First pass: Draw glow around lightning (only drawn portions)
-				for _, branch := range lightning.branches {
-					if branch.progress > 0 {
-						drawLightningGlow(screen, branch, glowStyle, glowMediumStyle, w, h, grayscale)
-					}
-				}
+	for _, lightning := range l.lightnings {
+		if !lightning.active {
+			continue
+		}
 
-				// Second pass: Draw the lightning itself (only drawn portions)
-				for _, branch := range lightning.branches {
-					if branch.progress > 0 {
-						drawLightningBranch(screen, branch, lightningStyle, brightLightningStyle, w, h)
-					}
-				}
+		// First pass: Draw glow around lightning (only drawn portions)
+		for _, branch := range lightning.branches {
+			if branch.progress > 0 {
+				drawLightningGlow(screen, l.tick, branch, glowStyle, glowMediumStyle, w, h)
 			}
+		}
 
-			screen.Show()
+		// Second pass: Draw the lightning itself (only drawn portions) on the
+		// canvas, which goes over the glow
+		for _, branch := range lightning.branches {
+			if branch.progress > 0 {
+				drawLightningBranch(l.canvas, l.tick, branch, theme.Color(RoleAccent), theme.Color(RoleBright))
+			}
 		}
 	}
+	l.canvas.Draw(screen, 0, 0, theme.Color(RoleBackground))
 }
 
-func generateFractalLightning(startX, startY float64, w, h int, depth int) []LightningBranch {
+func generateFractalLightning(rng *rand.Rand, startX, startY float64, w, h int, depth int) []LightningBranch {
 	branches := make([]LightningBranch, 0)
 
 	// Maximum recursion depth
@@ -388,7 +419,7 @@ func generateFractalLightning(startX, startY float64, w, h int, depth int) []Lig
 	}
 
 	// Create jagged path down
-	segments := 6 + rand.Intn(6) // 6-12 segments
+	segments := 6 + rng.Intn(6) // 6-12 segments
 	remainingDist := targetY - startY
 	if remainingDist <= 0 {
 		return branches
@@ -404,7 +435,7 @@ func generateFractalLightning(startX, startY float64, w, h int, depth int) []Lig
 
 	for i := 0; i < segments; i++ {
 		// Add horizontal jitter
-		jitter := (rand.Float64() - 0.5) * 4.0 // ±2 characters
+		jitter := (rng.Float64() - 0.5) * 4.0 // ±2 characters
 		currentX += jitter
 		currentY += segmentLength
 
@@ -439,10 +470,10 @@ func generateFractalLightning(startX, startY float64, w, h int, depth int) []Lig
 		})
 
 		// Create side branches more frequently (fractal branching)
-		if rand.Float64() < 0.6 && i < segments-1 && currentY < targetY-5 {
+		if rng.Float64() < 0.6 && i < segments-1 && currentY < targetY-5 {
 			// Determine branch direction and length
-			branchAngle := (rand.Float64() - 0.5) * 1.5 // -0.75 to 0.75
-			branchLength := 5.0 + rand.Float64()*10.0   // 5-15 units
+			branchAngle := (rng.Float64() - 0.5) * 1.5 // -0.75 to 0.75
+			branchLength := 5.0 + rng.Float64()*10.0   // 5-15 units
 
 			branchEndX := currentX + branchAngle*branchLength
 			branchEndY := currentY + branchLength*0.7 // Go mostly down
@@ -464,9 +495,9 @@ func generateFractalLightning(startX, startY float64, w, h int, depth int) []Lig
 				})
 
 				// Recursively create sub-branches (fractal)
-				if depth < 2 && rand.Float64() < 0.5 {
+				if depth < 2 && rng.Float64() < 0.5 {
 					sideBranchIdx := len(branches) - 1
-					subBranches := generateFractalLightning(branchEndX, branchEndY, w, h, depth+1)
+					subBranches := generateFractalLightning(rng, branchEndX, branchEndY, w, h, depth+1)
 					// Update parent indices for sub-branches - first branch should point to side branch
 					for k := range subBranches {
 						if subBranches[k].parentIdx == -1 {
@@ -494,7 +525,7 @@ func generateFractalLightning(startX, startY float64, w, h int, depth int) []Lig
 	return branches
 }
 
-func drawLightningGlow(screen tcell.Screen, branch LightningBranch, glowStyle, glowMediumStyle tcell.Style, w, h int, grayscale bool) {
+func drawLightningGlow(screen tcell.Screen, tick int, branch LightningBranch, glowStyle, glowMediumStyle tcell.Style, w, h int) {
 	// Draw soft glow around the lightning path (only drawn portion)
 	dx := branch.endX - branch.startX
 	dy := branch.endY - branch.startY
@@ -552,7 +583,7 @@ func drawLightningGlow(screen tcell.Screen, branch LightningBranch, glowStyle, g
 					}
 
 					// Randomly skip some glow for organic effect
-					if rand.Float64() < 0.6 {
+					if tickNoise(x, y, tick) < 0.6 {
 						screen.SetContent(x, y, glowChar, nil, style)
 					}
 				}
@@ -561,55 +592,21 @@ func drawLightningGlow(screen tcell.Screen, branch LightningBranch, glowStyle, g
 	}
 }
 
-func drawLightningBranch(screen tcell.Screen, branch LightningBranch, style, brightStyle tcell.Style, w, h int) {
-	// Draw line from start to end (only drawn portion)
-	dx := branch.endX - branch.startX
-	dy := branch.endY - branch.startY
-	length := math.Sqrt(dx*dx + dy*dy)
-
-	if length == 0 {
-		return
-	}
-
-	steps := int(length) + 1
-	if steps < 1 {
-		steps = 1
+// drawLightningBranch draws the part of a branch the bolt has reached as a
+// thin line on a Braille canvas.
+func drawLightningBranch(canvas *drawing.Canvas, tick int, branch LightningBranch, color, brightColor tcell.Color) {
+	// Alternate between bright and normal for flicker effect
+	if tickNoise(int(branch.startX), int(branch.startY), tick) < 0.3 {
+		color = brightColor
 	}
 
 	// Only draw up to the current progress
-	maxStep := int(float64(steps) * branch.progress)
-
-	lightningChars := []rune{'|', '/', '\\', '╲', '╱'}
-
-	for i := 0; i <= maxStep; i++ {
-		t := float64(i) / float64(steps)
-		x := branch.startX + dx*t
-		y := branch.startY + dy*t
-
-		xInt := int(x)
-		yInt := int(y)
-
-		if xInt >= 0 && xInt < w && yInt >= 0 && yInt < h {
-			// Choose character based on direction
-			var char rune
-			if math.Abs(dx) < 0.1 {
-				char = lightningChars[0] // |
-			} else if dx > 0 && dy > 0 {
-				char = lightningChars[1] // /
-			} else if dx < 0 && dy > 0 {
-				char = lightningChars[2] // \
-			} else if dx > 0 {
-				char = lightningChars[3] // ╲
-			} else {
-				char = lightningChars[4] // ╱
-			}
-
-			// Alternate between bright and normal for flicker effect
-			if rand.Float64() < 0.3 {
-				screen.SetContent(xInt, yInt, char, nil, brightStyle)
-			} else {
-				screen.SetContent(xInt, yInt, char, nil, style)
-			}
-		}
-	}
+	sx, sy := canvas.Scale()
+	endX := branch.startX + (branch.endX-branch.startX)*branch.progress
+	endY := branch.startY + (branch.endY-branch.startY)*branch.progress
+	canvas.Line(
+		(branch.startX+0.5)*float64(sx), (branch.startY+0.5)*float64(sy),
+		(endX+0.5)*float64(sx), (endY+0.5)*float64(sy),
+		color,
+	)
 }
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chorankates/termsaver/drawing"
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// LockConfig is the lock section of the config file, for "termsaver lock".
+// Without a password, any key unlocks.
+type LockConfig struct {
+	Password string `json:"password"` // bcrypt hash from "termsaver hash-password"
+}
+
+// Authenticator checks the password typed on a lock screen. Other ways of
+// checking it than a bcrypt hash would implement it too.
+type Authenticator interface {
+	// Authenticate returns nil if password unlocks the screen.
+	Authenticate(password string) error
+}
+
+// bcryptAuthenticator checks passwords against a bcrypt hash.
+type bcryptAuthenticator struct {
+	hash []byte
+}
+
+func (a bcryptAuthenticator) Authenticate(password string) error {
+	return bcrypt.CompareHashAndPassword(a.hash, []byte(password))
+}
+
+// newAuthenticator returns the Authenticator for c, or nil if any key
+// unlocks.
+func newAuthenticator(c LockConfig) (Authenticator, error) {
+	if c.Password != "" {
+		return bcryptAuthenticator{hash: []byte(c.Password)}, nil
+	}
+	return nil, nil
+}
+
+// failClosedMode runs a mode on a lock screen with a password, where a
+// panic would end termsaver and with it the lock. After a panic the mode
+// draws nothing more, and the lock carries on with a blank screen and the
+// password prompt.
+type failClosedMode struct {
+	mode   Mode
+	failed bool
+}
+
+// guard runs f, unless the mode has already failed, and recovers if it
+// panics.
+func (m *failClosedMode) guard(f func()) {
+	if m.failed {
+		return
+	}
+	defer func() {
+		if recover() != nil {
+			m.failed = true
+		}
+	}()
+	f()
+}
+
+func (m *failClosedMode) Init(env *Env, w, h int)  { m.guard(func() { m.mode.Init(env, w, h) }) }
+func (m *failClosedMode) Update(dt time.Duration)  { m.guard(func() { m.mode.Update(dt) }) }
+func (m *failClosedMode) Draw(screen tcell.Screen) { m.guard(func() { m.mode.Draw(screen) }) }
+func (m *failClosedMode) Resize(w, h int)          { m.guard(func() { m.mode.Resize(w, h) }) }
+
+func (m *failClosedMode) HandleEvent(ev tcell.Event) bool {
+	handled := false
+	m.guard(func() { handled = m.mode.HandleEvent(ev) })
+	return handled
+}
+
+func (m *failClosedMode) Reconfigure(env *Env) {
+	if r, ok := m.mode.(Reconfigurer); ok {
+		m.guard(func() { r.Reconfigure(env) })
+	}
+}
+
+// How long the password prompt stays up without a key being pressed.
+const lockPromptTime = 30 * time.Second
+
+// lockPrompt asks for the password on a lock screen. It comes up over the
+// running mode when a key is pressed and goes away again when nothing has
+// been typed for lockPromptTime. Each wrong password locks it for longer.
+type lockPrompt struct {
+	auth  Authenticator
+	clock Clock
+
+	input    []rune
+	lastKey  time.Time // Zero until the first key
+	failures int
+	wait     time.Time // No tries before this
+}
+
+func newLockPrompt(auth Authenticator, clock Clock) *lockPrompt {
+	return &lockPrompt{auth: auth, clock: clock}
+}
+
+// visible reports whether the prompt is up.
+func (p *lockPrompt) visible() bool {
+	return !p.lastKey.IsZero() && p.clock.Now().Sub(p.lastKey) < lockPromptTime
+}
+
+// key handles a key typed on the lock screen and reports whether it
+// unlocked it.
+func (p *lockPrompt) key(ev *tcell.EventKey) bool {
+	if !p.visible() {
+		// Whatever was typed last time has been abandoned
+		p.input = nil
+	}
+	now := p.clock.Now()
+	p.lastKey = now
+	if now.Before(p.wait) {
+		return false
+	}
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		if len(p.input) == 0 {
+			return false
+		}
+		err := p.auth.Authenticate(string(p.input))
+		p.input = nil
+		if err == nil {
+			return true
+		}
+		p.failures++
+		p.wait = now.Add(lockBackoff(p.failures))
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case tcell.KeyEscape, tcell.KeyCtrlU:
+		p.input = nil
+	case tcell.KeyRune:
+		if len(p.input) < 256 {
+			p.input = append(p.input, ev.Rune())
+		}
+	}
+	return false
+}
+
+// lockBackoff returns how long the prompt refuses tries after n wrong
+// passwords in a row: a second, doubling each time up to five minutes.
+func lockBackoff(n int) time.Duration {
+	if n >= 10 {
+		return 5 * time.Minute
+	}
+	return time.Second << (n - 1)
+}
+
+// draw shows the prompt in the middle of the screen, if it's up.
+func (p *lockPrompt) draw(screen tcell.Screen, theme *Theme) {
+	if !p.visible() {
+		return
+	}
+	// Fixed widths, so the box doesn't change size while typing
+	dots := strings.Repeat("•", min(len(p.input), 16))
+	msg := "Enter to unlock"
+	if left := p.wait.Sub(p.clock.Now()); left > 0 {
+		msg = fmt.Sprintf("Wrong password, wait %ds", int(math.Ceil(left.Seconds())))
+	} else if p.failures > 0 {
+		msg = "Wrong password, try again"
+	}
+	lines := []string{
+		fmt.Sprintf("%-28s", "Password: "+dots+"_"),
+		fmt.Sprintf("%-28s", msg),
+	}
+	drawing.Banner(screen, lines, theme.Style(RoleText))
+}
+
+// hashPassword asks for a password twice without showing it, and writes its
+// bcrypt hash to out for the lock section of the config. When in isn't a
+// terminal the password is the first line read from it.
+func hashPassword(in *os.File, out io.Writer) error {
+	var password []byte
+	if fd := int(in.Fd()); term.IsTerminal(fd) {
+		read := func(prompt string) ([]byte, error) {
+			fmt.Fprint(os.Stderr, prompt)
+			defer fmt.Fprintln(os.Stderr)
+			return term.ReadPassword(fd)
+		}
+		var err error
+		if password, err = read("Password: "); err != nil {
+			return fmt.Errorf("Error reading password: %v", err)
+		}
+		again, err := read("Again: ")
+		if err != nil {
+			return fmt.Errorf("Error reading password: %v", err)
+		}
+		if !bytes.Equal(password, again) {
+			return fmt.Errorf("Passwords don't match")
+		}
+	} else {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("Error reading password: %v", err)
+		}
+		password = []byte(strings.TrimRight(line, "\r\n"))
+	}
+	if len(password) == 0 {
+		return fmt.Errorf("Empty password")
+	}
+	hash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("Error hashing password: %v", err)
+	}
+	fmt.Fprintln(out, string(hash))
+	return nil
+}
+
+// lockConfig returns the lines that make target, "tmux" or "screen", run
+// "exe lock args" once it has been idle for idle seconds. A -idle flag in
+// args only sets the timeout, so it's left out of the command.
+func lockConfig(target, exe string, args []string, idle float64) (string, error) {
+	words := []string{shellQuote(exe), "lock"}
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "-idle" || a == "--idle":
+			i++
+		case strings.HasPrefix(a, "-idle=") || strings.HasPrefix(a, "--idle="):
+		default:
+			words = append(words, shellQuote(a))
+		}
+	}
+	command := strings.Join(words, " ")
+	seconds := max(int(math.Round(idle)), 1)
+
+	var b strings.Builder
+	switch target {
+	case "tmux":
+		fmt.Fprintf(&b, "# termsaver as the tmux lock screen, after %d idle seconds; for ~/.tmux.conf\n", seconds)
+		fmt.Fprintf(&b, "set -g lock-command %s\n", tmuxQuote(command))
+		fmt.Fprintf(&b, "set -g lock-after-time %d\n", seconds)
+		fmt.Fprintf(&b, "# Lock straight away with prefix C-x\n")
+		fmt.Fprintf(&b, "bind C-x lock-session\n")
+	case "screen":
+		fmt.Fprintf(&b, "# termsaver as the screen blanker, after %d idle seconds; for ~/.screenrc\n", seconds)
+		fmt.Fprintf(&b, "blankerprg %s\n", command)
+		fmt.Fprintf(&b, "idle %d blanker\n", seconds)
+	default:
+		return "", fmt.Errorf("Invalid lock-config target %q: want tmux or screen", target)
+	}
+	return b.String(), nil
+}
+
+// shellSafe matches words the shell takes as they are.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for the shell, if it needs it.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// tmuxQuote quotes s as a string in a tmux config file.
+func tmuxQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s) + `"`
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLockConfig(t *testing.T) {
+	tests := []struct {
+		target string
+		args   []string
+		want   string
+	}{
+		{"tmux", []string{"-mode", "matrix", "-idle", "120"}, "# termsaver as the tmux lock screen, after 120 idle seconds; for ~/.tmux.conf\n" +
+			"set -g lock-command \"/usr/bin/termsaver lock -mode matrix\"\n" +
+			"set -g lock-after-time 120\n" +
+			"# Lock straight away with prefix C-x\n" +
+			"bind C-x lock-session\n"},
+		{"tmux", []string{"-idle=120", "-config", "/tmp/a $b's.json"}, "# termsaver as the tmux lock screen, after 120 idle seconds; for ~/.tmux.conf\n" +
+			"set -g lock-command \"/usr/bin/termsaver lock -config '/tmp/a \\$b'\\\\''s.json'\"\n" +
+			"set -g lock-after-time 120\n" +
+			"# Lock straight away with prefix C-x\n" +
+			"bind C-x lock-session\n"},
+		{"screen", []string{"-playlist", "matrix:1m,snake:1m", "-idle", "120"}, "# termsaver as the screen blanker, after 120 idle seconds; for ~/.screenrc\n" +
+			"blankerprg /usr/bin/termsaver lock -playlist matrix:1m,snake:1m\n" +
+			"idle 120 blanker\n"},
+	}
+	for _, tt := range tests {
+		got, err := lockConfig(tt.target, "/usr/bin/termsaver", tt.args, 120)
+		if err != nil {
+			t.Errorf("%s %v: %v", tt.target, tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %v gave\n%s\nwant\n%s", tt.target, tt.args, got, tt.want)
+		}
+	}
+	if _, err := lockConfig("vim", "termsaver", nil, 120); err == nil {
+		t.Errorf("unknown target accepted")
+	}
+}
+
+// TestRunModeLock checks a lock screen ends on keys that would otherwise
+// do something else, and on SIGHUP rather than reloading.
+func TestRunModeLock(t *testing.T) {
+	for _, ev := range []tcell.Event{
+		tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone),
+	} {
+		if next := runLock(t, func(events chan tcell.Event, _ chan os.Signal) { events <- ev }); next {
+			t.Errorf("%v moved on to the next mode", ev)
+		}
+	}
+	if next := runLock(t, func(_ chan tcell.Event, sigs chan os.Signal) { sigs <- syscall.SIGHUP }); next {
+		t.Errorf("SIGHUP moved on to the next mode")
+	}
+}
+
+// runLock runs snake as a lock screen, ends it with end and returns what
+// runMode returned.
+func runLock(t *testing.T, end func(events chan tcell.Event, sigs chan os.Signal)) bool {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 10)
+
+	events, sigs := make(chan tcell.Event), make(chan os.Signal, 1)
+	s := &session{screen: screen, events: events, sigChan: sigs, clock: newManualClock(), fps: 100, speed: 1, lock: true}
+	info, _ := lookupModeInfo("snake")
+	env := defaultEnv()
+	env.Interactive = true
+	env.Rand = rand.New(rand.NewSource(1))
+
+	done := make(chan bool)
+	go func() { done <- s.runMode(info, env, time.Minute) }()
+	end(events, sigs)
+	select {
+	case next := <-done:
+		return next
+	case <-time.After(time.Second):
+		t.Fatalf("lock screen still running")
+		return false
+	}
+}
+
+// stubAuth is an Authenticator that only accepts password.
+type stubAuth struct {
+	password string
+}
+
+func (a stubAuth) Authenticate(password string) error {
+	if password != a.password {
+		return errors.New("wrong password")
+	}
+	return nil
+}
+
+// typeKeys types s into p, followed by Enter, and reports whether it
+// unlocked.
+func typeKeys(p *lockPrompt, s string) bool {
+	for _, r := range s {
+		p.key(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	return p.key(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+}
+
+func TestLockPrompt(t *testing.T) {
+	clock := newManualClock()
+	p := newLockPrompt(stubAuth{"hunter2"}, clock)
+	if p.visible() {
+		t.Errorf("prompt up before any key")
+	}
+	if typeKeys(p, "hunter3") {
+		t.Fatalf("wrong password unlocked")
+	}
+	if !p.visible() {
+		t.Errorf("prompt not up after typing")
+	}
+	// Locked out for a second, so even the right password does nothing
+	if typeKeys(p, "hunter2") {
+		t.Fatalf("unlocked during the lockout")
+	}
+	clock.Advance(time.Second)
+	p.key(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+	p.key(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone))
+	if !typeKeys(p, "hunter2") {
+		t.Fatalf("right password didn't unlock")
+	}
+
+	// Half-typed passwords are forgotten once the prompt goes away
+	p = newLockPrompt(stubAuth{"hunter2"}, clock)
+	typeKeys(p, "")
+	for _, r := range "junk" {
+		p.key(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	clock.Advance(lockPromptTime)
+	if p.visible() {
+		t.Errorf("prompt still up after %v", lockPromptTime)
+	}
+	if !typeKeys(p, "hunter2") {
+		t.Errorf("abandoned input kept")
+	}
+}
+
+func TestLockBackoff(t *testing.T) {
+	for n, want := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		5:  16 * time.Second,
+		9:  256 * time.Second,
+		10: 5 * time.Minute,
+		50: 5 * time.Minute,
+	} {
+		if got := lockBackoff(n); got != want {
+			t.Errorf("lockBackoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	if auth, err := newAuthenticator(LockConfig{}); auth != nil || err != nil {
+		t.Errorf("no password gave %v, %v", auth, err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := newAuthenticator(LockConfig{Password: string(hash)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Authenticate("hunter2"); err != nil {
+		t.Errorf("right password: %v", err)
+	}
+	if err := auth.Authenticate("hunter3"); err == nil {
+		t.Errorf("wrong password accepted")
+	}
+}
+
+// TestRunModeLockPassword checks a lock screen with a password only ends
+// once it's been typed.
+func TestRunModeLockPassword(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 10)
+
+	events := make(chan tcell.Event)
+	clock := newManualClock()
+	s := &session{screen: screen, events: events, clock: clock, fps: 100, speed: 1, lock: true}
+	s.prompt = newLockPrompt(stubAuth{"pw"}, clock)
+	info, _ := lookupModeInfo("snake")
+	env := defaultEnv()
+	env.Rand = rand.New(rand.NewSource(1))
+
+	done := make(chan bool)
+	go func() { done <- s.runMode(info, env, time.Minute) }()
+	for _, r := range "pw" {
+		events <- tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	select {
+	case <-done:
+		t.Fatalf("lock screen ended before Enter")
+	default:
+	}
+	events <- tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+	select {
+	case next := <-done:
+		if next {
+			t.Errorf("unlocking moved on to the next mode")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("lock screen still running after the password")
+	}
+}
+
+// panicMode is a mode that panics in everything it does.
+type panicMode struct{}
+
+func (panicMode) Init(env *Env, w, h int)         { panic("init") }
+func (panicMode) Update(dt time.Duration)         { panic("update") }
+func (panicMode) Draw(screen tcell.Screen)        { panic("draw") }
+func (panicMode) HandleEvent(ev tcell.Event) bool { panic("event") }
+func (panicMode) Resize(w, h int)                 { panic("resize") }
+
+// TestRunModeLockSurvivesPanics checks a lock screen with a password stays
+// locked when its mode panics, since ending would unlock the terminal.
+func TestRunModeLockSurvivesPanics(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 10)
+
+	events := make(chan tcell.Event)
+	clock := newManualClock()
+	s := &session{screen: screen, events: events, clock: clock, fps: 100, speed: 1, lock: true}
+	s.prompt = newLockPrompt(stubAuth{"pw"}, clock)
+	info := modeInfo{name: "panic", step: 10 * time.Millisecond, new: func() Mode { return panicMode{} }}
+	env := defaultEnv()
+
+	done := make(chan bool)
+	go func() { done <- s.runMode(info, env, time.Minute) }()
+	events <- tcell.NewEventKey(tcell.KeyRune, 'p', tcell.ModNone)
+	clock.Advance(time.Second)
+	events <- tcell.NewEventResize(20, 5)
+	events <- tcell.NewEventKey(tcell.KeyRune, 'w', tcell.ModNone)
+	time.Sleep(50 * time.Millisecond) // Let a few frames be drawn
+	select {
+	case <-done:
+		t.Fatalf("lock screen ended before Enter")
+	default:
+	}
+	events <- tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("lock screen still running after the password")
+	}
+}
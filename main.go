@@ -3,9 +3,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,111 +15,212 @@ import (
 )
 
 func main() {
-	var mode = flag.String("mode", "random", "Visualization mode: matrix, nyancat, snake, missiledefender, spectrograph, snowflakes, waterripple, lightning, or random")
-	var interactive = flag.Bool("interactive", false, "Enable interactive mode (for snake: use arrow keys to play)")
-	var grayscale = flag.Bool("grayscale", false, "Use grayscale colors instead of colors")
-	var windChangeTime = flag.Float64("wind-change-time", 3.0, "Time in seconds between wind direction changes (snowflakes mode)")
-	var windStrength = flag.Float64("wind-strength", 0.8, "Baseline wind strength (-1.0 to 1.0, snowflakes mode)")
-	var snakeSize = flag.Int("snake-size", 0, "Snake game grid size (0 = auto based on terminal, max 50)")
-	var snakeScale = flag.Int("snake-scale", 2, "Snake cell scale factor (1-4, affects how large each cell appears)")
-	flag.Parse()
-
-	screen, err := tcell.NewScreen()
+	flag.Usage = usage
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 && (args[0] == "daemon" || args[0] == "lock" || args[0] == "lock-config" || args[0] == "hash-password") {
+		command, args = args[0], args[1:]
+	}
+	target := "tmux"
+	if command == "lock-config" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		target, args = args[0], args[1:]
+	}
+	cfg, printConfig, err := parseConfig(flag.CommandLine, args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating screen: %v\n", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
+	if printConfig {
+		fmt.Println(cfg)
+		return
+	}
 
-	if err := screen.Init(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing screen: %v\n", err)
+	seed := cfg.Global.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	env := &Env{
+		Interactive: cfg.Global.Interactive,
+		Config:      cfg,
+	}
+
+	switch {
+	case command == "daemon":
+		err = runDaemon(env)
+	case command == "hash-password":
+		err = hashPassword(os.Stdin, os.Stdout)
+	case command == "lock-config":
+		err = printLockConfig(target, args, cfg.Daemon.Idle)
+	case command == "lock":
+		// Quietly, whatever ends it: the terminal belongs to tmux or screen
+		cfg.lock = true
+		err = run(env, seed, tcell.NewScreen)
+	case cfg.Global.Snapshot != "":
+		err = snapshot(env, seed)
+	default:
+		err = run(env, seed, tcell.NewScreen)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
-	defer screen.Fini()
+	if command != "" {
+		return
+	}
+	fmt.Printf("seed: %d (replay with -seed %d)\n", seed, seed)
+}
 
-	// Handle interrupt signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	// All available modes for cycling
-	allModes := []string{"matrix", "nyancat", "snake", "missiledefender", "spectrograph", "snowflakes", "waterripple", "lightning"}
-
-	// Handle random mode selection
-	selectedMode := *mode
-	modeIndex := 0
-	if selectedMode == "random" {
-		rand.Seed(time.Now().UnixNano())
-		modeIndex = rand.Intn(len(allModes))
-		selectedMode = allModes[modeIndex]
-	} else {
-		// Find the index of the selected mode
-		for i, m := range allModes {
-			if m == selectedMode {
-				modeIndex = i
-				break
-			}
+// run takes over the terminal, through the screen newScreen returns, and
+// shows modes from the playlist until the user exits.
+func run(env *Env, seed int64, newScreen func() (tcell.Screen, error)) (err error) {
+	// A lock screen with a password has to be able to check it before it
+	// takes over the terminal
+	var auth Authenticator
+	if env.Config.lock {
+		if auth, err = newAuthenticator(env.Config.Lock); err != nil {
+			return err
 		}
 	}
 
-	// Start the selected visualization, cycling on space press
-	for {
-		var cycleToNext bool
-
-		switch selectedMode {
-		case "matrix":
-			cycleToNext = runMatrixRain(screen, sigChan, *interactive, *grayscale)
-		case "nyancat":
-			cycleToNext = runNyancat(screen, sigChan, *interactive, *grayscale)
-		case "snake":
-			cycleToNext = runSnake(screen, sigChan, *interactive, *grayscale, *snakeSize, *snakeScale)
-		case "missiledefender":
-			cycleToNext = runMissileDefender(screen, sigChan, *interactive, *grayscale)
-		case "spectrograph":
-			cycleToNext = runSpectrograph(screen, sigChan, *interactive, *grayscale)
-		case "snowflakes":
-			cycleToNext = runSnowflakes(screen, sigChan, *interactive, *grayscale, *windChangeTime, *windStrength)
-		case "waterripple":
-			cycleToNext = runWaterRipple(screen, sigChan, *interactive, *grayscale)
-		case "lightning":
-			cycleToNext = runLightning(screen, sigChan, *interactive, *grayscale)
-		default:
-			screen.Fini()
-			fmt.Fprintf(os.Stderr, "Unknown mode: %s. Use: matrix, nyancat, snake, missiledefender, spectrograph, snowflakes, waterripple, lightning, or random\n", *mode)
-			os.Exit(1)
-		}
+	screen, err := newScreen()
+	if err != nil {
+		return fmt.Errorf("Error creating screen: %v", err)
+	}
+
+	if err := screen.Init(); err != nil {
+		return fmt.Errorf("Error initializing screen: %v", err)
+	}
+	defer screen.Fini()
+	env.Theme, _ = newTheme(env.Config.themeName(), screen.Colors())
+	if env.Interactive {
+		// For modes played with the mouse, such as towerdefense and missiledefender
+		screen.EnableMouse()
+	}
 
-		if !cycleToNext {
-			break
+	// Everything shown goes through the recorder when recording
+	if path := env.Config.Global.Record; path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("Error creating recording: %v", err)
 		}
+		defer f.Close()
+		rec, err := newRecorder(screen, f, realClock{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := rec.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		screen = rec
+	}
+
+	// Handle interrupt signals, and SIGHUP to reload the config
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	// One event pump for the whole session, shut down before the screen is
+	// finalized
+	pump := newEventPump(screen)
+	defer pump.Stop()
+
+	s := &session{
+		screen:  screen,
+		events:  pump.Events(),
+		sigChan: sigChan,
+		clock:   realClock{},
+		fps:     env.Config.Global.FPS,
+		speed:   env.Config.Global.Speed,
+		lock:    env.Config.lock,
+		overlay: newOverlay(env.Config.Overlay),
+
+		loadConfig: func() (*Config, error) {
+			flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			cfg, _, err := parseConfig(flags, env.Config.args)
+			return cfg, err
+		},
+	}
+	if auth != nil {
+		s.prompt = newLockPrompt(auth, s.clock)
+	}
+	if env.Config.Global.WatchConfig {
+		stop := make(chan struct{})
+		defer close(stop)
+		s.reload = watchFile(env.Config.path, time.Second, stop)
+	}
+
+	g := env.Config.Global
+	rng := rand.New(rand.NewSource(seed))
+	player := startPlaylist(g, rng)
 
-		// Cycle to next mode
-		modeIndex = (modeIndex + 1) % len(allModes)
-		selectedMode = allModes[modeIndex]
+	// Start the selected visualization, moving on when its time is up or
+	// space is pressed. Every mode shown gets its own source derived from the
+	// seed, so replaying a seed reproduces each mode the same way no matter
+	// how it was reached.
+	for n, entry := 0, player.Current(); ; n, entry = n+1, player.Next() {
+		env.Rand = rand.New(rand.NewSource(modeSeed(seed, n)))
+		i, _ := lookupMode(entry.Mode)
+		if !s.runMode(registry[i], env, entry.Duration) {
+			return nil
+		}
+		// Read the settings again, they may have been reloaded
+		g = env.Config.Global
+		s.startTransition(pickTransition(g.Transition, rng), time.Duration(g.TransitionTime*float64(time.Second)))
 		screen.Clear()
 	}
 }
 
-// toGrayscale converts a color to grayscale if grayscale mode is enabled
-func toGrayscale(color tcell.Color, grayscale bool) tcell.Color {
-	if !grayscale {
-		return color
-	}
-	
-	// Map colors to grayscale equivalents based on typical brightness
-	switch color {
-	case tcell.ColorWhite:
-		return tcell.ColorWhite
-	case tcell.ColorBlack:
-		return tcell.ColorBlack
-	case tcell.ColorYellow, tcell.ColorLime, tcell.ColorOrange:
-		return tcell.ColorWhite
-	case tcell.ColorGreen, tcell.ColorBlue, tcell.ColorRed, tcell.ColorPurple,
-		 tcell.ColorAqua, tcell.ColorFuchsia, tcell.ColorPink:
-		return tcell.ColorGray
-	case tcell.ColorDarkGray:
-		return tcell.ColorDarkGray
-	default:
-		// For any other colors, use a default gray
-		return tcell.ColorGray
+// printLockConfig prints the tmux or screen config for running termsaver as
+// a lock screen with the given flags.
+func printLockConfig(target string, args []string, idle float64) error {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "termsaver"
+	}
+	conf, err := lockConfig(target, exe, args, idle)
+	if err != nil {
+		return err
+	}
+	fmt.Print(conf)
+	return nil
+}
+
+// modeSeed derives the seed for the nth mode shown in a session.
+func modeSeed(seed int64, n int) int64 {
+	return seed + int64(n)
+}
+
+// usage prints the flag defaults followed by the registered modes.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [daemon | lock | lock-config [tmux | screen] | hash-password] [flags]\n\n", os.Args[0])
+	fmt.Fprintf(out, "With daemon, your shell runs inside termsaver, which takes over the\n")
+	fmt.Fprintf(out, "screen once the terminal has been idle for -idle seconds.\n")
+	fmt.Fprintf(out, "With lock, termsaver quietly exits on any key, for tmux's lock-command\n")
+	fmt.Fprintf(out, "or screen's blankerprg; lock-config prints the lines to set that up.\n")
+	fmt.Fprintf(out, "A lock with -lock-password asks for the password instead;\n")
+	fmt.Fprintf(out, "hash-password makes the hash for -lock-password.\n\nFlags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\nModes:\n")
+	for _, info := range registry {
+		fmt.Fprintf(out, "  %-16s %s\n", info.name, info.description)
 	}
+	fmt.Fprintf(out, "  %-16s %s\n", "random", "randomly selects one of the available modes")
 }
 
+// startPlaylist returns a player for the configured playlist, starting at the
+// configured mode. Without a playlist every mode is shown, cycling on space
+// press. A hidden mode asked for by name plays on its own.
+func startPlaylist(g GlobalConfig, rng *rand.Rand) *playlistPlayer {
+	entries := g.Playlist
+	if i, ok := lookupMode(g.Mode); len(entries) == 0 && ok && registry[i].hidden {
+		entries = Playlist{{Mode: g.Mode, Weight: 1}}
+	} else if len(entries) == 0 {
+		entries = defaultPlaylist()
+	}
+	return newPlaylistPlayer(entries, g.Shuffle, rng, g.Mode)
+}
@@ -2,116 +2,121 @@ package main
 
 import (
 	"math/rand"
-	"os"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	registerMode(modeInfo{
+		name:        "matrix",
+		description: "classic falling characters effect with katakana, hiragana, and alphanumeric characters",
+		step:        50 * time.Millisecond,
+		new:         func() Mode { return &MatrixRain{} },
+	})
+}
+
+// MatrixConfig is the [matrix] section of the config file.
+type MatrixConfig struct {
+	MinSpeed int `json:"min_speed"` // Rows a column falls per step
+	MaxSpeed int `json:"max_speed"`
+}
+
 type MatrixColumn struct {
 	chars    []rune
 	position int
 	speed    int
 }
 
-func runMatrixRain(screen tcell.Screen, sigChan chan os.Signal, interactive bool, grayscale bool) bool {
-	w, h := screen.Size()
-	columns := make([]MatrixColumn, w)
+type MatrixRain struct {
+	env     *Env
+	rng     *rand.Rand
+	w, h    int
+	columns []MatrixColumn
+}
+
+func (m *MatrixRain) Init(env *Env, w, h int) {
+	m.env = env
+	m.rng = env.Rand
+	m.w, m.h = w, h
+	m.columns = make([]MatrixColumn, w)
 
 	// Initialize columns
-	for i := range columns {
-		columns[i] = MatrixColumn{
-			chars:    generateMatrixChars(h),
-			position: -rand.Intn(h * 2),
-			speed:    1 + rand.Intn(2),
+	for i := range m.columns {
+		m.columns[i] = m.newColumn(h)
+	}
+}
+
+func (m *MatrixRain) newColumn(h int) MatrixColumn {
+	return MatrixColumn{
+		chars:    generateMatrixChars(m.rng, h),
+		position: -m.rng.Intn(h * 2),
+		speed:    m.columnSpeed(),
+	}
+}
+
+// columnSpeed picks a falling speed within the configured range.
+func (m *MatrixRain) columnSpeed() int {
+	cfg := m.env.Config.Matrix
+	return cfg.MinSpeed + m.rng.Intn(cfg.MaxSpeed-cfg.MinSpeed+1)
+}
+
+func (m *MatrixRain) Resize(w, h int) {
+	m.w, m.h = w, h
+	// Reinitialize columns for new size
+	newColumns := make([]MatrixColumn, w)
+	for i := range newColumns {
+		if i < len(m.columns) {
+			newColumns[i] = m.columns[i]
+		} else {
+			newColumns[i] = m.newColumn(h)
 		}
 	}
+	m.columns = newColumns
+}
 
-	ticker := time.NewTicker(50 * time.Millisecond)
-	defer ticker.Stop()
+func (m *MatrixRain) HandleEvent(ev tcell.Event) bool {
+	return false
+}
 
-	style := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorGreen, grayscale)).Background(tcell.ColorBlack)
-	brightStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
+func (m *MatrixRain) Update(dt time.Duration) {
+	for x := range m.columns {
+		col := &m.columns[x]
+		col.position += col.speed
 
-	// Event handling for resize
-	eventChan := make(chan tcell.Event, 10)
-	go func() {
-		for {
-			eventChan <- screen.PollEvent()
+		// Reset column when it goes off screen
+		if col.position > m.h+len(col.chars) {
+			col.position = -len(col.chars)
+			col.chars = generateMatrixChars(m.rng, m.h)
+			col.speed = m.columnSpeed()
 		}
-	}()
-
-	for {
-		select {
-		case <-sigChan:
-			return false
-		case event := <-eventChan:
-			switch ev := event.(type) {
-			case *tcell.EventResize:
-				w, h = screen.Size()
-				// Reinitialize columns for new size
-				newColumns := make([]MatrixColumn, w)
-				for i := range newColumns {
-					if i < len(columns) {
-						newColumns[i] = columns[i]
-					} else {
-						newColumns[i] = MatrixColumn{
-							chars:    generateMatrixChars(h),
-							position: -rand.Intn(h * 2),
-							speed:    1 + rand.Intn(2),
-						}
-					}
-				}
-				columns = newColumns
-				screen.Sync()
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
-					return false
-				}
-				// Space cycles to next mode
-				if ev.Rune() == ' ' {
-					return true
-				}
-				// In non-interactive mode, any key exits
-				if !interactive {
-					return false
-				}
-			}
-		case <-ticker.C:
-			screen.Clear()
-
-			for x := 0; x < w; x++ {
-				col := &columns[x]
-				col.position += col.speed
-
-				for i, char := range col.chars {
-					y := col.position - (len(col.chars) - i)
-					if y >= 0 && y < h {
-						// Fade effect: brighter at head, darker at tail
-						charStyle := style
-						if i == len(col.chars)-1 {
-							charStyle = brightStyle
-						} else if i > len(col.chars)-5 {
-							charStyle = tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorLime, grayscale)).Background(tcell.ColorBlack)
-						}
-						screen.SetContent(x, y, char, nil, charStyle)
-					}
-				}
+	}
+}
 
-				// Reset column when it goes off screen
-				if col.position > h+len(col.chars) {
-					col.position = -len(col.chars)
-					col.chars = generateMatrixChars(h)
-					col.speed = 1 + rand.Intn(2)
+func (m *MatrixRain) Draw(screen tcell.Screen) {
+	theme := m.env.Theme
+	style := theme.Style(RolePrimary)
+	brightStyle := theme.Style(RoleBright)
+	fadeStyle := theme.Style(RolePrimaryBright)
+
+	for x, col := range m.columns {
+		for i, char := range col.chars {
+			y := col.position - (len(col.chars) - i)
+			if y >= 0 && y < m.h {
+				// Fade effect: brighter at head, darker at tail
+				charStyle := style
+				if i == len(col.chars)-1 {
+					charStyle = brightStyle
+				} else if i > len(col.chars)-5 {
+					charStyle = fadeStyle
 				}
+				screen.SetContent(x, y, char, nil, charStyle)
 			}
-
-			screen.Show()
 		}
 	}
 }
 
-func generateMatrixChars(length int) []rune {
+func generateMatrixChars(rng *rand.Rand, length int) []rune {
 	chars := make([]rune, length)
 	for i := range chars {
 		// Mix of katakana, hiragana, and alphanumeric
@@ -123,8 +128,7 @@ func generateMatrixChars(length int) []rune {
 			'ル', 'レ', 'ロ', 'ワ', 'ヲ', 'ン',
 			'0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
 		}
-		chars[i] = matrixChars[rand.Intn(len(matrixChars))]
+		chars[i] = matrixChars[rng.Intn(len(matrixChars))]
 	}
 	return chars
 }
-
@@ -4,418 +4,725 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"os"
 	"time"
 
+	"github.com/chorankates/termsaver/drawing"
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	registerMode(modeInfo{
+		name:        "missiledefender",
+		description: "automatic missile defense game where bases shoot down ever bigger waves of missiles and splitting warheads before they flatten the cities (aim and fire yourself with -interactive)",
+		step:        100 * time.Millisecond,
+		new:         func() Mode { return &MissileDefender{} },
+	})
+}
+
+// MissileDefenderConfig is the [missiledefender] section of the config file.
+type MissileDefenderConfig struct {
+	SpawnInterval float64 `json:"spawn_interval"` // Seconds between incoming missiles on the first wave
+	MaxMissiles   int     `json:"max_missiles"`   // Missiles in flight at once
+	Ammo          int     `json:"ammo"`           // Interceptors each base holds at the start of a wave
+	Cities        int     `json:"cities"`         // Cities to defend
+}
+
+// The game is Missile Command: the bases fire interceptors, which explode
+// where they were aimed, to keep the missiles off the cities. The bases aim
+// for themselves unless the player does it with -interactive.
+const (
+	interceptorSpeed = 30.0                    // Cells per second, see blastDist
+	missileSpeed     = 4.0                     // Cells per second on the first wave
+	interceptorBlast = 4.0                     // Largest radius of an interceptor's blast, in cells
+	missileBlast     = 3.0                     // Largest radius of a missile's, wherever it goes off
+	blastTime        = 1500 * time.Millisecond // For a blast to grow and shrink away again
+	summaryTime      = 4 * time.Second         // How long the summary between waves is shown
+	missileScore     = 25                      // For each missile destroyed
+	cityBonus        = 100                     // For each city left at the end of a wave
+	ammoBonus        = 5                       // For each interceptor left at the end of a wave
+)
+
 type Base struct {
 	Pos      Point
-	Cooldown int
-	LastFire int
+	Cooldown int // Steps between automatic shots
+	LastFire int // Steps until it can fire again
+	Ammo     int // Interceptors left this wave
 }
 
-type Missile struct {
-	Pos      Point
-	PrevPos  Point
-	Velocity Point
-	Alive    bool
+type City struct {
+	Pos   Point
+	Alive bool
 }
 
-type Projectile struct {
-	Pos      Point
-	Velocity Point
-	Alive    bool
+// Interceptor flies from a base to the point it was aimed at, and explodes
+// there.
+type Interceptor struct {
+	From   Point   // Where it was launched, for drawing its trail
+	X, Y   float64 // Where it is now
+	Target Point
 }
 
-type Terrain struct {
-	Pos Point
+// Missile flies in a straight line from the top of the screen to a city or
+// base. A MIRV splits into several missiles part of the way down, which
+// carry on its trail.
+type Missile struct {
+	OX, OY float64 // Where it was launched, for drawing its trail
+	FX, FY float64 // Where it split off a MIRV, if Forked
+	X, Y   float64
+	VX, VY float64 // Cells per second
+	SplitY float64 // Row a MIRV splits at, 0 for an ordinary missile
+	Forked bool
+	Alive  bool
+}
+
+// Blast grows to Size and shrinks away again over blastTime, destroying
+// every missile it reaches, which explode in turn.
+type Blast struct {
+	X, Y    float64
+	Size    float64 // Largest radius, in cells across
+	Age     time.Duration
+	Hostile bool // A missile that hit the ground, which harms whatever is there
 }
 
 type MissileDefender struct {
-	bases       []Base
-	missiles    []Missile
-	projectiles []Projectile
-	terrain     []Terrain
-	lastRandomize time.Time
-	score       int
-	missilesDestroyed int
+	env               *Env
+	rng               *rand.Rand
+	w, h              int
+	bases             []Base
+	cities            []City
+	land              []int // Rows of land on the bedrock in each column
+	missiles          []Missile
+	interceptors      []Interceptor
+	blasts            []Blast
+	sinceSpawn        time.Duration
+	score             int
+	wave              int
+	toSpawn           int           // Missiles still to come this wave
+	missilesDestroyed int           // This wave
+	summary           []string      // Shown between waves
+	pause             time.Duration // Left to show the summary
+	over              time.Duration // Left to show the final score, once the cities are gone
+	canvas            *drawing.Canvas
+
+	// Interactive play
+	crosshair Point
+	buttons   tcell.ButtonMask // Mouse buttons held at the last mouse event
+}
+
+func (g *MissileDefender) Init(env *Env, w, h int) {
+	g.env = env
+	g.rng = env.Rand
+	g.w, g.h = w, h
+	g.newGame()
+	g.moveCrosshair(w/2, h/2)
 }
 
-func runMissileDefender(screen tcell.Screen, sigChan chan os.Signal, interactive bool, grayscale bool) bool {
-	w, h := screen.Size()
-	rand.Seed(time.Now().UnixNano())
+// Resize starts a new game laid out for the new size, since the landscape
+// can't be stretched.
+func (g *MissileDefender) Resize(w, h int) {
+	g.w, g.h = w, h
+	g.canvas = nil
+	g.newGame()
+	g.moveCrosshair(g.crosshair.X, g.crosshair.Y)
+}
 
-	game := &MissileDefender{
-		bases:       []Base{},
-		missiles:    []Missile{},
-		projectiles: []Projectile{},
-		terrain:     []Terrain{},
-		lastRandomize: time.Now(),
-		score:       0,
-		missilesDestroyed: 0,
+func (g *MissileDefender) HandleEvent(ev tcell.Event) bool {
+	if !g.env.Interactive {
+		return false
+	}
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		return g.handleKey(ev)
+	case *tcell.EventMouse:
+		// Fire when the button goes down, not for as long as it's held
+		x, y := ev.Position()
+		g.moveCrosshair(x, y)
+		pressed := ev.Buttons() &^ g.buttons
+		g.buttons = ev.Buttons()
+		if pressed&tcell.Button1 != 0 {
+			g.fire()
+		}
+		return true
 	}
+	return false
+}
 
-	game.randomizeLayout(w, h)
+// handleKey moves the crosshair with the arrow keys and fires with Enter or
+// space.
+func (g *MissileDefender) handleKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		g.moveCrosshair(g.crosshair.X, g.crosshair.Y-1)
+	case tcell.KeyDown:
+		g.moveCrosshair(g.crosshair.X, g.crosshair.Y+1)
+	case tcell.KeyLeft:
+		g.moveCrosshair(g.crosshair.X-1, g.crosshair.Y)
+	case tcell.KeyRight:
+		g.moveCrosshair(g.crosshair.X+1, g.crosshair.Y)
+	case tcell.KeyEnter:
+		g.fire()
+	case tcell.KeyRune:
+		if ev.Rune() != ' ' {
+			return false
+		}
+		g.fire()
+	default:
+		return false
+	}
+	return true
+}
 
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+// moveCrosshair puts the crosshair at x, y, kept inside the border and
+// above the bedrock.
+func (g *MissileDefender) moveCrosshair(x, y int) {
+	g.crosshair = Point{min(max(x, 1), g.w-2), min(max(y, 1), g.h-3)}
+}
 
-	// Event handling for exit
-	eventChan := make(chan tcell.Event, 10)
-	go func() {
-		for {
-			eventChan <- screen.PollEvent()
+// fire launches an interceptor at the crosshair from the nearest base that
+// has any left.
+func (g *MissileDefender) fire() {
+	if g.over > 0 || g.pause > 0 {
+		return
+	}
+	best := -1
+	for i, base := range g.bases {
+		if base.Ammo > 0 && (best < 0 || abs(base.Pos.X-g.crosshair.X) < abs(g.bases[best].Pos.X-g.crosshair.X)) {
+			best = i
 		}
-	}()
+	}
+	if best >= 0 {
+		g.launch(best, g.crosshair)
+	}
+}
+
+// launch fires an interceptor from base i at target.
+func (g *MissileDefender) launch(i int, target Point) {
+	base := &g.bases[i]
+	base.Ammo--
+	from := Point{X: base.Pos.X, Y: base.Pos.Y - 1}
+	g.interceptors = append(g.interceptors, Interceptor{
+		From:   from,
+		X:      float64(from.X),
+		Y:      float64(from.Y),
+		Target: target,
+	})
+}
 
-	frameCount := 0
+// missileWaveSize returns how many missiles come in wave n.
+func missileWaveSize(n int) int {
+	return 5 + 3*n
+}
 
-	for {
-		select {
-		case <-sigChan:
-			return false
-		case event := <-eventChan:
-			switch ev := event.(type) {
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
-					return false
-				}
-				// Space cycles to next mode
-				if ev.Rune() == ' ' {
-					return true
-				}
-				// In non-interactive mode, any key exits
-				if !interactive {
-					return false
-				}
-			case *tcell.EventResize:
-				w, h = screen.Size()
-				game.randomizeLayout(w, h)
-				screen.Sync()
-			}
-		case <-ticker.C:
-			frameCount++
+// missileWaveSpeed returns how fast the missiles of wave n fly, in cells per
+// second.
+func missileWaveSpeed(n int) float64 {
+	return missileSpeed * min(1+0.12*float64(n-1), 3)
+}
 
-			// Randomize layout every 30-45 seconds
-			if time.Since(game.lastRandomize) > time.Duration(30+rand.Intn(16))*time.Second {
-				game.randomizeLayout(w, h)
-				game.lastRandomize = time.Now()
-			}
+// mirvChance returns the chance that a missile of wave n is a MIRV. There
+// are none on the first wave.
+func mirvChance(n int) float64 {
+	return min(0.1*float64(n-1), 0.5)
+}
 
-			// Spawn missiles periodically from the top
-			if frameCount%25 == 0 && len(game.missiles) < 8 {
-				game.spawnMissile(w, h)
-			}
+func (g *MissileDefender) Update(dt time.Duration) {
+	switch {
+	case g.over > 0:
+		// Show the final score for a while, then start again
+		if g.over -= dt; g.over <= 0 {
+			g.newGame()
+		}
+		return
+	case g.pause > 0:
+		if g.pause -= dt; g.pause <= 0 {
+			g.startWave(g.wave + 1)
+		}
+		return
+	}
+
+	// Spawn missiles from the top, more often as the waves go on
+	cfg := g.env.Config.MissileDefender
+	g.sinceSpawn += dt
+	interval := time.Duration(cfg.SpawnInterval / (1 + 0.15*float64(g.wave-1)) * float64(time.Second))
+	if g.toSpawn > 0 && g.sinceSpawn >= interval {
+		g.sinceSpawn -= interval
+		if len(g.missiles) < cfg.MaxMissiles {
+			g.spawnMissile()
+			g.toSpawn--
+		}
+	}
+
+	g.moveMissiles(dt)
+	if !g.env.Interactive {
+		g.autoFire()
+	}
+	g.moveInterceptors(dt)
+	g.updateBlasts(dt)
+
+	missiles := g.missiles[:0]
+	for _, m := range g.missiles {
+		if m.Alive {
+			missiles = append(missiles, m)
+		}
+	}
+	g.missiles = missiles
+
+	lost := true
+	for _, c := range g.cities {
+		lost = lost && !c.Alive
+	}
+	switch {
+	case lost:
+		g.over = gameOverTime
+	case g.toSpawn == 0 && len(g.missiles) == 0 && len(g.interceptors) == 0 && len(g.blasts) == 0:
+		g.endWave()
+	}
+}
 
-			// Update game state
-			game.update(w, h)
+// newGame starts again from the first wave on a new landscape.
+func (g *MissileDefender) newGame() {
+	g.score = 0
+	g.randomizeLayout(g.w, g.h)
+	g.startWave(1)
+}
+
+// startWave sends wave n, with the bases restocked.
+func (g *MissileDefender) startWave(n int) {
+	g.wave = n
+	g.toSpawn = missileWaveSize(n)
+	g.missilesDestroyed = 0
+	g.sinceSpawn = 0
+	for i := range g.bases {
+		g.bases[i].Ammo = g.env.Config.MissileDefender.Ammo
+	}
+}
 
-			// Draw
-			game.draw(screen, w, h, grayscale)
-			screen.Show()
+// endWave pays the bonus for what's left and shows the summary of the wave.
+func (g *MissileDefender) endWave() {
+	cities, ammo := 0, 0
+	for _, c := range g.cities {
+		if c.Alive {
+			cities++
 		}
 	}
+	for _, b := range g.bases {
+		ammo += b.Ammo
+	}
+	bonus := cities*cityBonus + ammo*ammoBonus
+	g.score += bonus
+	g.summary = []string{
+		fmt.Sprintf("WAVE %d CLEARED", g.wave),
+		"",
+		fmt.Sprintf("Missiles destroyed  %d", g.missilesDestroyed),
+		fmt.Sprintf("Cities  %d x %d", cities, cityBonus),
+		fmt.Sprintf("Interceptors  %d x %d", ammo, ammoBonus),
+		fmt.Sprintf("Bonus  %d", bonus),
+	}
+	g.pause = summaryTime
 }
 
+// randomizeLayout lays out new land, with the bases and cities on it.
 func (g *MissileDefender) randomizeLayout(w, h int) {
-	// Clear existing layout
 	g.bases = []Base{}
-	g.terrain = []Terrain{}
+	g.cities = []City{}
 	g.missiles = []Missile{}
-	g.projectiles = []Projectile{}
-
-	// Place 3-5 bases on the bottom row
-	numBases := 3 + rand.Intn(3) // 3-5 bases
-	baseY := h - 2 // Bottom row (accounting for border)
-	
-	// Generate base positions with spacing
-	spacing := (w - 4) / numBases
+	g.interceptors = []Interceptor{}
+	g.blasts = []Blast{}
+	g.land = generateLand(g.rng, w, h)
+
+	// Place 3-5 bases along the ground, with spacing
+	numBases := 3 + g.rng.Intn(3)
+	spacing := max((w-4)/numBases, 1)
 	for i := 0; i < numBases; i++ {
-		x := 2 + (i * spacing) + rand.Intn(spacing/2) - spacing/4
-		// Keep within bounds
-		if x < 2 {
-			x = 2
-		}
-		if x >= w-2 {
-			x = w - 3
-		}
-		
+		x := 2 + (i * spacing) + g.rng.Intn(max(spacing/2, 1)) - spacing/4
+		x = min(max(x, 2), w-3)
 		g.bases = append(g.bases, Base{
-			Pos:      Point{X: x, Y: baseY},
+			Pos:      Point{X: x},
 			Cooldown: 20,
-			LastFire: rand.Intn(20), // Randomize initial cooldown
+			LastFire: g.rng.Intn(20), // Randomize initial cooldown
 		})
 	}
+	g.placeCities(w)
+	g.settle()
+}
 
-	// Randomize terrain (3-8 terrain pieces, not on bottom row)
-	numTerrain := 3 + rand.Intn(6)
-	for i := 0; i < numTerrain; i++ {
-		terrain := Terrain{
-			Pos: Point{
-				X: 1 + rand.Intn(w-2),
-				Y: 2 + rand.Intn(h-5), // Avoid bottom row and top border
-			},
-		}
-		// Make sure terrain doesn't overlap with bases
-		overlap := false
-		for _, base := range g.bases {
-			if terrain.Pos.X == base.Pos.X && terrain.Pos.Y == base.Pos.Y {
-				overlap = true
-				break
-			}
+// generateLand returns the height of the land in each of w columns, in rows
+// on top of the bedrock: rolling hills up to an eighth of the screen high.
+func generateLand(rng *rand.Rand, w, h int) []int {
+	land := make([]int, w)
+	top := max(h/8, 0)
+	height := rng.Intn(top + 1)
+	for x := 1; x < w-1; x++ {
+		if rng.Intn(2) == 0 {
+			height = min(max(height+rng.Intn(3)-1, 0), top)
 		}
-		// Also check for overlap with existing terrain
-		if !overlap {
-			for _, existing := range g.terrain {
-				if terrain.Pos.X == existing.Pos.X && terrain.Pos.Y == existing.Pos.Y {
-					overlap = true
-					break
-				}
-			}
+		land[x] = height
+	}
+	return land
+}
+
+// placeCities spreads the cities along the ground, in the gaps between the
+// bases.
+func (g *MissileDefender) placeCities(w int) {
+	n := g.env.Config.MissileDefender.Cities
+	taken := map[int]bool{}
+	for _, base := range g.bases {
+		// Leave room either side, to tell the base and city apart
+		taken[base.Pos.X-1], taken[base.Pos.X], taken[base.Pos.X+1] = true, true, true
+	}
+	for i := 0; i < n; i++ {
+		x := 1 + (2*i+1)*(w-2)/(2*n)
+		for x < w-2 && taken[x] {
+			x++
 		}
-		if !overlap {
-			g.terrain = append(g.terrain, terrain)
+		if taken[x] || x >= w-1 {
+			continue
 		}
+		taken[x] = true
+		g.cities = append(g.cities, City{Pos: Point{X: x}, Alive: true})
+	}
+}
+
+// groundY returns the row of the bedrock, which nothing can dig through.
+func (g *MissileDefender) groundY() int {
+	return g.h - 2
+}
+
+// surface returns the row of the top of the land in column x.
+func (g *MissileDefender) surface(x int) int {
+	if x < 0 || x >= len(g.land) {
+		return g.groundY()
 	}
+	return g.groundY() - g.land[x]
 }
 
-func (g *MissileDefender) spawnMissile(w, h int) {
-	// Spawn missiles from the top, falling downward
-	pos := Point{
-		X: 1 + rand.Intn(w-2),
-		Y: 1,
+// settle puts the bases and cities on top of the land, which may have been
+// blasted away from under them.
+func (g *MissileDefender) settle() {
+	for i := range g.bases {
+		g.bases[i].Pos.Y = g.surface(g.bases[i].Pos.X) - 1
 	}
-	
-	// Velocity: slight horizontal variation, always downward
-	velocity := Point{
-		X: rand.Intn(3) - 1, // -1, 0, or 1
-		Y: 1,                // Always fall down
+	for i := range g.cities {
+		g.cities[i].Pos.Y = g.surface(g.cities[i].Pos.X) - 1
 	}
+}
 
-	g.missiles = append(g.missiles, Missile{
-		Pos:      pos,
-		PrevPos:  pos,
-		Velocity: velocity,
-		Alive:    true,
-	})
+// spawnMissile launches a missile from a random point along the top, a MIRV
+// some of the time.
+func (g *MissileDefender) spawnMissile() {
+	x := 1 + g.rng.Float64()*float64(g.w-3)
+	m := g.aim(x, 1)
+	if g.rng.Float64() < mirvChance(g.wave) {
+		// Somewhere in the middle of the way down
+		m.SplitY = 1 + (0.3+0.3*g.rng.Float64())*float64(g.groundY()-1)
+	}
+	g.missiles = append(g.missiles, m)
+}
+
+// aim returns a missile at x, y heading for a city still standing or a
+// base, at the speed of the current wave.
+func (g *MissileDefender) aim(x, y float64) Missile {
+	var targets []Point
+	for _, c := range g.cities {
+		if c.Alive {
+			targets = append(targets, c.Pos)
+		}
+	}
+	for _, b := range g.bases {
+		targets = append(targets, b.Pos)
+	}
+	t := targets[g.rng.Intn(len(targets))]
+	dx, dy := float64(t.X)-x, float64(t.Y)-y
+	speed := missileWaveSpeed(g.wave) / max(blastDist(dx, dy), 1e-9)
+	return Missile{OX: x, OY: y, X: x, Y: y, VX: dx * speed, VY: dy * speed, Alive: true}
 }
 
-func (g *MissileDefender) update(w, h int) {
-	// Update missiles - move them downward
+// moveMissiles flies the missiles, splits MIRVs that are far enough down,
+// and blows up the missiles that hit the ground, a city or a base.
+func (g *MissileDefender) moveMissiles(dt time.Duration) {
+	var split []Missile
 	for i := range g.missiles {
-		if !g.missiles[i].Alive {
+		m := &g.missiles[i]
+		if !m.Alive {
 			continue
 		}
-		// Store previous position for line drawing
-		g.missiles[i].PrevPos = g.missiles[i].Pos
-		g.missiles[i].Pos.X += g.missiles[i].Velocity.X
-		g.missiles[i].Pos.Y += g.missiles[i].Velocity.Y
-
-		// Remove missiles that go off screen (especially bottom)
-		if g.missiles[i].Pos.Y >= h-1 || g.missiles[i].Pos.X < 1 || g.missiles[i].Pos.X >= w-1 {
-			g.missiles[i].Alive = false
+		m.X += m.VX * dt.Seconds()
+		m.Y += m.VY * dt.Seconds()
+
+		if m.SplitY > 0 && m.Y >= m.SplitY {
+			m.Alive = false
+			for n := 2 + g.rng.Intn(3); n > 0; n-- {
+				warhead := g.aim(m.X, m.Y)
+				warhead.OX, warhead.OY = m.OX, m.OY
+				warhead.FX, warhead.FY, warhead.Forked = m.X, m.Y, true
+				split = append(split, warhead)
+			}
 			continue
 		}
 
-		// Check collision with terrain
-		for _, t := range g.terrain {
-			if g.missiles[i].Pos.X == t.Pos.X && g.missiles[i].Pos.Y == t.Pos.Y {
-				g.missiles[i].Alive = false
-				break
-			}
+		c := Point{int(math.Round(m.X)), int(math.Round(m.Y))}
+		if c.X < 1 || c.X >= g.w-1 {
+			m.Alive = false
+			continue
 		}
+		if c.Y >= g.surface(c.X) || g.standingAt(c) {
+			m.Alive = false
+			g.blasts = append(g.blasts, Blast{X: m.X, Y: m.Y, Size: missileBlast, Hostile: true})
+		}
+	}
+	g.missiles = append(g.missiles, split...)
+}
 
-		// Check collision with bases
-		for _, base := range g.bases {
-			if g.missiles[i].Pos.X == base.Pos.X && g.missiles[i].Pos.Y == base.Pos.Y {
-				g.missiles[i].Alive = false
-				break
-			}
+// standingAt reports whether a city still standing or a base is at p.
+func (g *MissileDefender) standingAt(p Point) bool {
+	for _, c := range g.cities {
+		if c.Alive && c.Pos == p {
+			return true
 		}
 	}
+	for _, b := range g.bases {
+		if b.Pos == p {
+			return true
+		}
+	}
+	return false
+}
 
-	// Update bases - fire projectiles upward at missiles
+// autoFire has every base that's ready fire at the missile nearest to it,
+// aiming where the missile will be when the interceptor gets there. Missiles
+// another interceptor is already on the way to are left alone.
+func (g *MissileDefender) autoFire() {
 	for i := range g.bases {
-		if g.bases[i].LastFire > 0 {
-			g.bases[i].LastFire--
-		}
-
-		if g.bases[i].LastFire == 0 {
-			// Find closest missile above this base
-			var target *Missile
-			minDist := 10000 // Large initial distance
-			
-			for j := range g.missiles {
-				if !g.missiles[j].Alive {
-					continue
-				}
-				// Only target missiles above the base
-				if g.missiles[j].Pos.Y < g.bases[i].Pos.Y {
-					dx := g.missiles[j].Pos.X - g.bases[i].Pos.X
-					dy := g.missiles[j].Pos.Y - g.bases[i].Pos.Y
-					dist := dx*dx + dy*dy
-					if dist < minDist {
-						minDist = dist
-						target = &g.missiles[j]
-					}
-				}
+		base := &g.bases[i]
+		if base.LastFire > 0 {
+			base.LastFire--
+			continue
+		}
+		if base.Ammo == 0 {
+			continue
+		}
+		from := Point{X: base.Pos.X, Y: base.Pos.Y - 1}
+		best, bestDist := Point{}, math.Inf(1)
+		for _, m := range g.missiles {
+			if !m.Alive || m.Y >= float64(from.Y) {
+				continue
 			}
-
-			if target != nil {
-				// Fire projectile upward toward target
-				dx := target.Pos.X - g.bases[i].Pos.X
-				dy := target.Pos.Y - g.bases[i].Pos.Y
-				dist := math.Sqrt(float64(dx*dx + dy*dy))
-				if dist > 0 {
-					// Projectile moves upward (negative Y) toward target
-					velX := int(float64(dx) / dist * 2)
-					velY := int(float64(dy) / dist * 2)
-					
-					// Ensure projectile moves upward
-					if velY > 0 {
-						velY = -velY
-					}
-					if velY == 0 {
-						velY = -2 // Default upward velocity
-					}
-					
-					// Clamp velocities
-					if velX > 2 {
-						velX = 2
-					}
-					if velX < -2 {
-						velX = -2
-					}
-					if velY > -1 {
-						velY = -2
-					}
-
-					g.projectiles = append(g.projectiles, Projectile{
-						Pos:      Point{X: g.bases[i].Pos.X, Y: g.bases[i].Pos.Y - 1},
-						Velocity: Point{X: velX, Y: velY},
-						Alive:    true,
-					})
-					g.bases[i].LastFire = g.bases[i].Cooldown
-				}
+			// Lead the target, twice over to allow for the lead
+			x, y := m.X, m.Y
+			for n := 0; n < 2; n++ {
+				t := blastDist(x-float64(from.X), y-float64(from.Y)) / interceptorSpeed
+				x, y = m.X+m.VX*t, m.Y+m.VY*t
+			}
+			p := Point{int(math.Round(x)), int(math.Round(y))}
+			if p.X < 1 || p.X >= g.w-1 || p.Y < 1 || p.Y >= from.Y || g.covered(p) {
+				continue
 			}
+			if d := blastDist(float64(p.X-from.X), float64(p.Y-from.Y)); d < bestDist {
+				best, bestDist = p, d
+			}
+		}
+		if !math.IsInf(bestDist, 1) {
+			g.launch(i, best)
+			base.LastFire = base.Cooldown
 		}
 	}
+}
 
-	// Update projectiles - move them
-	for i := range g.projectiles {
-		if !g.projectiles[i].Alive {
-			continue
+// covered reports whether an interceptor is already on its way to near p.
+func (g *MissileDefender) covered(p Point) bool {
+	for _, in := range g.interceptors {
+		if blastDist(float64(in.Target.X-p.X), float64(in.Target.Y-p.Y)) < interceptorBlast/2 {
+			return true
 		}
-		g.projectiles[i].Pos.X += g.projectiles[i].Velocity.X
-		g.projectiles[i].Pos.Y += g.projectiles[i].Velocity.Y
+	}
+	return false
+}
 
-		// Remove projectiles that go off screen (especially top)
-		if g.projectiles[i].Pos.Y < 1 || g.projectiles[i].Pos.X < 1 || g.projectiles[i].Pos.X >= w-1 {
-			g.projectiles[i].Alive = false
+// moveInterceptors flies the interceptors, setting off a blast for each one
+// that gets to where it was aimed.
+func (g *MissileDefender) moveInterceptors(dt time.Duration) {
+	step := interceptorSpeed * dt.Seconds()
+	interceptors := g.interceptors[:0]
+	for _, in := range g.interceptors {
+		dx, dy := float64(in.Target.X)-in.X, float64(in.Target.Y)-in.Y
+		if d := blastDist(dx, dy); d > step {
+			in.X += dx * step / d
+			in.Y += dy * step / d
+			interceptors = append(interceptors, in)
 			continue
 		}
+		g.blasts = append(g.blasts, Blast{X: float64(in.Target.X), Y: float64(in.Target.Y), Size: interceptorBlast})
+	}
+	g.interceptors = interceptors
+}
 
-		// Check collision with missiles
+// updateBlasts grows and shrinks the blasts. Every missile a blast reaches
+// explodes too, which can set off others in a chain; a missile that hit the
+// ground also flattens the cities, empties the bases and digs away the land
+// its blast reaches.
+func (g *MissileDefender) updateBlasts(dt time.Duration) {
+	// Blasts set off along the way join the end, to go off from the next
+	// update
+	for i := 0; i < len(g.blasts); i++ {
+		g.blasts[i].Age += dt
+		b := g.blasts[i]
+		if b.Age >= blastTime {
+			continue
+		}
+		r := b.radius()
 		for j := range g.missiles {
-			if !g.missiles[j].Alive {
-				continue
-			}
-			// Check if projectile is close to missile (within 1 cell)
-			if abs(g.projectiles[i].Pos.X-g.missiles[j].Pos.X) <= 1 &&
-				abs(g.projectiles[i].Pos.Y-g.missiles[j].Pos.Y) <= 1 {
-				g.missiles[j].Alive = false
-				g.projectiles[i].Alive = false
+			m := &g.missiles[j]
+			if m.Alive && blastDist(m.X-b.X, m.Y-b.Y) <= r {
+				m.Alive = false
 				g.missilesDestroyed++
-				g.score += 10
-				break
+				g.score += missileScore
+				g.blasts = append(g.blasts, Blast{X: m.X, Y: m.Y, Size: missileBlast})
 			}
 		}
+		if b.Hostile {
+			g.damage(b, r)
+		}
 	}
 
-	// Clean up dead missiles and projectiles
-	newMissiles := []Missile{}
-	for _, m := range g.missiles {
-		if m.Alive {
-			newMissiles = append(newMissiles, m)
+	blasts := g.blasts[:0]
+	for _, b := range g.blasts {
+		if b.Age < blastTime {
+			blasts = append(blasts, b)
 		}
 	}
-	g.missiles = newMissiles
+	g.blasts = blasts
+}
 
-	newProjectiles := []Projectile{}
-	for _, p := range g.projectiles {
-		if p.Alive {
-			newProjectiles = append(newProjectiles, p)
+// damage flattens the cities and empties the bases within r of b, and digs
+// a crater in the land.
+func (g *MissileDefender) damage(b Blast, r float64) {
+	reached := func(p Point) bool { return blastDist(float64(p.X)-b.X, float64(p.Y)-b.Y) <= r }
+	for i := range g.cities {
+		if reached(g.cities[i].Pos) {
+			g.cities[i].Alive = false
+		}
+	}
+	for i := range g.bases {
+		if reached(g.bases[i].Pos) {
+			g.bases[i].Ammo = 0
 		}
 	}
-	g.projectiles = newProjectiles
+	for x := max(int(b.X-r), 1); x <= min(int(b.X+r)+1, g.w-2); x++ {
+		for g.land[x] > 0 && reached(Point{x, g.surface(x)}) {
+			g.land[x]--
+		}
+	}
+	g.settle()
+}
+
+// radius returns how far the blast reaches, in cells across.
+func (b Blast) radius() float64 {
+	return b.Size * math.Sin(math.Pi*b.Age.Seconds()/blastTime.Seconds())
+}
+
+// blastDist returns the length of dx, dy measured in cell widths: cells are
+// about twice as tall as they are wide, so blasts look round.
+func blastDist(dx, dy float64) float64 {
+	return math.Hypot(dx, 2*dy)
 }
 
-func (g *MissileDefender) draw(screen tcell.Screen, w, h int, grayscale bool) {
-	screen.Clear()
+func (g *MissileDefender) Draw(screen tcell.Screen) {
+	w, h := g.w, g.h
+	theme := g.env.Theme
 
 	// Draw border
-	borderStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-	for x := 0; x < w; x++ {
-		screen.SetContent(x, 0, '─', nil, borderStyle)
-		screen.SetContent(x, h-1, '─', nil, borderStyle)
-	}
-	for y := 0; y < h; y++ {
-		screen.SetContent(0, y, '│', nil, borderStyle)
-		screen.SetContent(w-1, y, '│', nil, borderStyle)
-	}
-	screen.SetContent(0, 0, '┌', nil, borderStyle)
-	screen.SetContent(w-1, 0, '┐', nil, borderStyle)
-	screen.SetContent(0, h-1, '└', nil, borderStyle)
-	screen.SetContent(w-1, h-1, '┘', nil, borderStyle)
-
-	// Draw solid green ground line at the bottom
-	groundY := h - 2
-	groundStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorGreen, grayscale)).Background(tcell.ColorBlack)
-	for x := 1; x < w-1; x++ {
-		screen.SetContent(x, groundY, '─', nil, groundStyle)
+	drawing.Box(screen, drawing.Rect{W: w, H: h}, theme.Style(RoleText))
+
+	// Draw solid green bedrock at the bottom, with the land on top of it
+	groundY := g.groundY()
+	drawing.FillRect(screen, drawing.Rect{X: 1, Y: groundY, W: w - 2, H: 1}, '─', theme.Style(RolePrimary))
+	landStyle := theme.Style(RolePrimary)
+	for x, height := range g.land {
+		for y := groundY - height; y < groundY; y++ {
+			screen.SetContent(x, y, '▓', nil, landStyle)
+		}
 	}
 
-	// Draw terrain (optional, can be removed or kept for variety)
-	terrainStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorYellow, grayscale)).Background(tcell.ColorBlack)
-	for _, t := range g.terrain {
-		if t.Pos.Y < groundY {
-			screen.SetContent(t.Pos.X, t.Pos.Y, '▓', nil, terrainStyle)
+	// Draw bases on the land (yellow triangles), with the interceptors they
+	// have left in the bedrock under them
+	baseStyle := theme.Style(RoleAccent)
+	for _, base := range g.bases {
+		screen.SetContent(base.Pos.X, base.Pos.Y, '▲', nil, baseStyle)
+		ammoStyle := theme.Style(RoleBright)
+		if base.Ammo == 0 {
+			ammoStyle = theme.Style(RoleDanger)
 		}
+		label := fmt.Sprint(base.Ammo)
+		drawing.Text(screen, base.Pos.X-(len(label)-1)/2, groundY, label, ammoStyle)
 	}
 
-	// Draw bases on the bottom (yellow triangles on green ground)
-	baseStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorYellow, grayscale)).Background(tcell.ColorBlack)
-	for _, base := range g.bases {
-		// Draw base on the ground line (one row above the ground line so it sits on top)
-		screen.SetContent(base.Pos.X, base.Pos.Y-1, '▲', nil, baseStyle)
+	// Draw cities, or the rubble of them
+	for _, city := range g.cities {
+		if city.Alive {
+			screen.SetContent(city.Pos.X, city.Pos.Y, '▆', nil, theme.Style(RolePrimaryBright))
+		} else {
+			screen.SetContent(city.Pos.X, city.Pos.Y, '▁', nil, theme.Style(RoleMuted))
+		}
 	}
 
-	// Draw missiles falling from sky as cyan lines
-	missileStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorBlue, grayscale)).Background(tcell.ColorBlack)
-	for _, missile := range g.missiles {
-		if missile.Alive {
-			// Draw line from previous position to current position
-			drawLine(screen, missile.PrevPos, missile.Pos, missileStyle)
+	// Draw missiles falling from sky with their trails as thin lines at
+	// Braille resolution, MIRVs in red until they split
+	if g.canvas == nil {
+		g.canvas = drawing.NewCanvas(drawing.Braille, w, h)
+	}
+	g.canvas.Clear()
+	sx, sy := g.canvas.Scale()
+	dot := func(x, y float64) (float64, float64) {
+		// Cell coordinates to the dot at the middle of the cell
+		return x*float64(sx) + float64(sx)/2, y*float64(sy) + float64(sy)/2
+	}
+	line := func(x0, y0, x1, y1 float64, color tcell.Color) {
+		x0, y0 = dot(x0, y0)
+		x1, y1 = dot(x1, y1)
+		g.canvas.Line(x0, y0, x1, y1, color)
+	}
+	for _, m := range g.missiles {
+		color := theme.Color(RoleCool)
+		if m.SplitY > 0 {
+			color = theme.Color(RoleDanger)
+		}
+		if m.Forked {
+			line(m.OX, m.OY, m.FX, m.FY, color)
+			line(m.FX, m.FY, m.X, m.Y, color)
+		} else {
+			line(m.OX, m.OY, m.X, m.Y, color)
 		}
 	}
 
-	// Draw projectiles (defensive shots) - white dots
-	projectileStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-	for _, proj := range g.projectiles {
-		if proj.Alive {
-			screen.SetContent(proj.Pos.X, proj.Pos.Y, '*', nil, projectileStyle)
+	// Interceptor trails, and the blasts over everything else
+	for _, in := range g.interceptors {
+		line(float64(in.From.X), float64(in.From.Y), in.X, in.Y, theme.Color(RoleText))
+	}
+	for _, b := range g.blasts {
+		color := theme.Color(RoleBright)
+		if b.Hostile {
+			color = theme.Color(RoleDanger)
 		}
+		x, y := dot(b.X, b.Y)
+		g.canvas.Disk(x, y, b.radius()*float64(sx), color)
+	}
+	g.canvas.Draw(screen, 0, 0, theme.Color(RoleBackground))
+
+	// Where the interceptors are going
+	for _, in := range g.interceptors {
+		screen.SetContent(in.Target.X, in.Target.Y, 'x', nil, theme.Style(RoleMuted))
 	}
 
 	// Draw score in cyan
-	scoreStr := fmt.Sprintf("SCORE= %d", g.score)
-	scoreStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorBlue, grayscale)).Background(tcell.ColorBlack)
-	for i, char := range scoreStr {
-		if i+1 < w {
-			screen.SetContent(i+1, 1, char, nil, scoreStyle)
-		}
+	drawing.Text(screen, 1, 1, fmt.Sprintf("SCORE= %d  WAVE= %d", g.score, g.wave), theme.Style(RoleCool))
+
+	switch {
+	case g.over > 0:
+		drawing.Banner(screen, []string{"THE END", fmt.Sprintf("Score %d", g.score)}, theme.Style(RoleBright))
+	case g.pause > 0:
+		drawing.Banner(screen, g.summary, theme.Style(RoleBright))
+	case g.env.Interactive:
+		screen.SetContent(g.crosshair.X, g.crosshair.Y, '+', nil, theme.Style(RoleBright))
 	}
 }
 
@@ -425,54 +732,3 @@ func abs(x int) int {
 	}
 	return x
 }
-
-// drawLine draws a line between two points using simple line drawing
-func drawLine(screen tcell.Screen, p1, p2 Point, style tcell.Style) {
-	dx := abs(p2.X - p1.X)
-	dy := abs(p2.Y - p1.Y)
-	sx := 1
-	if p1.X > p2.X {
-		sx = -1
-	}
-	sy := 1
-	if p1.Y > p2.Y {
-		sy = -1
-	}
-	err := dx - dy
-
-	x, y := p1.X, p1.Y
-	maxSteps := dx + dy
-	if maxSteps == 0 {
-		maxSteps = 1
-	}
-	
-	char := '│'
-	if dx > dy {
-		char = '─'
-	} else if (p2.X > p1.X && p2.Y > p1.Y) || (p2.X < p1.X && p2.Y < p1.Y) {
-		char = '\\'
-	} else {
-		char = '/'
-	}
-
-	for i := 0; i <= maxSteps; i++ {
-		w, h := screen.Size()
-		if x >= 1 && x < w-1 && y >= 1 && y < h-1 {
-			screen.SetContent(x, y, char, nil, style)
-		}
-		
-		if x == p2.X && y == p2.Y {
-			break
-		}
-		
-		e2 := 2 * err
-		if e2 > -dy {
-			err -= dy
-			x += sx
-		}
-		if e2 < dx {
-			err += dx
-			y += sy
-		}
-	}
-}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newInteractiveMissileDefender(t *testing.T) (*harness, *MissileDefender) {
+	t.Helper()
+	env := defaultEnv()
+	env.Interactive = true
+	h := newHarness(t, "missiledefender", env, 60, 20, 1)
+	return h, h.mode.(*MissileDefender)
+}
+
+// step is how far the harness moves missiledefender on each frame.
+const step = 100 * time.Millisecond
+
+func TestMissileDefenderInteractiveGoldenFrame(t *testing.T) {
+	h, g := newInteractiveMissileDefender(t)
+	h.Step(40)
+	g.HandleEvent(tcell.NewEventMouse(30, 8, tcell.Button1, tcell.ModNone))
+	g.HandleEvent(tcell.NewEventMouse(30, 8, tcell.ButtonNone, tcell.ModNone))
+	g.HandleEvent(key(tcell.KeyLeft, 0))
+	g.HandleEvent(key(tcell.KeyRune, ' '))
+	h.Step(9)
+	checkGolden(t, "missiledefender-interactive", h.Frame())
+}
+
+func TestMissileDefenderFiring(t *testing.T) {
+	_, g := newInteractiveMissileDefender(t)
+	g.missiles = nil
+	if len(g.cities) != 6 {
+		t.Fatalf("game has %d cities", len(g.cities))
+	}
+
+	// Fires from the nearest base, then the next nearest once it's empty
+	base := g.bases[1]
+	g.moveCrosshair(base.Pos.X, 5)
+	for i := 0; i < 10; i++ {
+		g.HandleEvent(key(tcell.KeyEnter, 0))
+	}
+	if g.bases[1].Ammo != 0 || len(g.interceptors) != 10 {
+		t.Fatalf("nearest base has %d left after 10 shots, %d in the air", g.bases[1].Ammo, len(g.interceptors))
+	}
+	g.HandleEvent(key(tcell.KeyEnter, 0))
+	if in := g.interceptors[10]; in.From.X == base.Pos.X || in.Target != (Point{base.Pos.X, 5}) {
+		t.Errorf("11th interceptor from %v to %v", in.From, in.Target)
+	}
+
+	// It explodes where it was aimed and takes out a missile there
+	g.interceptors = g.interceptors[:1]
+	g.missiles = []Missile{{X: float64(base.Pos.X + 1), Y: 5, Alive: true}}
+	for i := 0; i < 10; i++ {
+		g.moveInterceptors(step)
+		g.updateBlasts(step)
+	}
+	if len(g.interceptors) != 0 || len(g.blasts) != 2 {
+		t.Fatalf("%d interceptors and %d blasts, want the blast and the missile's", len(g.interceptors), len(g.blasts))
+	}
+	if g.missiles[0].Alive || g.score != missileScore {
+		t.Errorf("missile next to the blast survived, score %d", g.score)
+	}
+
+	// Other keys still reach the runner
+	if g.HandleEvent(key(tcell.KeyRune, 's')) {
+		t.Errorf("s was taken from the runner")
+	}
+}
+
+func TestMissileDefenderCitiesLost(t *testing.T) {
+	h, g := newInteractiveMissileDefender(t)
+	g.missiles = nil
+	for i := range g.cities {
+		p := g.cities[i].Pos
+		g.missiles = append(g.missiles, Missile{X: float64(p.X), Y: float64(p.Y) - 0.5, VY: 5, Alive: true})
+	}
+	h.Step(10)
+	if g.over <= 0 {
+		t.Fatalf("game goes on with every city hit")
+	}
+	g.HandleEvent(key(tcell.KeyEnter, 0))
+	if len(g.interceptors) != 0 {
+		t.Errorf("fired after the game was over")
+	}
+	h.Step(int(gameOverTime / step))
+	if g.over > 0 || !g.cities[0].Alive || g.wave != 1 || g.score != 0 {
+		t.Errorf("no new game after the final score")
+	}
+}
+
+func TestMissileDefenderMIRVSplits(t *testing.T) {
+	_, g := newInteractiveMissileDefender(t)
+	g.missiles = []Missile{{OX: 30, OY: 1, X: 30, Y: 7.9, VY: 4, SplitY: 8, Alive: true}}
+	g.moveMissiles(step)
+	if n := len(g.missiles); n < 3 || n > 5 || g.missiles[0].Alive {
+		t.Fatalf("MIRV split into %d warheads, itself alive %t", n-1, g.missiles[0].Alive)
+	}
+	for _, m := range g.missiles[1:] {
+		if !m.Alive || !m.Forked || m.OX != 30 || m.FY != g.missiles[0].Y || m.SplitY != 0 || m.VY <= 0 {
+			t.Errorf("warhead %+v", m)
+		}
+	}
+}
+
+func TestMissileDefenderChainReaction(t *testing.T) {
+	_, g := newInteractiveMissileDefender(t)
+	// The second missile is out of reach of the blast, but not of the first
+	// missile's
+	g.missiles = []Missile{
+		{X: 33, Y: 5, Alive: true},
+		{X: 35.5, Y: 5, Alive: true},
+	}
+	g.blasts = []Blast{{X: 30, Y: 5, Size: interceptorBlast}}
+	for i := 0; i < int(blastTime/step); i++ {
+		g.updateBlasts(step)
+	}
+	if g.missiles[0].Alive || g.missiles[1].Alive || g.missilesDestroyed != 2 {
+		t.Errorf("chain reaction destroyed %d missiles", g.missilesDestroyed)
+	}
+	for i := 0; i < int(blastTime/step); i++ {
+		g.updateBlasts(step)
+	}
+	if len(g.blasts) != 0 {
+		t.Errorf("%d blasts left after they all shrank away", len(g.blasts))
+	}
+}
+
+func TestMissileDefenderImpact(t *testing.T) {
+	_, g := newInteractiveMissileDefender(t)
+	city := g.cities[2]
+	for x := range g.land {
+		g.land[x] = min(g.land[x]+2, 3)
+	}
+	g.settle()
+	land := append([]int(nil), g.land...)
+
+	// A missile coming straight down on the city flattens it and digs into
+	// the land around it
+	g.missiles = []Missile{{X: float64(city.Pos.X), Y: float64(city.Pos.Y - 3), VY: 10, Alive: true}}
+	for i := 0; i < 10; i++ {
+		g.moveMissiles(step)
+		g.updateBlasts(step)
+	}
+	if g.cities[2].Alive || !g.cities[0].Alive {
+		t.Errorf("after the impact, city alive %t, far city alive %t", g.cities[2].Alive, g.cities[0].Alive)
+	}
+	if g.land[city.Pos.X] >= land[city.Pos.X] || g.land[1] != land[1] {
+		t.Errorf("land under the impact %d, was %d; far away %d, was %d", g.land[city.Pos.X], land[city.Pos.X], g.land[1], land[1])
+	}
+	if y := g.cities[2].Pos.Y; y != g.surface(city.Pos.X)-1 {
+		t.Errorf("rubble at row %d, land at %d", y, g.surface(city.Pos.X))
+	}
+}
+
+func TestMissileDefenderWaves(t *testing.T) {
+	h, g := newInteractiveMissileDefender(t)
+	if g.wave != 1 || g.toSpawn != missileWaveSize(1) {
+		t.Fatalf("first wave %d has %d missiles", g.wave, g.toSpawn)
+	}
+	if missileWaveSize(5) <= missileWaveSize(1) || missileWaveSpeed(5) <= missileWaveSpeed(1) || mirvChance(1) != 0 || mirvChance(5) <= 0 {
+		t.Errorf("waves don't get any harder")
+	}
+
+	// Clearing the wave shows a summary and pays for what's left
+	g.HandleEvent(key(tcell.KeyEnter, 0))
+	g.toSpawn, g.missiles, g.interceptors = 0, nil, nil
+	g.cities[0].Alive = false
+	h.Step(1)
+	ammo := len(g.bases)*10 - 1
+	if g.pause <= 0 || g.score != 5*cityBonus+ammo*ammoBonus || len(g.summary) == 0 {
+		t.Fatalf("after the wave: pause %v, score %d, summary %q", g.pause, g.score, g.summary)
+	}
+	g.HandleEvent(key(tcell.KeyEnter, 0))
+	if len(g.interceptors) != 0 {
+		t.Errorf("fired between waves")
+	}
+
+	// Then the next wave comes, with the bases restocked
+	h.Step(int(summaryTime / step))
+	if g.wave != 2 || g.toSpawn != missileWaveSize(2) || g.bases[0].Ammo != 10 || g.cities[0].Alive {
+		t.Errorf("second wave %d has %d missiles, %d ammo", g.wave, g.toSpawn, g.bases[0].Ammo)
+	}
+}
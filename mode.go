@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Mode is a single visualization. The runner owns the screen, the event loop
+// and the frame timing; a mode only keeps its own state, advances it one step
+// at a time and draws it. Modes never read the wall clock: anything timed is
+// measured in simulation time accumulated from Update.
+type Mode interface {
+	// Init sets up the mode for a w x h screen.
+	Init(env *Env, w, h int)
+	// Update advances the simulation by one fixed timestep of length dt.
+	Update(dt time.Duration)
+	// Draw renders the current state. The screen has already been cleared
+	// to the theme's background.
+	Draw(screen tcell.Screen)
+	// HandleEvent offers an input event to the mode and reports whether the
+	// mode consumed it. Keys that are not consumed fall through to the
+	// runner: space cycles to the next mode and, unless -interactive is set,
+	// any other key exits.
+	HandleEvent(ev tcell.Event) bool
+	// Resize is called when the terminal size changes.
+	Resize(w, h int)
+}
+
+// Reconfigurer is implemented by modes that copy settings out of the config
+// when they start. Reconfigure is called after the config has been reloaded
+// so they can pick up the new values from env.Config.
+type Reconfigurer interface {
+	Reconfigure(env *Env)
+}
+
+// Env holds the settings every mode is started with.
+type Env struct {
+	Interactive bool
+	Theme       *Theme
+	Rand        *rand.Rand // Source for all of a mode's randomness, seeded per mode by the runner
+	Config      *Config    // Each mode reads its own section
+}
+
+type modeInfo struct {
+	name        string
+	description string
+	step        time.Duration // Simulation timestep passed to Update
+	new         func() Mode
+	hidden      bool // Only shown when asked for by name, never in the default rotation
+}
+
+// registry holds every registered mode sorted by name, which is also the
+// order space cycles through them.
+var registry []modeInfo
+
+// registerMode adds a mode to the registry. Each mode calls it from an init
+// function in its own file.
+func registerMode(info modeInfo) {
+	if _, ok := lookupMode(info.name); ok {
+		panic(fmt.Sprintf("mode %q registered twice", info.name))
+	}
+	registry = append(registry, info)
+	sort.Slice(registry, func(i, j int) bool {
+		return registry[i].name < registry[j].name
+	})
+}
+
+// lookupMode returns the registry index of the named mode.
+func lookupMode(name string) (int, bool) {
+	for i, info := range registry {
+		if info.name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func modeNames() []string {
+	names := make([]string, len(registry))
+	for i, info := range registry {
+		names[i] = info.name
+	}
+	return names
+}
@@ -1,115 +1,84 @@
 package main
 
 import (
-	"os"
 	"time"
 
+	"github.com/chorankates/termsaver/drawing"
 	"github.com/gdamore/tcell/v2"
 )
 
-var rainbow = []tcell.Color{
-	tcell.ColorRed,
-	tcell.ColorOrange,
-	tcell.ColorYellow,
-	tcell.ColorGreen,
-	tcell.ColorBlue,
-	tcell.ColorPurple,
+func init() {
+	registerMode(modeInfo{
+		name:        "nyancat",
+		description: "animated rainbow-trailing cat flying through space",
+		step:        100 * time.Millisecond,
+		new:         func() Mode { return &Nyancat{} },
+	})
 }
 
-func runNyancat(screen tcell.Screen, sigChan chan os.Signal, interactive bool, grayscale bool) bool {
-	w, h := screen.Size()
-
-	// Nyancat sprite (simplified ASCII art)
-	catSprite := []string{
+// Nyancat sprite (simplified ASCII art), paddling its legs
+var catSprite = drawing.NewSprite(
+	[]string{
 		"   ,,,",
 		"  (*_*)",
 		" (    )",
 		"  \"  \"",
-	}
+	},
+	[]string{
+		"   ,,,",
+		"  (*_*)",
+		" (    )",
+		" \"  \"",
+	},
+)
 
-	catY := h / 2
-	x := 0
+type Nyancat struct {
+	env  *Env
+	w, h int
+	catY int
+	x    int
+}
 
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+func (n *Nyancat) Init(env *Env, w, h int) {
+	n.env = env
+	n.w, n.h = w, h
+	n.catY = h / 2
+	n.x = 0
+}
 
-	// Event handling for resize
-	eventChan := make(chan tcell.Event, 10)
-	go func() {
-		for {
-			eventChan <- screen.PollEvent()
-		}
-	}()
+func (n *Nyancat) Resize(w, h int) {
+	n.w, n.h = w, h
+	n.catY = h / 2
+}
 
-	for {
-		select {
-		case <-sigChan:
-			return false
-		case event := <-eventChan:
-			switch ev := event.(type) {
-			case *tcell.EventResize:
-				w, h = screen.Size()
-				catY = h / 2
-				screen.Sync()
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
-					return false
-				}
-				// Space cycles to next mode
-				if ev.Rune() == ' ' {
-					return true
-				}
-				// In non-interactive mode, any key exits
-				if !interactive {
-					return false
-				}
-			}
-		case <-ticker.C:
-			screen.Clear()
+func (n *Nyancat) HandleEvent(ev tcell.Event) bool {
+	return false
+}
 
-			// Draw rainbow trail
-			for i := 0; i < w; i++ {
-				if x-i >= 0 && x-i < len(rainbow)*3 {
-					color := rainbow[(x-i)/3%len(rainbow)]
-					style := tcell.StyleDefault.Foreground(toGrayscale(color, grayscale)).Background(tcell.ColorBlack)
-					for j := 0; j < 3; j++ {
-						if catY+j < h && catY+j >= 0 {
-							screen.SetContent(i, catY+j, '▔', nil, style)
-						}
-					}
-				}
-			}
+func (n *Nyancat) Update(dt time.Duration) {
+	n.x++
+	if n.x >= n.w+20 {
+		n.x = -20
+	}
+}
 
-			// Draw nyancat
-			catX := x
-			for i, line := range catSprite {
-				y := catY + i - len(catSprite)/2
-				if y >= 0 && y < h {
-					for j, char := range line {
-						px := catX + j
-						if px >= 0 && px < w {
-							style := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-							screen.SetContent(px, y, char, nil, style)
-						}
-					}
-				}
-			}
+func (n *Nyancat) Draw(screen tcell.Screen) {
+	w, h := n.w, n.h
+	x, catY := n.x, n.catY
+	theme := n.env.Theme
 
-			// Draw stars in background
-			for i := 0; i < 20; i++ {
-				sx := (x + i*7) % w
-				sy := (i * 3) % h
-				style := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-				screen.SetContent(sx, sy, '*', nil, style)
-			}
+	// Draw rainbow trail, three columns per stripe
+	for i := 0; i < theme.Stops(GradientRainbow); i++ {
+		style := theme.StyleColor(theme.Stop(GradientRainbow, i))
+		drawing.FillRect(screen, drawing.Rect{X: x - i*3 - 2, Y: catY, W: 3, H: 3}, '▔', style)
+	}
 
-			x++
-			if x >= w+20 {
-				x = -20
-			}
+	// Draw nyancat
+	_, catH := catSprite.Size()
+	catSprite.Draw(screen, x, catY-catH/2, x/2, theme.Style(RoleText))
 
-			screen.Show()
-		}
+	// Draw stars in background
+	for i := 0; i < 20 && w > 0 && h > 0; i++ {
+		drawing.Set(screen, (x+i*7)%w, (i*3)%h, '*', theme.Style(RoleText))
 	}
 }
-
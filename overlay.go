@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chorankates/termsaver/drawing"
+	"github.com/gdamore/tcell/v2"
+)
+
+// OverlayConfig is the overlay section of the config file: information
+// drawn on top of whichever mode is running. Nothing is drawn unless at
+// least one of the items is turned on.
+type OverlayConfig struct {
+	Clock      bool   `json:"clock"`   // Large digital clock
+	Seconds    bool   `json:"seconds"` // Include seconds on the clock
+	Date       bool   `json:"date"`
+	DateFormat string `json:"date_format"` // Go time layout
+	Hostname   bool   `json:"hostname"`
+	Load       bool   `json:"load"` // Load averages, from /proc/loadavg
+	Message    string `json:"message"`
+	Position   string `json:"position"`   // One of overlayPositions
+	Color      string `json:"color"`      // Theme role for the clock
+	TextColor  string `json:"text_color"` // Theme role for everything else
+}
+
+// overlayPositions are where the overlay can go. A bouncing overlay drifts
+// around the screen, bouncing off the edges.
+var overlayPositions = []string{"top-left", "top-right", "bottom-left", "bottom-right", "center", "bounce"}
+
+// bounceSpeed is how far a bouncing overlay moves each second, in cells.
+const bounceSpeed = 4
+
+// loadTime is how often the load averages are read again.
+const loadTime = 5 * time.Second
+
+// overlay draws the clock, date, hostname, load and message on top of the
+// running mode. It follows the session clock, so tests see a fixed time.
+type overlay struct {
+	cfg      OverlayConfig
+	hostname string
+	readLoad func() (string, error) // Load averages, as shown
+
+	clock  string   // Big clock text, if it fits
+	lines  []string // Small lines under it
+	x, y   float64  // Top left corner of the box
+	dx, dy float64  // Bounce direction
+	last   time.Time
+	load   string
+	loadAt time.Time
+}
+
+// newOverlay returns the overlay for cfg, or nil if it shows nothing.
+func newOverlay(cfg OverlayConfig) *overlay {
+	if !cfg.Clock && !cfg.Date && !cfg.Hostname && !cfg.Load && cfg.Message == "" {
+		return nil
+	}
+	o := &overlay{cfg: cfg, readLoad: readLoadavg, x: 1, y: 1, dx: 1, dy: 0.5}
+	if cfg.Hostname {
+		o.hostname, _ = os.Hostname()
+	}
+	return o
+}
+
+// readLoadavg returns the 1, 5 and 15 minute load averages from /proc.
+func readLoadavg() (string, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return "", fmt.Errorf("unexpected /proc/loadavg: %q", data)
+	}
+	return "load " + strings.Join(fields[:3], " "), nil
+}
+
+// update works out what the overlay shows at now on a w x h screen, and
+// where, and reports whether that differs from the last call.
+func (o *overlay) update(now time.Time, w, h int) bool {
+	clock := ""
+	var lines []string
+	if o.cfg.Clock {
+		layout := "15:04"
+		if o.cfg.Seconds {
+			layout = "15:04:05"
+		}
+		clock = now.Format(layout)
+		if drawing.BigTextWidth(clock)+2 > w || drawing.BigTextHeight+2 > h {
+			// Too big for the screen, so it becomes an ordinary line
+			lines = append(lines, clock)
+			clock = ""
+		}
+	}
+	if o.cfg.Date {
+		lines = append(lines, now.Format(o.cfg.DateFormat))
+	}
+	if o.cfg.Hostname && o.hostname != "" {
+		lines = append(lines, o.hostname)
+	}
+	if o.cfg.Load {
+		if o.loadAt.IsZero() || now.Sub(o.loadAt) >= loadTime {
+			o.load, _ = o.readLoad()
+			o.loadAt = now
+		}
+		if o.load != "" {
+			lines = append(lines, o.load)
+		}
+	}
+	if o.cfg.Message != "" {
+		lines = append(lines, o.cfg.Message)
+	}
+
+	oldX, oldY := int(o.x), int(o.y)
+	bw, bh := o.size(clock, lines)
+	o.place(now, w-bw, h-bh)
+	changed := clock != o.clock || strings.Join(lines, "\n") != strings.Join(o.lines, "\n") ||
+		int(o.x) != oldX || int(o.y) != oldY
+	o.clock, o.lines = clock, lines
+	return changed
+}
+
+// size returns the size of the box holding clock and lines, with a cell of
+// padding all round.
+func (o *overlay) size(clock string, lines []string) (int, int) {
+	w, h := 0, len(lines)
+	if clock != "" {
+		w = drawing.BigTextWidth(clock)
+		h += drawing.BigTextHeight
+		if len(lines) > 0 {
+			h++
+		}
+	}
+	for _, line := range lines {
+		w = max(w, len([]rune(line)))
+	}
+	return w + 2, h + 2
+}
+
+// place moves the box for the configured position, given the largest x and
+// y its top left corner can have.
+func (o *overlay) place(now time.Time, maxX, maxY int) {
+	maxX, maxY = max(maxX, 0), max(maxY, 0)
+	switch o.cfg.Position {
+	case "top-left":
+		o.x, o.y = 0, 0
+	case "top-right":
+		o.x, o.y = float64(maxX), 0
+	case "bottom-left":
+		o.x, o.y = 0, float64(maxY)
+	case "bottom-right":
+		o.x, o.y = float64(maxX), float64(maxY)
+	case "center":
+		o.x, o.y = float64(maxX/2), float64(maxY/2)
+	case "bounce":
+		// Frames can come far apart, e.g. after the terminal was
+		// suspended, so the box never jumps more than a second's worth
+		dt := min(now.Sub(o.last), time.Second)
+		if o.last.IsZero() {
+			dt = 0
+		}
+		o.last = now
+		o.x += o.dx * bounceSpeed * dt.Seconds()
+		o.y += o.dy * bounceSpeed * dt.Seconds()
+		o.x, o.dx = bounce(o.x, o.dx, float64(maxX))
+		o.y, o.dy = bounce(o.y, o.dy, float64(maxY))
+	}
+}
+
+// bounce keeps pos between 0 and hi, reflecting it and its direction d off
+// whichever end it went past.
+func bounce(pos, d, hi float64) (float64, float64) {
+	switch {
+	case hi <= 0:
+		return 0, d
+	case pos < 0:
+		return min(-pos, hi), -d
+	case pos > hi:
+		return max(2*hi-pos, 0), -d
+	}
+	return pos, d
+}
+
+// draw draws the overlay where update last put it, on the theme's
+// background so it stays readable over the mode.
+func (o *overlay) draw(screen tcell.Screen, theme *Theme) {
+	bw, bh := o.size(o.clock, o.lines)
+	box := drawing.Rect{X: int(o.x), Y: int(o.y), W: bw, H: bh}
+	drawing.FillRect(screen, box, ' ', theme.Style(RoleBackground))
+	y := box.Y + 1
+	if o.clock != "" {
+		cw := drawing.BigTextWidth(o.clock)
+		drawing.BigText(screen, box.X+1+(bw-2-cw)/2, y, o.clock, theme.Style(roleNames[o.cfg.Color]))
+		y += drawing.BigTextHeight + 1
+	}
+	for _, line := range o.lines {
+		drawing.CenterText(screen, box.X+1, y, bw-2, line, theme.Style(roleNames[o.cfg.TextColor]))
+		y++
+	}
+}
+
+// validate checks the position and colors are ones the overlay knows.
+func (c OverlayConfig) validate() error {
+	known := false
+	for _, p := range overlayPositions {
+		known = known || p == c.Position
+	}
+	if !known {
+		return fmt.Errorf("Unknown overlay position: %s. Use: %s", c.Position, strings.Join(overlayPositions, ", "))
+	}
+	for _, role := range []string{c.Color, c.TextColor} {
+		if _, ok := roleNames[role]; !ok {
+			names := make([]string, 0, len(roleNames))
+			for name := range roleNames {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("Unknown overlay color: %s. Use a theme role: %s", role, strings.Join(names, ", "))
+		}
+	}
+	if c.DateFormat == "" {
+		return fmt.Errorf("Invalid overlay date_format: must not be empty")
+	}
+	return nil
+}
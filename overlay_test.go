@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testOverlay returns an overlay for cfg that doesn't depend on the machine
+// the test runs on.
+func testOverlay(cfg OverlayConfig) *overlay {
+	o := newOverlay(cfg)
+	o.hostname = "build-box"
+	o.readLoad = func() (string, error) { return "load 0.42 0.17 0.05", nil }
+	return o
+}
+
+func TestOverlayGoldenFrame(t *testing.T) {
+	cfg := defaultConfig().Overlay
+	cfg.Clock, cfg.Date, cfg.Hostname, cfg.Load = true, true, true, true
+	cfg.Message = "standup at 10"
+	h := newHarness(t, "matrix", defaultEnv(), 60, 20, 1)
+	h.session.overlay = testOverlay(cfg)
+	h.Step(10)
+	checkGolden(t, "overlay", h.Frame())
+}
+
+func TestOverlayPositions(t *testing.T) {
+	now := newManualClock().Now()
+	tests := []struct {
+		position string
+		x, y     int
+	}{
+		{"top-left", 0, 0},
+		{"top-right", 60 - 15, 0},
+		{"bottom-left", 0, 20 - 3},
+		{"bottom-right", 60 - 15, 20 - 3},
+		{"center", (60 - 15) / 2, (20 - 3) / 2},
+	}
+	for _, tt := range tests {
+		cfg := defaultConfig().Overlay
+		cfg.Message, cfg.Position = "standup at 10", tt.position
+		o := testOverlay(cfg)
+		o.update(now, 60, 20)
+		if int(o.x) != tt.x || int(o.y) != tt.y {
+			t.Errorf("%s: overlay at %d,%d, want %d,%d", tt.position, int(o.x), int(o.y), tt.x, tt.y)
+		}
+	}
+}
+
+// TestOverlayBounce checks a bouncing overlay keeps moving and never leaves
+// the screen, and that the clock shrinks to a line on small screens.
+func TestOverlayBounce(t *testing.T) {
+	cfg := defaultConfig().Overlay
+	cfg.Clock, cfg.Position = true, "bounce"
+	o := testOverlay(cfg)
+	clock := newManualClock()
+	moves := 0
+	for i := 0; i < 1000; i++ {
+		if o.update(clock.Now(), 40, 12) {
+			moves++
+		}
+		w, h := o.size(o.clock, o.lines)
+		if o.x < 0 || o.y < 0 || int(o.x)+w > 40 || int(o.y)+h > 12 {
+			t.Fatalf("step %d: %dx%d overlay at %g,%g is off the 40x12 screen", i, w, h, o.x, o.y)
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+	if moves < 100 {
+		t.Errorf("overlay only changed %d times in 100s", moves)
+	}
+
+	o.update(clock.Now(), 20, 5)
+	if o.clock != "" || len(o.lines) != 1 {
+		t.Errorf("on a small screen the clock is %q and lines %q, want a single line", o.clock, o.lines)
+	}
+}
+
+func TestOverlayConfig(t *testing.T) {
+	if newOverlay(defaultConfig().Overlay) != nil {
+		t.Errorf("overlay shown with nothing turned on")
+	}
+	for _, args := range [][]string{
+		{"-overlay-position", "middle"},
+		{"-config", writeConfig(t, `{"overlay": {"color": "purple"}}`)},
+	} {
+		if _, _, err := parseTestConfig(args...); err == nil {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
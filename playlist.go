@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlaylistEntry is one mode in a playlist.
+type PlaylistEntry struct {
+	Mode     string
+	Duration time.Duration // How long to show the mode, 0 = until space is pressed
+	Weight   float64       // Relative chance of being picked when shuffling
+}
+
+// Playlist is the list of modes to rotate through. It is written as
+// "name[:duration[:weight]],..." both on the command line and in the config
+// file, e.g. "matrix:2m,lightning:90s:2,snake".
+type Playlist []PlaylistEntry
+
+func (p *Playlist) String() string {
+	if p == nil {
+		return ""
+	}
+	entries := make([]string, len(*p))
+	for i, e := range *p {
+		entry := e.Mode
+		if e.Duration > 0 || e.Weight != 1 {
+			entry += ":"
+			if e.Duration > 0 {
+				entry += formatDuration(e.Duration)
+			}
+		}
+		if e.Weight != 1 {
+			entry += ":" + strconv.FormatFloat(e.Weight, 'g', -1, 64)
+		}
+		entries[i] = entry
+	}
+	return strings.Join(entries, ",")
+}
+
+// formatDuration is d.String() without trailing zero units, so two minutes is
+// "2m" rather than "2m0s".
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Set parses a playlist, replacing the current one.
+func (p *Playlist) Set(s string) error {
+	var list Playlist
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fields := strings.Split(entry, ":")
+		if len(fields) > 3 {
+			return fmt.Errorf("playlist entry %q: want name[:duration[:weight]]", entry)
+		}
+
+		e := PlaylistEntry{Mode: fields[0], Weight: 1}
+		if _, ok := lookupMode(e.Mode); !ok {
+			return fmt.Errorf("playlist entry %q: unknown mode %s", entry, e.Mode)
+		}
+		if len(fields) > 1 && fields[1] != "" {
+			d, err := time.ParseDuration(fields[1])
+			if err != nil || d < 0 {
+				return fmt.Errorf("playlist entry %q: bad duration %s", entry, fields[1])
+			}
+			e.Duration = d
+		}
+		if len(fields) > 2 {
+			w, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil || w <= 0 {
+				return fmt.Errorf("playlist entry %q: weight must be a positive number", entry)
+			}
+			e.Weight = w
+		}
+		list = append(list, e)
+	}
+	*p = list
+	return nil
+}
+
+func (p Playlist) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Playlist) UnmarshalText(text []byte) error {
+	return p.Set(string(text))
+}
+
+func (p Playlist) contains(mode string) bool {
+	for _, e := range p {
+		if e.Mode == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultPlaylist shows every mode that isn't hidden in registry order until
+// space is pressed.
+func defaultPlaylist() Playlist {
+	var list Playlist
+	for _, info := range registry {
+		if !info.hidden {
+			list = append(list, PlaylistEntry{Mode: info.name, Weight: 1})
+		}
+	}
+	return list
+}
+
+// playlistPlayer picks which playlist entry to show next, either in order or
+// shuffled by weight without showing the same entry twice in a row.
+type playlistPlayer struct {
+	entries Playlist
+	shuffle bool
+	rng     *rand.Rand
+	current int
+}
+
+// newPlaylistPlayer starts at the entry for the named mode, or at a random
+// entry for "random".
+func newPlaylistPlayer(entries Playlist, shuffle bool, rng *rand.Rand, start string) *playlistPlayer {
+	p := &playlistPlayer{entries: entries, shuffle: shuffle, rng: rng}
+	if start == "random" {
+		p.current = p.pick(-1)
+		return p
+	}
+	for i, e := range entries {
+		if e.Mode == start {
+			p.current = i
+			break
+		}
+	}
+	return p
+}
+
+func (p *playlistPlayer) Current() PlaylistEntry {
+	return p.entries[p.current]
+}
+
+// Next moves on to the next entry and returns it.
+func (p *playlistPlayer) Next() PlaylistEntry {
+	if p.shuffle && len(p.entries) > 1 {
+		p.current = p.pick(p.current)
+	} else {
+		p.current = (p.current + 1) % len(p.entries)
+	}
+	return p.Current()
+}
+
+// pick chooses a random entry by weight, never the one at index skip.
+func (p *playlistPlayer) pick(skip int) int {
+	total := 0.0
+	for i, e := range p.entries {
+		if i != skip {
+			total += e.Weight
+		}
+	}
+	r := p.rng.Float64() * total
+	last := 0
+	for i, e := range p.entries {
+		if i == skip {
+			continue
+		}
+		if r < e.Weight {
+			return i
+		}
+		r -= e.Weight
+		last = i
+	}
+	return last
+}
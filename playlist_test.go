@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParsePlaylist(t *testing.T) {
+	var p Playlist
+	if err := p.Set("matrix:2m, lightning:90s:2,snake,nyancat::3"); err != nil {
+		t.Fatal(err)
+	}
+	want := Playlist{
+		{Mode: "matrix", Duration: 2 * time.Minute, Weight: 1},
+		{Mode: "lightning", Duration: 90 * time.Second, Weight: 2},
+		{Mode: "snake", Weight: 1},
+		{Mode: "nyancat", Weight: 3},
+	}
+	if len(p) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(p), len(want))
+	}
+	for i := range want {
+		if p[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, p[i], want[i])
+		}
+	}
+	if got := p.String(); got != "matrix:2m,lightning:1m30s:2,snake,nyancat::3" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestParsePlaylistErrors(t *testing.T) {
+	for _, s := range []string{"nope", "matrix:soon", "matrix:-1m", "matrix:1m:0", "matrix:1m:x", "matrix:1m:1:1"} {
+		var p Playlist
+		if err := p.Set(s); err == nil {
+			t.Errorf("playlist %q was accepted", s)
+		}
+	}
+}
+
+func TestPlaylistInOrder(t *testing.T) {
+	var p Playlist
+	p.Set("matrix,snake,lightning")
+	player := newPlaylistPlayer(p, false, rand.New(rand.NewSource(1)), "snake")
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, player.Current().Mode)
+		player.Next()
+	}
+	want := []string{"snake", "lightning", "matrix", "snake"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("played %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPlaylistShuffle(t *testing.T) {
+	var p Playlist
+	p.Set("matrix:1m:8,snake:1m:1,lightning:1m:1")
+	player := newPlaylistPlayer(p, true, rand.New(rand.NewSource(1)), "random")
+
+	counts := map[string]int{}
+	last := player.Current().Mode
+	for i := 0; i < 1000; i++ {
+		mode := player.Next().Mode
+		if mode == last {
+			t.Fatalf("%s played twice in a row", mode)
+		}
+		last = mode
+		counts[mode]++
+	}
+	// matrix is never repeated, so it can play at most every other time,
+	// but its weight should get it close to that
+	if counts["matrix"] < 400 || counts["snake"] < 150 || counts["lightning"] < 150 {
+		t.Errorf("unexpected shuffle distribution: %v", counts)
+	}
+}
+
+func TestRunModeAdvancesWhenTimeIsUp(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 10)
+
+	clock := newManualClock()
+	s := &session{screen: screen, events: make(chan tcell.Event), clock: clock, fps: 100, speed: 1}
+	info, _ := lookupModeInfo("matrix")
+	env := defaultEnv()
+	env.Rand = rand.New(rand.NewSource(1))
+
+	done := make(chan bool)
+	go func() { done <- s.runMode(info, env, time.Minute) }()
+
+	select {
+	case <-done:
+		t.Fatalf("mode finished before its time was up")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Advance(time.Minute)
+	select {
+	case next := <-done:
+		if !next {
+			t.Errorf("runMode asked to exit instead of moving on")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("mode still running after its time was up")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPTY opens a new pseudo-terminal, returning its controlling side and
+// the terminal for the program run in it.
+func openPTY() (ptm, pts *os.File, err error) {
+	ptm, err = os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	fd := ptm.Fd()
+	var name [128]byte
+	for _, req := range []struct {
+		cmd uintptr
+		arg uintptr
+	}{
+		{unix.TIOCPTYGRANT, 0},
+		{unix.TIOCPTYUNLK, 0},
+		{unix.TIOCPTYGNAME, uintptr(unsafe.Pointer(&name[0]))},
+	} {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req.cmd, req.arg); errno != 0 {
+			ptm.Close()
+			return nil, nil, errno
+		}
+	}
+	pts, err = os.OpenFile(string(name[:bytes.IndexByte(name[:], 0)]), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	return ptm, pts, nil
+}
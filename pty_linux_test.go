@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestOpenPTY(t *testing.T) {
+	ptm, pts, err := openPTY()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ptm.Close()
+	setWinsize(ptm, 42, 7)
+
+	cmd := exec.Command("/bin/sh", "-c", "stty size; printf done")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = pts, pts, pts
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	pts.Close()
+	var got strings.Builder
+	buf := make([]byte, 256)
+	for !strings.Contains(got.String(), "done") {
+		n, err := ptm.Read(buf)
+		got.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	cmd.Wait()
+	if !strings.Contains(got.String(), "7 42") {
+		t.Errorf("shell in the pseudo-terminal printed %q, want its size 7 42", got.String())
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// recorder wraps a screen and writes everything shown on it to an asciicast
+// v2 file, the format asciinema plays back. The first frame and any frame
+// after a resize or Sync are written in full, the rest as the cells that
+// changed since the frame before.
+type recorder struct {
+	tcell.Screen
+	out   *bufio.Writer
+	clock Clock
+	start time.Time
+	last  *frame // What a player's terminal shows after the last event
+	err   error  // First write error, reported by Close
+}
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+
+	IdleTimeLimit float64 `json:"idle_time_limit,omitempty"` // Longest pause to keep on playback, in seconds
+}
+
+// newRecorder starts a recording of screen to w, timed by clock.
+func newRecorder(screen tcell.Screen, w io.Writer, clock Clock) (*recorder, error) {
+	r := &recorder{Screen: screen, out: bufio.NewWriter(w), clock: clock, start: clock.Now()}
+	width, height := screen.Size()
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error writing recording: %v", err)
+	}
+	r.write(string(header) + "\n")
+	return r, r.err
+}
+
+func (r *recorder) Show() {
+	r.Screen.Show()
+	r.record(false)
+}
+
+func (r *recorder) Sync() {
+	r.Screen.Sync()
+	r.record(true)
+}
+
+// Close flushes the recording and reports the first error writing it.
+func (r *recorder) Close() error {
+	if err := r.out.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.err != nil {
+		return fmt.Errorf("Error writing recording: %v", r.err)
+	}
+	return nil
+}
+
+// record writes the current frame as an output event, preceded by a resize
+// event if the screen size has changed.
+func (r *recorder) record(full bool) {
+	cur := captureFrame(r.Screen)
+	var b strings.Builder
+	switch {
+	case r.last == nil:
+		// Hide the cursor for the whole recording
+		b.WriteString("\x1b[?25l")
+		full = true
+	case cur.w != r.last.w || cur.h != r.last.h:
+		r.event("r", fmt.Sprintf("%dx%d", cur.w, cur.h))
+		full = true
+	}
+	if full {
+		b.WriteString("\x1b[0m\x1b[2J")
+	}
+
+	cx, cy := -1, -1 // Where the player's cursor is, if known
+	var style tcell.Style
+	styled := false
+	for y := 0; y < cur.h; y++ {
+		for x := 0; x < cur.w; x++ {
+			c := cur.at(x, y)
+			// A cell that was the right half of a wide rune was blanked
+			// when the rune was replaced, so it is written again
+			if !full && c.equal(r.last.at(x, y)) && (x == 0 || r.last.at(x-1, y).width != 2) {
+				if c.width == 2 {
+					x++
+				}
+				continue
+			}
+			if x != cx || y != cy {
+				fmt.Fprintf(&b, "\x1b[%d;%dH", y+1, x+1)
+			}
+			if !styled || c.style != style {
+				b.WriteString(sgr(c.style))
+				style, styled = c.style, true
+			}
+			ch := c.r
+			if ch == 0 || (c.width == 2 && x == cur.w-1) {
+				// A wide rune doesn't fit in the last column; tcell shows a
+				// space there too
+				ch, c.comb, c.width = ' ', nil, 1
+			}
+			b.WriteRune(ch)
+			b.WriteString(string(c.comb))
+			cx, cy = x+1, y
+			if c.width == 2 {
+				cx++
+				x++
+			}
+		}
+	}
+	r.last = cur
+	if b.Len() > 0 {
+		r.event("o", b.String())
+	}
+}
+
+// event writes one event line: the time since the start, its type and data.
+func (r *recorder) event(kind, data string) {
+	t := math.Round(r.clock.Now().Sub(r.start).Seconds()*1e6) / 1e6
+	line, err := json.Marshal([]interface{}{t, kind, data})
+	if err != nil {
+		r.err = err
+		return
+	}
+	r.write(string(line) + "\n")
+}
+
+func (r *recorder) write(s string) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = r.out.WriteString(s)
+}
+
+// sgr returns the escape sequence that switches the terminal to style.
+func sgr(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+	codes := []string{"0"}
+	for _, a := range []struct {
+		attr tcell.AttrMask
+		code string
+	}{
+		{tcell.AttrBold, "1"},
+		{tcell.AttrDim, "2"},
+		{tcell.AttrItalic, "3"},
+		{tcell.AttrUnderline, "4"},
+		{tcell.AttrBlink, "5"},
+		{tcell.AttrReverse, "7"},
+		{tcell.AttrStrikeThrough, "9"},
+	} {
+		if attrs&a.attr != 0 {
+			codes = append(codes, a.code)
+		}
+	}
+	codes = append(codes, sgrColor(fg, "38", "39"), sgrColor(bg, "48", "49"))
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// sgrColor returns the SGR parameters setting a color, with set being 38 for
+// the foreground or 48 for the background.
+func sgrColor(c tcell.Color, set, reset string) string {
+	switch {
+	case c == tcell.ColorDefault || !c.Valid():
+		return reset
+	case c.IsRGB():
+		red, green, blue := c.RGB()
+		return fmt.Sprintf("%s;2;%d;%d;%d", set, red, green, blue)
+	default:
+		return set + ";5;" + strconv.Itoa(int(c-tcell.ColorValid))
+	}
+}
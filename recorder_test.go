@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// recordedEvent is an event as readCast sees it, kept apart from replay's
+// castEvent so the recorder is checked without going through replay.
+type recordedEvent struct {
+	t    float64
+	kind string
+	data string
+}
+
+// readCast parses an asciicast v2 recording.
+func readCast(t *testing.T, data []byte) (castHeader, []recordedEvent) {
+	t.Helper()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	var header castHeader
+	if !scanner.Scan() {
+		t.Fatal("recording is empty")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("bad header %q: %v", scanner.Text(), err)
+	}
+	var events []recordedEvent
+	for scanner.Scan() {
+		var fields []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil || len(fields) != 3 {
+			t.Fatalf("bad event %q: %v", scanner.Text(), err)
+		}
+		tm, ok1 := fields[0].(float64)
+		kind, ok2 := fields[1].(string)
+		data, ok3 := fields[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			t.Fatalf("bad event %q", scanner.Text())
+		}
+		if n := len(events); n > 0 && tm < events[n-1].t {
+			t.Errorf("event at %g goes back in time from %g", tm, events[n-1].t)
+		}
+		events = append(events, recordedEvent{tm, kind, data})
+	}
+	return header, events
+}
+
+// playCast plays a recording into a grid of runes the way a terminal would,
+// understanding only the escape sequences the recorder writes, and returns
+// the final screen as lines of text.
+func playCast(t *testing.T, header castHeader, events []recordedEvent) []string {
+	t.Helper()
+	w, h := header.Width, header.Height
+	grid := make([][]rune, h)
+	clear := func() {
+		grid = make([][]rune, h)
+		for y := range grid {
+			grid[y] = []rune(strings.Repeat(" ", w))
+		}
+	}
+	clear()
+	// Wide runes are followed by a 0 standing for their right half
+	unpair := func(row []rune, x int) {
+		if row[x] == 0 && x > 0 {
+			row[x-1] = ' '
+		}
+		if runewidth.RuneWidth(row[x]) == 2 && x+1 < len(row) {
+			row[x+1] = ' '
+		}
+	}
+	x, y := 0, 0
+	for _, ev := range events {
+		if ev.kind == "r" {
+			parts := strings.Split(ev.data, "x")
+			w, _ = strconv.Atoi(parts[0])
+			h, _ = strconv.Atoi(parts[1])
+			clear()
+			continue
+		}
+		runes := []rune(ev.data)
+		for i := 0; i < len(runes); i++ {
+			if runes[i] != 0x1b {
+				wide := runewidth.RuneWidth(runes[i]) == 2
+				if wide && x == w-1 {
+					// A terminal would wrap it onto the next line
+					t.Fatalf("wide rune %q written in the last column", runes[i])
+				}
+				if y < h && x < w {
+					// Writing over either half of a wide rune blanks it
+					unpair(grid[y], x)
+					if wide && x+1 < w {
+						unpair(grid[y], x+1)
+					}
+					grid[y][x] = runes[i]
+					if wide && x+1 < w {
+						grid[y][x+1] = 0
+					}
+				}
+				x += runewidth.RuneWidth(runes[i])
+				continue
+			}
+			// CSI: parameters up to the final letter
+			j := i + 2
+			for j < len(runes) && !(runes[j] >= 'A' && runes[j] <= 'z') {
+				j++
+			}
+			params := string(runes[i+2 : j])
+			switch runes[j] {
+			case 'H':
+				parts := strings.Split(params, ";")
+				row, _ := strconv.Atoi(parts[0])
+				col, _ := strconv.Atoi(parts[1])
+				x, y = col-1, row-1
+			case 'J':
+				clear()
+			case 'm', 'l':
+			default:
+				t.Fatalf("unexpected escape sequence %q", string(runes[i:j+1]))
+			}
+			i = j
+		}
+	}
+	lines := make([]string, h)
+	for y := range grid {
+		lines[y] = strings.ReplaceAll(string(grid[y]), "\x00", "")
+	}
+	return lines
+}
+
+// screenText returns what a terminal shows for the screen's cells.
+func screenText(screen tcell.Screen) []string {
+	f := captureFrame(screen)
+	lines := make([]string, f.h)
+	for y := 0; y < f.h; y++ {
+		var b strings.Builder
+		for x := 0; x < f.w; x++ {
+			c := f.at(x, y)
+			if c.r == 0 || (c.width == 2 && x == f.w-1) {
+				// tcell shows a space for a wide rune that doesn't fit
+				c.r = ' '
+			}
+			b.WriteRune(c.r)
+			if c.width == 2 {
+				x++
+			}
+		}
+		lines[y] = b.String()
+	}
+	return lines
+}
+
+func TestRecorderEvents(t *testing.T) {
+	h := newHarness(t, "snake", defaultEnv(), 6, 2, 1)
+	var out bytes.Buffer
+	rec, err := newRecorder(h.screen, &out, h.clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.screen.SetContent(0, 0, 'h', nil, tcell.StyleDefault)
+	h.screen.SetContent(1, 0, 'i', nil, tcell.StyleDefault)
+	rec.Show()
+	h.clock.Advance(100 * time.Millisecond)
+	h.screen.SetContent(1, 0, 'o', nil, tcell.StyleDefault.Bold(true).Foreground(tcell.NewHexColor(0xff8000)))
+	rec.Show()
+	rec.Show() // Nothing changed, nothing written
+	h.clock.Advance(150 * time.Millisecond)
+	h.screen.SetSize(4, 3)
+	rec.Show()
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header, events := readCast(t, out.Bytes())
+	if header.Version != 2 || header.Width != 6 || header.Height != 2 {
+		t.Errorf("header = %+v, want version 2 and 6x2", header)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4: %+v", len(events), events)
+	}
+	if e := events[0]; e.t != 0 || e.kind != "o" || !strings.Contains(e.data, "\x1b[2J") || !strings.Contains(e.data, "hi") {
+		t.Errorf("first event = %+v, want the whole screen at 0", e)
+	}
+	want := recordedEvent{0.1, "o", "\x1b[1;2H\x1b[0;1;38;2;255;128;0;49mo"}
+	if events[1] != want {
+		t.Errorf("second event = %+v, want only the changed cell %+v", events[1], want)
+	}
+	if e := events[2]; e != (recordedEvent{0.25, "r", "4x3"}) {
+		t.Errorf("third event = %+v, want a resize to 4x3 at 0.25", e)
+	}
+	if e := events[3]; e.kind != "o" || !strings.Contains(e.data, "\x1b[2J") {
+		t.Errorf("last event = %+v, want a full redraw", e)
+	}
+}
+
+func TestRecorderWideRunes(t *testing.T) {
+	h := newHarness(t, "snake", defaultEnv(), 4, 1, 1)
+	var out bytes.Buffer
+	rec, _ := newRecorder(h.screen, &out, h.clock)
+
+	h.screen.SetContent(0, 0, 'ア', nil, tcell.StyleDefault)
+	h.screen.SetContent(2, 0, 'b', nil, tcell.StyleDefault)
+	rec.Show()
+	h.screen.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	rec.Show()
+	rec.Close()
+
+	header, events := readCast(t, out.Bytes())
+	got := playCast(t, header, events)
+	if want := screenText(h.screen); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("replayed %q, want %q", got, want)
+	}
+}
+
+func TestRecorderWideRuneInLastColumn(t *testing.T) {
+	h := newHarness(t, "snake", defaultEnv(), 4, 1, 1)
+	var out bytes.Buffer
+	rec, _ := newRecorder(h.screen, &out, h.clock)
+
+	h.screen.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	h.screen.SetContent(3, 0, 'ア', nil, tcell.StyleDefault)
+	rec.Show()
+	rec.Close()
+
+	header, events := readCast(t, out.Bytes())
+	got := playCast(t, header, events)
+	if want := []string{"a   "}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("replayed %q, want %q", got, want)
+	}
+}
+
+// recordMode records the named mode, with a resize along the way, and
+// returns the harness it ran in and the recording.
+func recordMode(t *testing.T, name string) (*harness, []byte) {
+	t.Helper()
+	h := newHarness(t, name, defaultEnv(), 40, 12, 1)
+	var out bytes.Buffer
+	rec, err := newRecorder(h.screen, &out, h.clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.session.screen = rec
+
+	h.Run(time.Second)
+	h.Resize(30, 10)
+	rec.Sync()
+	h.Run(time.Second)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return h, out.Bytes()
+}
+
+// TestRecordModes records every mode and checks that playing the recording
+// back ends on the same screen.
+func TestRecordModes(t *testing.T) {
+	for _, info := range registry {
+		t.Run(info.name, func(t *testing.T) {
+			h, data := recordMode(t, info.name)
+			header, events := readCast(t, data)
+			if len(events) < 3 {
+				t.Errorf("only %d events recorded", len(events))
+			}
+			got := playCast(t, header, events)
+			if want := screenText(h.screen); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("replay ends on\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
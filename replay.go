@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/chorankates/termsaver/drawing"
+	"github.com/gdamore/tcell/v2"
+)
+
+func init() {
+	registerMode(modeInfo{
+		name:        "replay",
+		description: "plays back a recording made with -record (or any asciicast v2 file)",
+		step:        20 * time.Millisecond,
+		new:         func() Mode { return &Replay{} },
+		hidden:      true,
+	})
+}
+
+// ReplayConfig is the replay section of the config file.
+type ReplayConfig struct {
+	File string `json:"file"` // asciicast v2 recording to play
+	Fit  string `json:"fit"`  // "crop" to center it at its own size, "scale" to stretch it to the terminal
+	Loop bool   `json:"loop"` // Start over at the end instead of holding the last frame
+}
+
+const (
+	replaySeek     = 5 * time.Second  // How far the arrow keys seek
+	replayKeyframe = 5 * time.Second  // Recording time between saved states for seeking back
+	replayHold     = 2 * time.Second  // Pause on the last frame before looping
+	replayOSD      = 2 * time.Second  // How long the position stays on screen after a key
+	replayMaxIdle  = 10 * time.Second // Longest pause kept when the file doesn't set a limit
+)
+
+// castEvent is one event of an asciicast recording.
+type castEvent struct {
+	t    time.Duration // Since the start of the recording
+	kind string        // "o" for output, "r" for a resize to "WxH"
+	data string
+}
+
+// cast is a loaded asciicast v2 recording.
+type cast struct {
+	header castHeader
+	events []castEvent
+}
+
+// duration returns the time of the last event.
+func (c *cast) duration() time.Duration {
+	if len(c.events) == 0 {
+		return 0
+	}
+	return c.events[len(c.events)-1].t
+}
+
+// loadCast reads an asciicast v2 recording. Pauses longer than the file's
+// idle_time_limit, or replayMaxIdle without one, are shortened to it.
+func loadCast(r io.Reader) (*cast, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty recording")
+	}
+	c := &cast{}
+	if err := json.Unmarshal(scanner.Bytes(), &c.header); err != nil {
+		return nil, fmt.Errorf("bad header: %v", err)
+	}
+	if c.header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d, want 2", c.header.Version)
+	}
+	if c.header.Width < 1 || c.header.Height < 1 || c.header.Width > maxSize || c.header.Height > maxSize {
+		return nil, fmt.Errorf("bad size %dx%d", c.header.Width, c.header.Height)
+	}
+	maxIdle := replayMaxIdle
+	if c.header.IdleTimeLimit > 0 {
+		maxIdle = time.Duration(c.header.IdleTimeLimit * float64(time.Second))
+	}
+
+	var last, shift time.Duration
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var fields []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil || len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: want [time, type, data]", line)
+		}
+		secs, ok1 := fields[0].(float64)
+		kind, ok2 := fields[1].(string)
+		data, ok3 := fields[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			return nil, fmt.Errorf("line %d: want [time, type, data]", line)
+		}
+		t := time.Duration(secs * float64(time.Second))
+		if t < last {
+			return nil, fmt.Errorf("line %d: event at %gs is before the one before it", line, secs)
+		}
+		if gap := t - last; gap > maxIdle {
+			shift += gap - maxIdle
+		}
+		last = t
+		if kind == "o" || kind == "r" {
+			c.events = append(c.events, castEvent{t: t - shift, kind: kind, data: data})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadCastFile reads the recording at path.
+func loadCastFile(path string) (*cast, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := loadCast(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// replayState is the state of the terminal at some point in a recording,
+// kept so seeking back doesn't have to start over.
+type replayState struct {
+	next int // Index of the first event not yet applied
+	term *vterm
+}
+
+// Replay plays back a recording by feeding its output through a terminal
+// emulator and drawing the emulated screen.
+type Replay struct {
+	env  *Env
+	w, h int
+	cfg  ReplayConfig
+
+	cast      *cast
+	err       error // Why there is nothing to play
+	term      *vterm
+	next      int           // Index of the first event not yet applied
+	pos       time.Duration // Position in the recording
+	keyframes []replayState // keyframes[i] is the state at i*replayKeyframe
+	paused    bool
+	held      time.Duration // Time spent on the last frame
+	osd       time.Duration // How much longer to show the position
+}
+
+func (r *Replay) Init(env *Env, w, h int) {
+	r.env = env
+	r.w, r.h = w, h
+	r.cfg = env.Config.Replay
+	r.load()
+}
+
+// load reads the configured recording and rewinds to its start.
+func (r *Replay) load() {
+	r.cast, r.err = nil, nil
+	if r.cfg.File == "" {
+		r.err = fmt.Errorf("no recording to play: set -replay-file")
+		return
+	}
+	r.cast, r.err = loadCastFile(r.cfg.File)
+	if r.err != nil {
+		return
+	}
+	r.term = newVterm(r.cast.header.Width, r.cast.header.Height)
+	r.keyframes = []replayState{{term: r.term.clone()}}
+	r.next, r.pos, r.held = 0, 0, 0
+	r.advance(0)
+}
+
+// Reconfigure picks up a changed fit or loop setting, and loads the
+// recording again if the file has changed.
+func (r *Replay) Reconfigure(env *Env) {
+	cfg := env.Config.Replay
+	file := r.cfg.File
+	r.cfg = cfg
+	if cfg.File != file {
+		r.load()
+	}
+}
+
+func (r *Replay) Resize(w, h int) {
+	r.w, r.h = w, h
+}
+
+func (r *Replay) HandleEvent(ev tcell.Event) bool {
+	key, ok := ev.(*tcell.EventKey)
+	if !ok || r.cast == nil {
+		return false
+	}
+	switch {
+	case key.Key() == tcell.KeyRune && (key.Rune() == 'p' || key.Rune() == 'P'):
+		r.paused = !r.paused
+	case key.Key() == tcell.KeyLeft:
+		r.seek(r.pos - replaySeek)
+	case key.Key() == tcell.KeyRight:
+		r.seek(r.pos + replaySeek)
+	case key.Key() == tcell.KeyHome:
+		r.seek(0)
+	case key.Key() == tcell.KeyEnd:
+		r.seek(r.cast.duration())
+	default:
+		return false
+	}
+	r.osd = replayOSD
+	return true
+}
+
+func (r *Replay) Update(dt time.Duration) {
+	if r.osd > 0 {
+		r.osd -= dt
+	}
+	if r.cast == nil || r.paused {
+		return
+	}
+	if end := r.cast.duration(); r.pos >= end {
+		r.held += dt
+		if r.cfg.Loop && r.held >= replayHold {
+			r.seek(0)
+		}
+		return
+	}
+	r.advance(r.pos + dt)
+}
+
+// seek moves to position t in the recording, going back to the nearest
+// keyframe first if t is behind the current position.
+func (r *Replay) seek(t time.Duration) {
+	t = max(0, min(t, r.cast.duration()))
+	r.held = 0
+	if t < r.pos {
+		i := min(int(t/replayKeyframe), len(r.keyframes)-1)
+		r.term, r.next = r.keyframes[i].term.clone(), r.keyframes[i].next
+		r.pos = time.Duration(i) * replayKeyframe
+	}
+	r.advance(t)
+}
+
+// advance applies the events up to t, saving keyframes on the way.
+func (r *Replay) advance(t time.Duration) {
+	events := r.cast.events
+	for r.next < len(events) && events[r.next].t <= t {
+		// Save the state at each keyframe boundary crossed before this event
+		for k := len(r.keyframes); time.Duration(k)*replayKeyframe <= events[r.next].t; k++ {
+			r.keyframes = append(r.keyframes, replayState{next: r.next, term: r.term.clone()})
+		}
+		ev := events[r.next]
+		if ev.kind == "r" {
+			// A size too big to emulate is left out, like a garbled one
+			if w, h, err := parseSize(ev.data); err == nil {
+				r.term.Resize(w, h)
+			}
+		} else {
+			r.term.Write(ev.data)
+		}
+		r.next++
+	}
+	r.pos = t
+}
+
+func (r *Replay) Draw(screen tcell.Screen) {
+	theme := r.env.Theme
+	if r.err != nil {
+		drawing.Banner(screen, []string{"replay", r.err.Error()}, theme.Style(RoleText))
+		return
+	}
+
+	// Map each screen cell to a cell of the recording, either one to one
+	// around the center or stretched to fit
+	tw, th := r.term.w, r.term.h
+	offX, offY := (tw-r.w)/2, (th-r.h)/2
+	for y := 0; y < r.h; y++ {
+		for x := 0; x < r.w; x++ {
+			sx, sy := x+offX, y+offY
+			if r.cfg.Fit == "scale" {
+				sx, sy = x*tw/r.w, y*th/r.h
+			}
+			if sx < 0 || sy < 0 || sx >= tw || sy >= th {
+				continue
+			}
+			c := r.term.at(sx, sy)
+			if c.width == 0 || (c.width == 2 && x == r.w-1) {
+				continue
+			}
+			screen.SetContent(x, y, c.r, c.comb, r.themed(c.style))
+			if c.width == 2 {
+				x++
+			}
+		}
+	}
+
+	if r.paused || r.osd > 0 {
+		label := fmt.Sprintf(" %s / %s ", formatSeconds(r.pos), formatSeconds(r.cast.duration()))
+		if r.paused {
+			label = " paused" + label
+		}
+		style := tcell.StyleDefault.Foreground(theme.Color(RoleBackground)).Background(theme.Color(RoleText))
+		drawing.Text(screen, 0, r.h-1, label, style)
+	}
+}
+
+// themed replaces the terminal's default colors in a recorded style with the
+// theme's.
+func (r *Replay) themed(style tcell.Style) tcell.Style {
+	fg, bg, _ := style.Decompose()
+	if fg == tcell.ColorDefault {
+		style = style.Foreground(r.env.Theme.Color(RoleText))
+	}
+	if bg == tcell.ColorDefault {
+		style = style.Background(r.env.Theme.Color(RoleBackground))
+	}
+	return style
+}
+
+// formatSeconds formats d as m:ss.
+func formatSeconds(d time.Duration) string {
+	s := int(d / time.Second)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
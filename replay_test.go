@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func replayEnv(fit string) *Env {
+	env := defaultEnv()
+	env.Config.Replay.File = "testdata/replay.cast"
+	env.Config.Replay.Fit = fit
+	return env
+}
+
+// vtermText returns the emulated screen as lines of text.
+func vtermText(t *vterm) string {
+	lines := make([]string, t.h)
+	for y := 0; y < t.h; y++ {
+		var b strings.Builder
+		for x := 0; x < t.w; x++ {
+			if c := t.at(x, y); c.width > 0 {
+				b.WriteRune(c.r)
+			}
+		}
+		lines[y] = b.String()
+	}
+	return strings.Join(lines, "|")
+}
+
+func TestVterm(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"wrap", []string{"abcdefg"}, "abcde|fg   |     "},
+		{"pending wrap", []string{"abcde\r\nf"}, "abcde|f    |     "},
+		{"scroll", []string{"1\r\n2\r\n3\r\n4"}, "2    |3    |4    "},
+		{"cursor", []string{"\x1b[2;3Hx\x1b[Ay\x1b[3Gz\x1b[Bw"}, "  zy |  xw |     "},
+		{"erase line", []string{"abcde\x1b[1;3H\x1b[K"}, "ab   |     |     "},
+		{"erase display", []string{"abcde\r\nfghij\x1b[1;2H\x1b[J"}, "a    |     |     "},
+		{"delete and insert", []string{"abcde\x1b[1;2H\x1b[2P\r\x1b[1@"}, " ade |     |     "},
+		{"wide runes", []string{"aワb\x1b[1;3Hc"}, "a cb |     |     "},
+		{"wide rune at the edge", []string{"abcdワ"}, "abcd |ワ   |     "},
+		{"split sequence", []string{"\x1b[", "2;", "2Hx\x1b]0;ti", "tle\x07y"}, "     | xy  |     "},
+		{"save and restore", []string{"\x1b7\x1b[3;3Hx\x1b8y"}, "y    |     |  x  "},
+		{"reverse index", []string{"a\x1bMb"}, " b   |a    |     "},
+		{"scrolling region", []string{"a\r\nb\r\nc\x1b[2;3r\x1b[3;1H\nd"}, "a    |c    |d    "},
+		{"alternate screen", []string{"main\x1b[?1049h\x1b[2;2Halt\x1b[?1049lx"}, "mainx|     |     "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := newVterm(5, 3)
+			for _, w := range tt.writes {
+				term.Write(w)
+			}
+			if got := vtermText(term); got != tt.want {
+				t.Errorf("screen is %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVtermRepaint checks that repainting a terminal onto a blank one
+// reproduces it.
+func TestVtermRepaint(t *testing.T) {
+	term := newVterm(8, 4)
+	term.Write("\x1b[1;31mred\x1b[0m ワ\r\n\x1b[7mrev\x1b[?2004h\x1b[?1049h\x1b[3;4r\x1b[4;2H\x1b[32mgreen")
+	blank := newVterm(8, 4)
+	blank.Write(term.repaint())
+	if !sameTerm(blank, term) {
+		t.Errorf("repainted screen is %q, want %q", vtermText(blank), vtermText(term))
+	}
+	if blank.x != term.x || blank.y != term.y || blank.style != term.style {
+		t.Errorf("cursor at %d,%d, want %d,%d", blank.x, blank.y, term.x, term.y)
+	}
+	if blank.top != term.top || blank.bottom != term.bottom || !blank.modes[2004] {
+		t.Errorf("scrolling region or modes not repainted")
+	}
+}
+
+func TestLoadCast(t *testing.T) {
+	c, err := loadCast(strings.NewReader(`{"version": 2, "width": 10, "height": 4, "idle_time_limit": 2}
+[0.5, "o", "a"]
+[1.0, "i", "typed"]
+[11.0, "r", "20x5"]
+[11.5, "m", "marker"]
+[12.0, "o", "b"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Input and marker events are dropped, and the 10s pause after the
+	// input is cut to 2s
+	want := []castEvent{
+		{500 * time.Millisecond, "o", "a"},
+		{3 * time.Second, "r", "20x5"},
+		{4 * time.Second, "o", "b"},
+	}
+	if len(c.events) != len(want) {
+		t.Fatalf("events = %+v, want %+v", c.events, want)
+	}
+	for i := range want {
+		if c.events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, c.events[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{
+		"",
+		`{"version": 1, "width": 10, "height": 4}`,
+		`{"version": 2, "width": 0, "height": 4}`,
+		`{"version": 2, "width": 100000, "height": 100000}`,
+		"{\"version\": 2, \"width\": 10, \"height\": 4}\n[1, \"o\"]",
+		"{\"version\": 2, \"width\": 10, \"height\": 4}\n[2, \"o\", \"a\"]\n[1, \"o\", \"b\"]",
+	} {
+		if _, err := loadCast(strings.NewReader(bad)); err == nil {
+			t.Errorf("loading %q: want an error", bad)
+		}
+	}
+}
+
+// frameText returns what a terminal shows for the frame's cells, with the
+// style of each cell that differs from the one before it in brackets.
+func frameText(f *frame) string {
+	lines := make([]string, f.h)
+	for y := 0; y < f.h; y++ {
+		var b strings.Builder
+		style := tcell.StyleDefault
+		for x := 0; x < f.w; x++ {
+			c := f.at(x, y)
+			if c.r == 0 || (c.width == 2 && x == f.w-1) {
+				c.r = ' '
+			}
+			if c.style != style {
+				fmt.Fprintf(&b, "[%s]", describeStyle(c.style))
+				style = c.style
+			}
+			b.WriteRune(c.r)
+			if c.width == 2 {
+				x++
+			}
+		}
+		lines[y] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestReplayRecordings plays recordings of every mode through loadCast and
+// the terminal emulator, and checks they end on the screen the recorder
+// tests' own player shows, and in the same styles as the screen recorded.
+func TestReplayRecordings(t *testing.T) {
+	for _, info := range registry {
+		t.Run(info.name, func(t *testing.T) {
+			h, data := recordMode(t, info.name)
+			c, err := loadCast(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			term := newVterm(c.header.Width, c.header.Height)
+			for _, ev := range c.events {
+				if ev.kind == "r" {
+					var w, h int
+					fmt.Sscanf(ev.data, "%dx%d", &w, &h)
+					term.Resize(w, h)
+				} else {
+					term.Write(ev.data)
+				}
+			}
+
+			header, events := readCast(t, data)
+			if got, want := vtermText(term), strings.Join(playCast(t, header, events), "|"); got != want {
+				t.Errorf("replay ends on %q, want %q", got, want)
+			}
+			got := frameText(&frame{w: term.w, h: term.h, cells: term.cells})
+			if want := frameText(captureFrame(h.screen)); got != want {
+				t.Errorf("replay ends on\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestReplaySkipsBadResizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resize.cast")
+	if err := os.WriteFile(path, []byte(`{"version": 2, "width": 10, "height": 4}
+[0.1, "r", "100000x100000"]
+[0.2, "r", "20x"]
+[0.3, "r", "-5x3"]
+[0.4, "o", "ok"]
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := replayEnv("crop")
+	env.Config.Replay.File = path
+	r := &Replay{}
+	r.Init(env, 30, 10)
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	r.play(time.Second)
+	if r.term.w != 10 || r.term.h != 4 {
+		t.Errorf("terminal is %dx%d after bad resizes, want 10x4", r.term.w, r.term.h)
+	}
+	if got := vtermText(r.term); !strings.HasPrefix(got, "ok") {
+		t.Errorf("screen is %q, want the output after the resizes", got)
+	}
+}
+
+func TestReplayGolden(t *testing.T) {
+	for _, fit := range []string{"crop", "scale"} {
+		t.Run(fit, func(t *testing.T) {
+			h := newHarness(t, "replay", replayEnv(fit), 30, 10, 1)
+			h.Run(13 * time.Second)
+			checkGolden(t, "replay-"+fit, h.Frame())
+		})
+	}
+}
+
+// newReplay starts replay mode on the test recording.
+func newReplay(t *testing.T, loop bool) *Replay {
+	t.Helper()
+	env := replayEnv("crop")
+	env.Config.Replay.Loop = loop
+	r := &Replay{}
+	r.Init(env, 30, 10)
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	return r
+}
+
+// play runs the replay for d in 20ms steps.
+func (r *Replay) play(d time.Duration) {
+	for ; d > 0; d -= 20 * time.Millisecond {
+		r.Update(20 * time.Millisecond)
+	}
+}
+
+func sameTerm(a, b *vterm) bool {
+	if a.w != b.w || a.h != b.h {
+		return false
+	}
+	for i := range a.cells {
+		if !a.cells[i].equal(b.cells[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplaySeek(t *testing.T) {
+	want := newReplay(t, true)
+	want.play(8 * time.Second)
+
+	// Seeking back past a keyframe ends up where playing straight through does
+	r := newReplay(t, true)
+	r.play(12 * time.Second)
+	r.seek(8 * time.Second)
+	if r.pos != want.pos || !sameTerm(r.term, want.term) {
+		t.Errorf("after seeking back to %v the screen differs from playing to %v", r.pos, want.pos)
+	}
+
+	key := func(k tcell.Key, ch rune) {
+		t.Helper()
+		if !r.HandleEvent(tcell.NewEventKey(k, ch, tcell.ModNone)) {
+			t.Fatalf("key %v %q not handled", k, ch)
+		}
+	}
+	key(tcell.KeyHome, 0)
+	if fresh := newReplay(t, true); r.pos != 0 || !sameTerm(r.term, fresh.term) {
+		t.Errorf("Home went to %v, want the start", r.pos)
+	}
+	key(tcell.KeyRight, 0)
+	key(tcell.KeyRight, 0)
+	key(tcell.KeyLeft, 0)
+	if r.pos != replaySeek {
+		t.Errorf("position after seeking = %v, want %v", r.pos, replaySeek)
+	}
+
+	// Paused, time doesn't move
+	key(tcell.KeyRune, 'p')
+	r.play(time.Second)
+	if r.pos != replaySeek {
+		t.Errorf("paused replay moved to %v", r.pos)
+	}
+	key(tcell.KeyRune, 'p')
+	r.play(time.Second)
+	if r.pos != replaySeek+time.Second {
+		t.Errorf("resumed replay at %v, want %v", r.pos, replaySeek+time.Second)
+	}
+
+}
+
+// TestReplaySpeed checks that the global speed keys set the playback rate.
+func TestReplaySpeed(t *testing.T) {
+	h := newHarness(t, "replay", replayEnv("crop"), 30, 10, 1)
+	h.session.handleSpeedKey(tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModNone), h.sched)
+	h.Run(2 * time.Second)
+	r := h.mode.(*Replay)
+	if want := time.Duration(float64(2*time.Second) * h.session.speed); r.pos < want-100*time.Millisecond || r.pos > want+100*time.Millisecond {
+		t.Errorf("at %gx for 2s the replay is at %v, want about %v", h.session.speed, r.pos, want)
+	}
+}
+
+func TestReplayLoop(t *testing.T) {
+	end := newReplay(t, true).cast.duration()
+
+	r := newReplay(t, false)
+	r.play(end + 2*replayHold)
+	if r.pos != end {
+		t.Errorf("without looping the replay is at %v, want it held at the end %v", r.pos, end)
+	}
+
+	r = newReplay(t, true)
+	r.play(end + replayHold + time.Second)
+	if r.pos <= 0 || r.pos > 2*time.Second {
+		t.Errorf("looping replay is at %v, want it back near the start", r.pos)
+	}
+}
+
+func TestReplayIsHidden(t *testing.T) {
+	for _, e := range defaultPlaylist() {
+		if e.Mode == "replay" {
+			t.Errorf("replay is in the default playlist")
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/chorankates/termsaver/drawing"
+	"github.com/gdamore/tcell/v2"
+)
+
+// statusTime is how long a status message such as the speed stays on screen.
+const statusTime = 2 * time.Second
+
+// session is the state shared by every mode shown in one run of termsaver.
+type session struct {
+	screen  tcell.Screen
+	events  <-chan tcell.Event // From the shared event pump
+	sigChan chan os.Signal
+	clock   Clock
+	fps     int
+	speed   float64 // Carries over between modes
+	env     *Env    // Settings of the running mode
+
+	// loadConfig re-reads the config file and flags. It's called on SIGHUP
+	// and whenever reload fires.
+	loadConfig func() (*Config, error)
+	reload     <-chan struct{}
+
+	// While from is set the outgoing mode's last frame is blended into
+	// the new mode with transition, over transitionTime
+	transition      transitionFunc
+	transitionTime  time.Duration
+	transitionStart time.Time
+	from            *frame
+
+	status      string    // Message shown in the bottom right corner
+	statusUntil time.Time // When the status message goes away
+	statusShown bool      // Whether the last frame drew the status message
+	dirty       bool      // Redraw even if no simulation steps are due
+
+	capture *snapshotCapture // Snapshot being taken, if any
+	lock    bool             // Running as a lock screen: any key or signal ends the session
+	prompt  *lockPrompt      // Password a lock screen asks for instead, if any
+	overlay *overlay         // Clock and other information drawn over every mode, if any
+}
+
+// runMode runs a single mode until the user exits, asks for the next mode or,
+// if d isn't zero, d has passed. It returns true if the runner should cycle
+// to the next mode.
+func (s *session) runMode(info modeInfo, env *Env, d time.Duration) bool {
+	screen := s.screen
+	w, h := screen.Size()
+	s.env = env
+	mode := info.new()
+	if s.prompt != nil {
+		// Only the password may end a lock screen, not a broken mode
+		mode = &failClosedMode{mode: mode}
+	}
+	mode.Init(env, w, h)
+
+	sched := newScheduler(s.clock, info.step, s.fps, s.speed)
+	ticker := time.NewTicker(sched.frame)
+	defer ticker.Stop()
+	end := s.clock.Now().Add(d)
+
+	for {
+		select {
+		case sig := <-s.sigChan:
+			if sig == syscall.SIGHUP && !s.lock {
+				s.reloadConfig(env, mode, sched, ticker)
+				continue
+			}
+			return false
+		case <-s.reload:
+			s.reloadConfig(env, mode, sched, ticker)
+		case event, ok := <-s.events:
+			if !ok {
+				return false
+			}
+			switch ev := event.(type) {
+			case *tcell.EventResize:
+				w, h = screen.Size()
+				mode.Resize(w, h)
+				screen.Sync()
+			case *tcell.EventError:
+				// The terminal has gone away
+				return false
+			case *tcell.EventKey:
+				// A lock screen ends on any key, or once the password
+				// has been typed
+				if s.lock {
+					if s.prompt == nil || s.prompt.key(ev) {
+						return false
+					}
+					s.dirty = true
+					continue
+				}
+				// Always handle exit keys, regardless of interactive mode
+				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
+					return false
+				}
+				if mode.HandleEvent(ev) {
+					continue
+				}
+				if s.handleSpeedKey(ev, sched) {
+					continue
+				}
+				if s.handleSnapshotKey(ev, info.name) {
+					continue
+				}
+				// Space cycles to next mode
+				if ev.Rune() == ' ' {
+					return true
+				}
+				// In non-interactive mode, any key exits
+				if !env.Interactive {
+					return false
+				}
+			default:
+				mode.HandleEvent(ev)
+			}
+		case <-ticker.C:
+			if d > 0 && !s.clock.Now().Before(end) {
+				return true
+			}
+			s.renderFrame(mode, sched)
+		}
+	}
+}
+
+// handleSpeedKey adjusts the simulation speed for +/- (and = as an unshifted
+// +), or resets it for 0. It reports whether the key was one of these.
+func (s *session) handleSpeedKey(ev *tcell.EventKey, sched *scheduler) bool {
+	if ev.Key() != tcell.KeyRune {
+		return false
+	}
+	switch ev.Rune() {
+	case '+', '=':
+		s.speed = fasterSpeed(s.speed)
+	case '-', '_':
+		s.speed = slowerSpeed(s.speed)
+	case '0':
+		s.speed = 1
+	default:
+		return false
+	}
+	sched.speed = s.speed
+	s.showStatus(fmt.Sprintf("speed %gx", s.speed))
+	return true
+}
+
+// reloadConfig loads the config again and applies it to the running mode.
+// The mode, playlist and seed only take effect on the next start; everything else is
+// picked up straight away. If the config can't be loaded the old one stays.
+func (s *session) reloadConfig(env *Env, mode Mode, sched *scheduler, ticker *time.Ticker) {
+	if s.loadConfig == nil {
+		return
+	}
+	cfg, err := s.loadConfig()
+	if err != nil {
+		s.showStatus(fmt.Sprintf("config not reloaded: %v", err))
+		return
+	}
+
+	env.Config = cfg
+	env.Theme, _ = newTheme(cfg.themeName(), s.screen.Colors())
+	s.overlay = newOverlay(cfg.Overlay)
+	s.speed = cfg.Global.Speed
+	sched.speed = s.speed
+	if cfg.Global.FPS != s.fps {
+		s.fps = cfg.Global.FPS
+		sched.frame = time.Second / time.Duration(s.fps)
+		ticker.Reset(sched.frame)
+	}
+	if r, ok := mode.(Reconfigurer); ok {
+		r.Reconfigure(env)
+	}
+	s.showStatus("config reloaded")
+}
+
+// showStatus shows msg briefly over the running mode.
+func (s *session) showStatus(msg string) {
+	s.status = msg
+	s.statusUntil = s.clock.Now().Add(statusTime)
+	s.dirty = true
+}
+
+// startTransition keeps what's on screen so the next mode can transition
+// from it using fn. A nil fn means the next mode just starts.
+func (s *session) startTransition(fn transitionFunc, d time.Duration) {
+	s.from = nil
+	if fn == nil || d <= 0 {
+		return
+	}
+	s.from = captureFrame(s.screen)
+	s.transition = fn
+	s.transitionTime = d
+	s.transitionStart = s.clock.Now()
+}
+
+// renderFrame runs the simulation steps that are due and, if any ran, draws
+// and shows the result. Frames where nothing changed are skipped, except
+// during a transition or while taking a snapshot.
+func (s *session) renderFrame(mode Mode, sched *scheduler) bool {
+	n := sched.Steps()
+	showStatus := s.clock.Now().Before(s.statusUntil)
+	showPrompt := s.prompt != nil && s.prompt.visible()
+	overlayChanged := false
+	if s.overlay != nil {
+		w, h := s.screen.Size()
+		overlayChanged = s.overlay.update(s.clock.Now(), w, h)
+	}
+	if n == 0 && !s.dirty && showStatus == s.statusShown && s.from == nil && s.capture == nil && !showPrompt && !overlayChanged {
+		return false
+	}
+	s.dirty = false
+	s.statusShown = showStatus
+
+	for i := 0; i < n; i++ {
+		mode.Update(sched.step)
+	}
+	s.screen.Fill(' ', s.env.Theme.Style(RoleBackground))
+	mode.Draw(s.screen)
+	if s.from != nil {
+		p := float64(s.clock.Now().Sub(s.transitionStart)) / float64(s.transitionTime)
+		if p >= 1 {
+			s.from = nil
+		} else {
+			s.transition(s.screen, s.from, captureFrame(s.screen), p)
+		}
+	}
+	if s.overlay != nil {
+		s.overlay.draw(s.screen, s.env.Theme)
+	}
+	if s.capture != nil {
+		// Before the status message, which isn't part of the picture
+		s.collect()
+	}
+	if showStatus {
+		drawStatus(s.screen, s.status)
+	}
+	if showPrompt {
+		s.prompt.draw(s.screen, s.env.Theme)
+	}
+	s.screen.Show()
+	return true
+}
+
+// drawStatus shows msg in the bottom right corner, cut to fit the screen.
+func drawStatus(screen tcell.Screen, msg string) {
+	w, h := screen.Size()
+	label := []rune(" " + msg + " ")
+	if len(label) > w {
+		label = label[:w]
+	}
+	style := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+	drawing.Text(screen, w-len(label), h-1, string(label), style)
+}
@@ -0,0 +1,84 @@
+package main
+
+import "time"
+
+// defaultFPS is how often frames are rendered unless configured otherwise.
+const defaultFPS = 30
+
+// maxFrameGap is the most clock time a single frame catches up on, so a
+// stalled terminal doesn't come back to a burst of fast-forwarded animation.
+const maxFrameGap = 250 * time.Millisecond
+
+// speeds are the simulation speed multipliers the +/- keys step through.
+var speeds = []float64{0.1, 0.25, 0.5, 0.75, 1, 1.5, 2, 3, 5, 10}
+
+// scheduler runs a mode's simulation in fixed timesteps driven by a Clock.
+// Frames are rendered at their own rate and each one runs however many steps
+// are due, so animation speed doesn't depend on how often frames are drawn.
+type scheduler struct {
+	clock Clock
+	step  time.Duration // Simulation timestep
+	frame time.Duration // Time between rendered frames
+	speed float64       // Simulation time per unit of clock time
+
+	last time.Time
+	lag  time.Duration // Simulation time owed but not yet stepped
+}
+
+func newScheduler(clock Clock, step time.Duration, fps int, speed float64) *scheduler {
+	if fps < 1 {
+		fps = defaultFPS
+	}
+	return &scheduler{
+		clock: clock,
+		step:  step,
+		frame: time.Second / time.Duration(fps),
+		speed: clampSpeed(speed),
+		last:  clock.Now(),
+	}
+}
+
+// Steps returns how many simulation steps are due since the last call.
+func (s *scheduler) Steps() int {
+	now := s.clock.Now()
+	elapsed := now.Sub(s.last)
+	s.last = now
+	if elapsed > maxFrameGap {
+		elapsed = maxFrameGap
+	}
+
+	s.lag += time.Duration(float64(elapsed) * s.speed)
+	n := int(s.lag / s.step)
+	s.lag -= time.Duration(n) * s.step
+	return n
+}
+
+func clampSpeed(speed float64) float64 {
+	if speed < speeds[0] {
+		return speeds[0]
+	}
+	if max := speeds[len(speeds)-1]; speed > max {
+		return max
+	}
+	return speed
+}
+
+// fasterSpeed returns the next speed step above speed.
+func fasterSpeed(speed float64) float64 {
+	for _, s := range speeds {
+		if s > speed {
+			return s
+		}
+	}
+	return speeds[len(speeds)-1]
+}
+
+// slowerSpeed returns the next speed step below speed.
+func slowerSpeed(speed float64) float64 {
+	for i := len(speeds) - 1; i >= 0; i-- {
+		if speeds[i] < speed {
+			return speeds[i]
+		}
+	}
+	return speeds[0]
+}
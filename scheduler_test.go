@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSchedulerStepsFollowTheClock(t *testing.T) {
+	clock := newManualClock()
+	sched := newScheduler(clock, 100*time.Millisecond, 20, 1)
+
+	steps := 0
+	for i := 0; i < 20; i++ {
+		clock.Advance(sched.frame)
+		steps += sched.Steps()
+	}
+	// 20 frames at 20fps is one second of simulation
+	if steps != 10 {
+		t.Errorf("got %d steps in one second, want 10", steps)
+	}
+}
+
+func TestSchedulerCapsCatchUp(t *testing.T) {
+	clock := newManualClock()
+	sched := newScheduler(clock, 50*time.Millisecond, 30, 1)
+
+	clock.Advance(time.Minute)
+	if got, want := sched.Steps(), int(maxFrameGap/sched.step); got != want {
+		t.Errorf("got %d steps after a stall, want %d", got, want)
+	}
+	if got := sched.Steps(); got != 0 {
+		t.Errorf("got %d steps right after catching up, want 0", got)
+	}
+}
+
+func TestSchedulerSpeed(t *testing.T) {
+	for _, speed := range []float64{0.5, 1, 2, 10} {
+		clock := newManualClock()
+		sched := newScheduler(clock, 100*time.Millisecond, 20, speed)
+
+		steps := 0
+		for i := 0; i < 40; i++ {
+			clock.Advance(sched.frame)
+			steps += sched.Steps()
+		}
+		// Two seconds of clock time
+		if want := int(20 * speed); steps != want {
+			t.Errorf("speed %g: got %d steps in two seconds, want %d", speed, steps, want)
+		}
+	}
+}
+
+func TestSpeedSteps(t *testing.T) {
+	if got := fasterSpeed(1); got != 1.5 {
+		t.Errorf("fasterSpeed(1) = %g, want 1.5", got)
+	}
+	if got := slowerSpeed(1); got != 0.75 {
+		t.Errorf("slowerSpeed(1) = %g, want 0.75", got)
+	}
+	// Speeds between steps snap to the neighbouring step
+	if got := fasterSpeed(1.2); got != 1.5 {
+		t.Errorf("fasterSpeed(1.2) = %g, want 1.5", got)
+	}
+	if got := slowerSpeed(1.2); got != 1 {
+		t.Errorf("slowerSpeed(1.2) = %g, want 1", got)
+	}
+	if got := fasterSpeed(10); got != 10 {
+		t.Errorf("fasterSpeed(10) = %g, want 10", got)
+	}
+	if got := slowerSpeed(0.1); got != 0.1 {
+		t.Errorf("slowerSpeed(0.1) = %g, want 0.1", got)
+	}
+}
+
+func TestSpeedIndicatorComesAndGoes(t *testing.T) {
+	h := newHarness(t, "matrix", defaultEnv(), 40, 10, 1)
+	h.session.handleSpeedKey(tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModNone), h.sched)
+	if h.session.speed != 1.5 || h.sched.speed != 1.5 {
+		t.Fatalf("speed after + is %g (scheduler %g), want 1.5", h.session.speed, h.sched.speed)
+	}
+
+	h.Run(time.Second)
+	if !strings.Contains(h.Frame(), "speed 1.5x") {
+		t.Errorf("speed indicator not shown after changing speed")
+	}
+	h.Run(2 * time.Second)
+	if strings.Contains(h.Frame(), "speed 1.5x") {
+		t.Errorf("speed indicator still shown after %v", statusTime)
+	}
+}
+
+func TestAnimationSpeedIndependentOfFPS(t *testing.T) {
+	var frames []string
+	for _, fps := range []int{5, 30, 120} {
+		h := newHarness(t, "matrix", defaultEnv(), 40, 15, 3)
+		h.sched = newScheduler(h.clock, h.info.step, fps, 1)
+		h.Run(3 * time.Second)
+		frames = append(frames, h.Frame())
+	}
+	for i := 1; i < len(frames); i++ {
+		if frames[i] != frames[0] {
+			t.Errorf("frame after 3s differs between frame rates")
+		}
+	}
+}
@@ -3,12 +3,27 @@ package main
 import (
 	"fmt"
 	"math/rand"
-	"os"
 	"time"
 
+	"github.com/chorankates/termsaver/drawing"
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	registerMode(modeInfo{
+		name:        "snake",
+		description: "classic Nokia-style snake game (use arrow keys to play with -interactive)",
+		step:        150 * time.Millisecond,
+		new:         func() Mode { return &SnakeGame{} },
+	})
+}
+
+// SnakeConfig is the [snake] section of the config file.
+type SnakeConfig struct {
+	Size  int `json:"size"`  // Interactive grid size, 0 = auto based on terminal (max 50)
+	Scale int `json:"scale"` // Cell scale factor, 1-4
+}
+
 type Point struct {
 	X, Y int
 }
@@ -19,10 +34,34 @@ type Snake struct {
 	alive     bool
 }
 
-func runSnake(screen tcell.Screen, sigChan chan os.Signal, interactive bool, grayscale bool, requestedSize int, scale int) bool {
-	termW, termH := screen.Size()
+type SnakeGame struct {
+	env          *Env
+	rng          *rand.Rand
+	termW, termH int
+
+	// Cell size: each game cell is rendered as cellW x cellH terminal characters
+	cellW, cellH int
+
+	// Game grid size (in game cells, not terminal chars) and its placement
+	gameW, gameH     int
+	offsetX, offsetY int
+
+	snake    Snake
+	food     Point
+	score    int
+	gameOver time.Duration // Time since the snake died
+
+	cfg SnakeConfig // Settings the board was laid out with
+}
+
+func (g *SnakeGame) Init(env *Env, w, h int) {
+	g.env = env
+	g.rng = env.Rand
+	g.termW, g.termH = w, h
+	g.cfg = env.Config.Snake
 
 	// Clamp scale to reasonable values
+	scale := g.cfg.Scale
 	if scale < 1 {
 		scale = 1
 	}
@@ -30,39 +69,23 @@ func runSnake(screen tcell.Screen, sigChan chan os.Signal, interactive bool, gra
 		scale = 4
 	}
 
-	// Cell size: each game cell is rendered as cellW x cellH terminal characters
 	// Using scale for width and scale/2 (min 1) for height to maintain roughly square cells
-	cellW := scale
-	cellH := scale / 2
-	if cellH < 1 {
-		cellH = 1
+	g.cellW = scale
+	g.cellH = scale / 2
+	if g.cellH < 1 {
+		g.cellH = 1
 	}
 
-	// Calculate game grid size (in game cells, not terminal chars)
-	// Default to a reasonable size based on terminal
-	var gameW, gameH int
-	var offsetX, offsetY int
-
-	if !interactive {
+	if !env.Interactive {
 		// Non-interactive mode: use entire terminal window
-		// Leave 1 row at top for score display
-		gameW = termW / cellW
-		gameH = (termH - 1) / cellH
-		if gameW < 10 {
-			gameW = 10
-		}
-		if gameH < 10 {
-			gameH = 10
-		}
-		offsetX = 0
-		offsetY = 1 // Leave room for score at top
+		g.fitToTerminal()
 	} else {
 		// Interactive mode: centered square game area
-		gameSize := requestedSize
+		gameSize := g.cfg.Size
 		if gameSize <= 0 {
 			// Auto-size based on terminal, accounting for cell size
-			maxW := (termW * 8 / 10) / cellW
-			maxH := (termH * 8 / 10) / cellH
+			maxW := (w * 8 / 10) / g.cellW
+			maxH := (h * 8 / 10) / g.cellH
 			gameSize = maxW
 			if maxH < gameSize {
 				gameSize = maxH
@@ -75,35 +98,48 @@ func runSnake(screen tcell.Screen, sigChan chan os.Signal, interactive bool, gra
 			gameSize = 10
 		}
 
-		gameW = gameSize
-		gameH = gameSize
-
-		// Calculate offset to center the game area in terminal
-		pixelW := gameW * cellW
-		pixelH := gameH * cellH
-		offsetX = (termW - pixelW) / 2
-		offsetY = (termH - pixelH) / 2
+		g.gameW = gameSize
+		g.gameH = gameSize
+		g.center()
 	}
 
-	// Calculate pixel dimensions (used for score positioning and resize)
-	pixelW := gameW * cellW
-	pixelH := gameH * cellH
+	g.reset()
+}
 
-	// Helper function to draw a game cell as a block
-	drawCell := func(gx, gy int, ch rune, style tcell.Style) {
-		px := offsetX + gx*cellW
-		py := offsetY + gy*cellH
-		for dy := 0; dy < cellH; dy++ {
-			for dx := 0; dx < cellW; dx++ {
-				screen.SetContent(px+dx, py+dy, ch, nil, style)
-			}
-		}
+// Reconfigure starts a new game if the grid size or scale changed, since the
+// board can't be laid out again under a running snake.
+func (g *SnakeGame) Reconfigure(env *Env) {
+	if env.Config.Snake != g.cfg {
+		g.Init(env, g.termW, g.termH)
 	}
+}
 
-	// Initialize snake in center of game area
-	centerX := gameW / 2
-	centerY := gameH / 2
-	snake := Snake{
+// fitToTerminal sizes the game to fill the terminal, leaving 1 row at the top
+// for the score display.
+func (g *SnakeGame) fitToTerminal() {
+	g.gameW = g.termW / g.cellW
+	g.gameH = (g.termH - 1) / g.cellH
+	if g.gameW < 10 {
+		g.gameW = 10
+	}
+	if g.gameH < 10 {
+		g.gameH = 10
+	}
+	g.offsetX = 0
+	g.offsetY = 1 // Leave room for score at top
+}
+
+// center calculates the offset to center the game area in the terminal.
+func (g *SnakeGame) center() {
+	g.offsetX = (g.termW - g.gameW*g.cellW) / 2
+	g.offsetY = (g.termH - g.gameH*g.cellH) / 2
+}
+
+// reset starts a new game with the snake in the center of the game area.
+func (g *SnakeGame) reset() {
+	centerX := g.gameW / 2
+	centerY := g.gameH / 2
+	g.snake = Snake{
 		body: []Point{
 			{centerX, centerY},
 			{centerX - 1, centerY},
@@ -112,276 +148,187 @@ func runSnake(screen tcell.Screen, sigChan chan os.Signal, interactive bool, gra
 		direction: Point{1, 0},
 		alive:     true,
 	}
+	g.food = Point{1 + (g.gameW-2)/4, 1 + (g.gameH-2)/4}
+	g.score = 0
+	g.gameOver = 0
+}
+
+func (g *SnakeGame) Resize(w, h int) {
+	g.termW, g.termH = w, h
+	if !g.env.Interactive {
+		// Non-interactive: resize game to fill terminal
+		g.fitToTerminal()
+	} else {
+		// Interactive: recalculate offset to keep game centered
+		g.center()
+	}
+}
 
-	food := Point{1 + (gameW-2)/4, 1 + (gameH-2)/4}
-	score := 0
-	var gameOverTime time.Time
+func (g *SnakeGame) HandleEvent(ev tcell.Event) bool {
+	key, ok := ev.(*tcell.EventKey)
+	if !ok || !g.env.Interactive {
+		return false
+	}
+	// Space doesn't cycle during interactive play
+	if key.Rune() == ' ' {
+		return true
+	}
+	snake := &g.snake
+	switch key.Key() {
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		if snake.direction.Y == 0 {
+			snake.direction = Point{0, -1}
+		}
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		if snake.direction.Y == 0 {
+			snake.direction = Point{0, 1}
+		}
+	case tcell.KeyLeft, tcell.KeyCtrlB:
+		if snake.direction.X == 0 {
+			snake.direction = Point{-1, 0}
+		}
+	case tcell.KeyRight, tcell.KeyCtrlF:
+		if snake.direction.X == 0 {
+			snake.direction = Point{1, 0}
+		}
+	default:
+		return false
+	}
+	return true
+}
 
-	ticker := time.NewTicker(150 * time.Millisecond)
-	defer ticker.Stop()
+// countdown returns the seconds left before a finished game restarts.
+func (g *SnakeGame) countdown() int {
+	return 3 - int(g.gameOver.Seconds())
+}
 
-	// Event handling for input
-	eventChan := make(chan tcell.Event, 10)
-	go func() {
-		for {
-			eventChan <- screen.PollEvent()
+func (g *SnakeGame) Update(dt time.Duration) {
+	if !g.snake.alive {
+		g.gameOver += dt
+		if g.countdown() <= 0 {
+			// Countdown finished - restart the game at the current terminal size
+			g.Resize(g.termW, g.termH)
+			g.reset()
 		}
-	}()
-
-	for {
-		select {
-		case <-sigChan:
-			return false
-		case event := <-eventChan:
-			switch ev := event.(type) {
-			case *tcell.EventKey:
-				// Always handle exit keys, regardless of interactive mode
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
-					return false
-				}
-				// Space cycles to next mode (but not during interactive play)
-				if ev.Rune() == ' ' && !interactive {
-					return true
-				}
-				// In non-interactive mode, any other key exits
-				if !interactive {
-					return false
-				}
-				// Handle movement keys only in interactive mode
-				if interactive {
-					switch ev.Key() {
-					case tcell.KeyUp, tcell.KeyCtrlP:
-						if snake.direction.Y == 0 {
-							snake.direction = Point{0, -1}
-						}
-					case tcell.KeyDown, tcell.KeyCtrlN:
-						if snake.direction.Y == 0 {
-							snake.direction = Point{0, 1}
-						}
-					case tcell.KeyLeft, tcell.KeyCtrlB:
-						if snake.direction.X == 0 {
-							snake.direction = Point{-1, 0}
-						}
-					case tcell.KeyRight, tcell.KeyCtrlF:
-						if snake.direction.X == 0 {
-							snake.direction = Point{1, 0}
-						}
-					}
-				}
-			case *tcell.EventResize:
-				termW, termH = screen.Size()
-				if !interactive {
-					// Non-interactive: resize game to fill terminal
-					gameW = termW / cellW
-					gameH = (termH - 1) / cellH
-					if gameW < 10 {
-						gameW = 10
-					}
-					if gameH < 10 {
-						gameH = 10
-					}
-					pixelW = gameW * cellW
-					pixelH = gameH * cellH
-					offsetX = 0
-					offsetY = 1
-				} else {
-					// Interactive: recalculate offset to keep game centered
-					offsetX = (termW - pixelW) / 2
-					offsetY = (termH - pixelH) / 2
-				}
-				screen.Sync()
-			}
-		case <-ticker.C:
-			if !snake.alive {
-				// Track when game over started
-				if gameOverTime.IsZero() {
-					gameOverTime = time.Now()
-				}
-				
-				// Calculate countdown (3, 2, 1, 0)
-				elapsed := time.Since(gameOverTime)
-				countdown := 3 - int(elapsed.Seconds())
-				
-				screen.Clear()
-				
-				if countdown > 0 {
-					// Show countdown
-					msg1 := fmt.Sprintf("GAME OVER - Score: %d", score)
-					countdownMsg := fmt.Sprintf("Restarting in %d...", countdown)
-					
-					x1 := (termW - len(msg1)) / 2
-					if x1 < 0 {
-						x1 = 0
-					}
-					x2 := (termW - len(countdownMsg)) / 2
-					if x2 < 0 {
-						x2 = 0
-					}
-					
-					style1 := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorRed, grayscale)).Background(tcell.ColorBlack)
-					style2 := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorYellow, grayscale)).Background(tcell.ColorBlack)
-					
-					for i, char := range msg1 {
-						if x1+i >= 0 && x1+i < termW {
-							screen.SetContent(x1+i, termH/2-1, char, nil, style1)
-						}
-					}
-					for i, char := range countdownMsg {
-						if x2+i >= 0 && x2+i < termW {
-							screen.SetContent(x2+i, termH/2+1, char, nil, style2)
-						}
-					}
-				} else {
-					// Countdown finished - restart the game
-					termW, termH = screen.Size()
-					if !interactive {
-						// Non-interactive: resize game to fill terminal
-						gameW = termW / cellW
-						gameH = (termH - 1) / cellH
-						if gameW < 10 {
-							gameW = 10
-						}
-						if gameH < 10 {
-							gameH = 10
-						}
-						pixelW = gameW * cellW
-						pixelH = gameH * cellH
-						offsetX = 0
-						offsetY = 1
-					} else {
-						offsetX = (termW - pixelW) / 2
-						offsetY = (termH - pixelH) / 2
-					}
-					// Recalculate center based on current game size
-					centerX = gameW / 2
-					centerY = gameH / 2
-					snake = Snake{
-						body: []Point{
-							{centerX, centerY},
-							{centerX - 1, centerY},
-							{centerX - 2, centerY},
-						},
-						direction: Point{1, 0},
-						alive:     true,
-					}
-					food = Point{1 + (gameW-2)/4, 1 + (gameH-2)/4}
-					score = 0
-					gameOverTime = time.Time{}
-					continue
-				}
-				
-				screen.Show()
-				continue
-			}
+		return
+	}
 
-			// Automatic gameplay: calculate optimal direction
-			if !interactive {
-				snake.direction = findOptimalDirection(snake, food, gameW, gameH)
-			}
+	gameW, gameH := g.gameW, g.gameH
 
-			// Move snake
-			head := snake.body[0]
-			newHead := Point{
-				X: head.X + snake.direction.X,
-				Y: head.Y + snake.direction.Y,
-			}
+	// Automatic gameplay: calculate optimal direction
+	if !g.env.Interactive {
+		g.snake.direction = findOptimalDirection(g.snake, g.food, gameW, gameH)
+	}
 
-			// Check wall collision (account for border)
-			if newHead.X <= 0 || newHead.X >= gameW-1 || newHead.Y <= 0 || newHead.Y >= gameH-1 {
-				snake.alive = false
-				continue
-			}
+	// Move snake
+	head := g.snake.body[0]
+	newHead := Point{
+		X: head.X + g.snake.direction.X,
+		Y: head.Y + g.snake.direction.Y,
+	}
 
-			// Check self collision
-			for _, segment := range snake.body {
-				if newHead.X == segment.X && newHead.Y == segment.Y {
-					snake.alive = false
+	// Check wall collision (account for border)
+	if newHead.X <= 0 || newHead.X >= gameW-1 || newHead.Y <= 0 || newHead.Y >= gameH-1 {
+		g.snake.alive = false
+		return
+	}
+
+	// Check self collision
+	for _, segment := range g.snake.body {
+		if newHead.X == segment.X && newHead.Y == segment.Y {
+			g.snake.alive = false
+			return
+		}
+	}
+
+	g.snake.body = append([]Point{newHead}, g.snake.body...)
+
+	// Check food collision
+	if newHead.X == g.food.X && newHead.Y == g.food.Y {
+		g.score++
+		// Generate new food (avoid border area)
+		g.food = Point{1 + g.rng.Intn(gameW-2), 1 + g.rng.Intn(gameH-2)}
+		// Make sure food is not on snake
+		for {
+			onSnake := false
+			for _, segment := range g.snake.body {
+				if g.food.X == segment.X && g.food.Y == segment.Y {
+					onSnake = true
 					break
 				}
 			}
-
-			if !snake.alive {
-				continue
+			if !onSnake {
+				break
 			}
+			g.food = Point{1 + g.rng.Intn(gameW-2), 1 + g.rng.Intn(gameH-2)}
+		}
+	} else {
+		g.snake.body = g.snake.body[:len(g.snake.body)-1]
+	}
+}
 
-			snake.body = append([]Point{newHead}, snake.body...)
-
-			// Check food collision
-			if newHead.X == food.X && newHead.Y == food.Y {
-				score++
-				// Generate new food (avoid border area)
-				food = Point{1 + rand.Intn(gameW-2), 1 + rand.Intn(gameH-2)}
-				// Make sure food is not on snake
-				for {
-					onSnake := false
-					for _, segment := range snake.body {
-						if food.X == segment.X && food.Y == segment.Y {
-							onSnake = true
-							break
-						}
-					}
-					if !onSnake {
-						break
-					}
-					food = Point{1 + rand.Intn(gameW-2), 1 + rand.Intn(gameH-2)}
-				}
-			} else {
-				snake.body = snake.body[:len(snake.body)-1]
-			}
+func (g *SnakeGame) Draw(screen tcell.Screen) {
+	theme := g.env.Theme
+	termW, termH := g.termW, g.termH
 
-			// Draw
-			screen.Clear()
-
-			// Draw grid background - checkerboard pattern for visibility
-			gridLight := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorDarkGray, grayscale)).Background(tcell.ColorBlack)
-			gridDark := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorBlack)
-			for y := 1; y < gameH-1; y++ {
-				for x := 1; x < gameW-1; x++ {
-					if (x+y)%2 == 0 {
-						drawCell(x, y, '·', gridLight)
-					} else {
-						drawCell(x, y, ' ', gridDark)
-					}
-				}
-			}
+	if !g.snake.alive {
+		// Show countdown (3, 2, 1)
+		msg1 := fmt.Sprintf("GAME OVER - Score: %d", g.score)
+		countdownMsg := fmt.Sprintf("Restarting in %d...", g.countdown())
 
-			// Draw border using block characters
-			borderStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-			// Top and bottom borders
-			for x := 0; x < gameW; x++ {
-				drawCell(x, 0, '█', borderStyle)
-				drawCell(x, gameH-1, '█', borderStyle)
-			}
-			// Left and right borders
-			for y := 1; y < gameH-1; y++ {
-				drawCell(0, y, '█', borderStyle)
-				drawCell(gameW-1, y, '█', borderStyle)
-			}
+		drawing.CenterText(screen, 0, termH/2-1, termW, msg1, theme.Style(RoleDanger))
+		drawing.CenterText(screen, 0, termH/2+1, termW, countdownMsg, theme.Style(RoleAccent))
+		return
+	}
 
-			// Draw snake (same character for head and body)
-			snakeStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorGreen, grayscale)).Background(tcell.ColorBlack)
-			for _, segment := range snake.body {
-				drawCell(segment.X, segment.Y, '█', snakeStyle)
-			}
+	gameW, gameH := g.gameW, g.gameH
+	cellW, cellH := g.cellW, g.cellH
 
-			// Draw food
-			foodStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorRed, grayscale)).Background(tcell.ColorBlack)
-			drawCell(food.X, food.Y, '█', foodStyle)
-
-			// Draw score above the game area
-			scoreStr := fmt.Sprintf("Score: %d", score)
-			scoreStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorYellow, grayscale)).Background(tcell.ColorBlack)
-			scoreX := offsetX + (pixelW-len(scoreStr))/2
-			scoreY := offsetY - 1
-			if scoreY >= 0 {
-				for i, char := range scoreStr {
-					screen.SetContent(scoreX+i, scoreY, char, nil, scoreStyle)
-				}
-			}
+	// Helper function to draw a game cell as a block
+	drawCell := func(gx, gy int, ch rune, style tcell.Style) {
+		cell := drawing.Rect{X: g.offsetX + gx*cellW, Y: g.offsetY + gy*cellH, W: cellW, H: cellH}
+		drawing.FillRect(screen, cell, ch, style)
+	}
 
-			screen.Show()
+	// Draw grid background - checkerboard pattern for visibility
+	gridLight := theme.Style(RoleMuted)
+	gridDark := theme.Style(RoleBackground)
+	for y := 1; y < gameH-1; y++ {
+		for x := 1; x < gameW-1; x++ {
+			if (x+y)%2 == 0 {
+				drawCell(x, y, '·', gridLight)
+			} else {
+				drawCell(x, y, ' ', gridDark)
+			}
 		}
 	}
+
+	// Draw border using block characters
+	borderStyle := theme.Style(RoleText)
+	// Top and bottom borders
+	for x := 0; x < gameW; x++ {
+		drawCell(x, 0, '█', borderStyle)
+		drawCell(x, gameH-1, '█', borderStyle)
+	}
+	// Left and right borders
+	for y := 1; y < gameH-1; y++ {
+		drawCell(0, y, '█', borderStyle)
+		drawCell(gameW-1, y, '█', borderStyle)
+	}
+
+	// Draw snake (same character for head and body)
+	snakeStyle := theme.Style(RolePrimary)
+	for _, segment := range g.snake.body {
+		drawCell(segment.X, segment.Y, '█', snakeStyle)
+	}
+
+	// Draw food
+	foodStyle := theme.Style(RoleDanger)
+	drawCell(g.food.X, g.food.Y, '█', foodStyle)
+
+	// Draw score above the game area
+	scoreStr := fmt.Sprintf("Score: %d", g.score)
+	drawing.CenterText(screen, g.offsetX, g.offsetY-1, gameW*cellW, scoreStr, theme.Style(RoleAccent))
 }
 
 // findOptimalDirection uses BFS pathfinding to find the best direction to the food
@@ -397,8 +344,8 @@ func findOptimalDirection(snake Snake, food Point, w, h int) Point {
 	// BFS to find shortest path to food
 	directions := []Point{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
 	type node struct {
-		pos       Point
-		firstDir  Point
+		pos      Point
+		firstDir Point
 	}
 
 	queue := []node{{head, Point{}}}
@@ -487,4 +434,3 @@ func findOptimalDirection(snake Snake, food Point, w, h int) Point {
 	// Fallback: continue in current direction
 	return snake.direction
 }
-
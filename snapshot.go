@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Size in pixels of one cell in a snapshot.
+const (
+	snapshotCellW = 12
+	snapshotCellH = 24
+)
+
+// snapshotCapture collects frames as they're drawn, to save as a snapshot
+// once there are enough of them.
+type snapshotCapture struct {
+	path   string
+	want   int // Frames to collect: 1 for a PNG
+	frames []*frame
+	times  []time.Time
+	done   func(error) // Called once the file is written or has failed to be
+}
+
+// collect adds what's on screen to the snapshot being captured, and saves it
+// once it's complete.
+func (s *session) collect() {
+	c := s.capture
+	c.frames = append(c.frames, captureFrame(s.screen))
+	c.times = append(c.times, s.clock.Now())
+	if len(c.frames) < c.want {
+		return
+	}
+	s.capture = nil
+
+	// Each frame stays up until the next one was drawn, and the last for a
+	// frame's time
+	delays := make([]time.Duration, len(c.frames))
+	for i := range delays {
+		if i+1 < len(c.times) {
+			delays[i] = c.times[i+1].Sub(c.times[i])
+		} else {
+			delays[i] = time.Second / time.Duration(s.fps)
+		}
+	}
+	c.done(saveSnapshot(c.path, c.frames, delays, s.env.Theme))
+}
+
+// handleSnapshotKey saves what's on screen as a PNG for s, or the next
+// SnapshotFrames frames as an animated GIF for S. It reports whether the key
+// was one of these.
+func (s *session) handleSnapshotKey(ev *tcell.EventKey, mode string) bool {
+	if ev.Key() != tcell.KeyRune || (ev.Rune() != 's' && ev.Rune() != 'S') {
+		return false
+	}
+	if s.capture != nil {
+		// Still busy with the last one
+		return true
+	}
+	name := fmt.Sprintf("termsaver-%s-%s", mode, s.clock.Now().Format("20060102-150405"))
+	c := &snapshotCapture{path: name + ".png", want: 1}
+	if ev.Rune() == 'S' {
+		c.path, c.want = name+".gif", s.env.Config.Global.SnapshotFrames
+		s.showStatus(fmt.Sprintf("capturing %d frames", c.want))
+	}
+	c.done = func(err error) {
+		if err != nil {
+			s.showStatus(fmt.Sprintf("snapshot failed: %v", err))
+		} else {
+			s.showStatus("saved " + c.path)
+		}
+	}
+	s.capture = c
+	s.dirty = true
+	return true
+}
+
+// snapshot runs the selected mode off-screen, without taking over the
+// terminal, and saves what it shows once SnapshotAfter has passed: one frame
+// as a PNG, or SnapshotFrames frames as an animated GIF. The clock is
+// simulated, so it takes a fraction of the time and a given seed always
+// gives the same picture.
+func snapshot(env *Env, seed int64) error {
+	g := env.Config.Global
+	w, h, err := parseSize(g.SnapshotSize)
+	if err != nil {
+		return err
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		return fmt.Errorf("Error initializing screen: %v", err)
+	}
+	defer screen.Fini()
+	screen.SetSize(w, h)
+	env.Theme, _ = newTheme(env.Config.themeName(), 1<<24)
+
+	clock := newManualClock()
+	s := &session{screen: screen, clock: clock, fps: g.FPS, speed: g.Speed, env: env, overlay: newOverlay(env.Config.Overlay)}
+
+	// The first mode of the playlist, seeded the way run would seed it
+	player := startPlaylist(g, rand.New(rand.NewSource(seed)))
+	i, _ := lookupMode(player.Current().Mode)
+	info := registry[i]
+	env.Rand = rand.New(rand.NewSource(modeSeed(seed, 0)))
+	mode := info.new()
+	mode.Init(env, w, h)
+	sched := newScheduler(clock, info.step, s.fps, s.speed)
+
+	for end := clock.Now().Add(g.SnapshotAfter); clock.Now().Before(end); {
+		clock.Advance(sched.frame)
+		s.renderFrame(mode, sched)
+	}
+	c := &snapshotCapture{path: g.Snapshot, want: 1}
+	if strings.EqualFold(filepath.Ext(g.Snapshot), ".gif") {
+		c.want = g.SnapshotFrames
+	}
+	c.done = func(e error) { err = e }
+	s.capture = c
+	for s.capture != nil {
+		clock.Advance(sched.frame)
+		s.renderFrame(mode, sched)
+	}
+	return err
+}
+
+// maxSize is the most columns or rows termsaver will lay out a screen of,
+// which keeps a mistyped or hostile size from using up all memory.
+const maxSize = 1000
+
+// parseSize parses a size given as "WxH".
+func parseSize(s string) (w, h int, err error) {
+	if _, err := fmt.Sscanf(s, "%dx%d", &w, &h); err != nil || w < 1 || h < 1 || w > maxSize || h > maxSize {
+		return 0, 0, fmt.Errorf("Invalid size %q: want WxH, e.g. 80x24", s)
+	}
+	return w, h, nil
+}
+
+// saveSnapshot writes frames to path: the last one as a PNG for a .png
+// file, or all of them as an animated GIF for a .gif file, each shown for
+// its delay.
+func saveSnapshot(path string, frames []*frame, delays []time.Duration, theme *Theme) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".gif") {
+		err = encodeGIF(f, frames, delays, theme)
+	} else {
+		err = png.Encode(f, snapshotImage(frames[len(frames)-1], theme))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// encodeGIF writes frames as an animated GIF. The palette is the colors the
+// frames use when there are few enough of them, or the nearest of a fixed
+// palette when there are too many.
+func encodeGIF(w io.Writer, frames []*frame, delays []time.Duration, theme *Theme) error {
+	images := make([]*image.RGBA, len(frames))
+	used := map[color.RGBA]bool{}
+	for i, f := range frames {
+		images[i] = snapshotImage(f, theme)
+		for _, c := range f.cells {
+			fg, bg := snapshotColors(c.style, theme)
+			used[fg], used[bg] = true, true
+		}
+	}
+	var pal color.Palette
+	if len(used) <= 256 {
+		for c := range used {
+			pal = append(pal, c)
+		}
+		// In a fixed order, so the same frames make the same file
+		sort.Slice(pal, func(i, j int) bool {
+			a, b := pal[i].(color.RGBA), pal[j].(color.RGBA)
+			return uint32(a.R)<<16|uint32(a.G)<<8|uint32(a.B) < uint32(b.R)<<16|uint32(b.G)<<8|uint32(b.B)
+		})
+	} else {
+		pal = palette.Plan9
+	}
+
+	index := map[color.RGBA]uint8{}
+	anim := &gif.GIF{}
+	for i, img := range images {
+		p := image.NewPaletted(img.Rect, pal)
+		var last color.RGBA
+		var n uint8
+		for j := 0; j < len(img.Pix); j += 4 {
+			// Runs of one color are common, so skip the lookup for them
+			c := color.RGBA{img.Pix[j], img.Pix[j+1], img.Pix[j+2], img.Pix[j+3]}
+			if c != last || j == 0 {
+				var ok bool
+				if n, ok = index[c]; !ok {
+					n = uint8(pal.Index(c))
+					index[c] = n
+				}
+				last = c
+			}
+			p.Pix[j/4] = n
+		}
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, max(int(delays[i]/(10*time.Millisecond)), 1))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// snapshotImage draws a frame the way a terminal would show it, each cell
+// snapshotCellW x snapshotCellH pixels.
+func snapshotImage(f *frame, theme *Theme) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, f.w*snapshotCellW, f.h*snapshotCellH))
+	for y := 0; y < f.h; y++ {
+		for x := 0; x < f.w; x++ {
+			c := f.at(x, y)
+			w := 1
+			if c.width == 2 {
+				if x == f.w-1 {
+					// A wide rune that doesn't fit shows as a space
+					c.r = ' '
+				} else {
+					w = 2
+				}
+			}
+			drawCell(img, x*snapshotCellW, y*snapshotCellH, w*snapshotCellW, c, theme)
+			x += w - 1
+		}
+	}
+	return img
+}
+
+// drawCell draws c into img with its top left corner at x0, y0.
+func drawCell(img *image.RGBA, x0, y0, w int, c cell, theme *Theme) {
+	fg, bg := snapshotColors(c.style, theme)
+	_, _, attrs := c.style.Decompose()
+	on := cellPixels(c.r, w, snapshotCellH)
+	px := fontPixel(snapshotCellH)
+	if attrs&tcell.AttrBold != 0 && (c.r < 0x2500 || c.r > 0x28ff) {
+		// Bold text is struck twice, a font pixel apart; blocks and
+		// lines stay as they are
+		plain := on
+		on = func(x, y int) bool { return plain(x, y) || plain(x-px, y) }
+	}
+	underline := attrs&tcell.AttrUnderline != 0
+	for y := 0; y < snapshotCellH; y++ {
+		for x := 0; x < w; x++ {
+			col := bg
+			if on(x, y) || (underline && y >= snapshotCellH-2*px && y < snapshotCellH-px) {
+				col = fg
+			}
+			img.SetRGBA(x0+x, y0+y, col)
+		}
+	}
+}
+
+// snapshotColors returns the colors a cell with the given style is drawn in,
+// with the theme's text and background standing in for the terminal's
+// default colors.
+func snapshotColors(style tcell.Style, theme *Theme) (fg, bg color.RGBA) {
+	f, b, attrs := style.Decompose()
+	fg = toRGBA(f, theme.Color(RoleText))
+	bg = toRGBA(b, theme.Color(RoleBackground))
+	if attrs&tcell.AttrReverse != 0 {
+		fg, bg = bg, fg
+	}
+	if attrs&tcell.AttrDim != 0 {
+		fg = color.RGBA{uint8((int(fg.R) + int(bg.R)) / 2), uint8((int(fg.G) + int(bg.G)) / 2), uint8((int(fg.B) + int(bg.B)) / 2), 255}
+	}
+	return fg, bg
+}
+
+// toRGBA converts c, or def if c is the default color, for an image.
+func toRGBA(c, def tcell.Color) color.RGBA {
+	if !c.Valid() {
+		c = def
+	}
+	r, g, b := c.RGB()
+	if r < 0 {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// pixelText draws which pixels of a w x h cell showing r are set.
+func pixelText(r rune, w, h int) string {
+	on := cellPixels(r, w, h)
+	var b strings.Builder
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if on(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('|')
+	}
+	return b.String()
+}
+
+func TestCellPixels(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want string
+	}{
+		{'▀', "####|####|....|....|"},
+		{'▐', "..##|..##|..##|..##|"},
+		{'▗', "....|....|..##|..##|"},
+		{'┌', "....|.###|.#..|.#..|"},
+		{'┤', ".#..|##..|.#..|.#..|"},
+		{'⢁', "#...|....|....|..#.|"}, // Dots 1 and 8
+	}
+	for _, tt := range tests {
+		if got := pixelText(tt.r, 4, 4); got != tt.want {
+			t.Errorf("%q draws %s, want %s", tt.r, got, tt.want)
+		}
+	}
+
+	// Font glyphs sit in the middle of a cell with space around them
+	got := pixelText('T', 6, 12)
+	want := "......|......|" +
+		"#####.|..#...|..#...|..#...|..#...|..#...|..#...|" +
+		"......|......|......|"
+	if got != want {
+		t.Errorf("'T' draws\n%s, want\n%s", strings.ReplaceAll(got, "|", "\n"), strings.ReplaceAll(want, "|", "\n"))
+	}
+}
+
+func TestSnapshotImage(t *testing.T) {
+	theme := mustTheme("default", 1<<24)
+	red := tcell.NewRGBColor(255, 0, 0)
+	f := &frame{w: 3, h: 1, cells: []cell{
+		{r: 'T', style: tcell.StyleDefault.Foreground(red), width: 1},
+		{r: '█', style: tcell.StyleDefault.Foreground(tcell.ColorBlue), width: 1},
+		{r: ' ', style: tcell.StyleDefault.Reverse(true), width: 1},
+	}}
+	img := snapshotImage(f, theme)
+	if b := img.Bounds(); b.Dx() != 3*snapshotCellW || b.Dy() != snapshotCellH {
+		t.Fatalf("image is %v, want 3x1 cells", b)
+	}
+
+	text := toRGBA(theme.Color(RoleText), 0)
+	background := toRGBA(theme.Color(RoleBackground), 0)
+	px := fontPixel(snapshotCellH)
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"corner of the T cell", 0, 0, background},
+		{"top of the T", snapshotCellW / 2, snapshotCellH/2 - 3*px, color.RGBA{255, 0, 0, 255}},
+		{"block", snapshotCellW + 1, 1, toRGBA(tcell.ColorBlue, 0)},
+		{"reversed space", 2*snapshotCellW + 1, 1, text},
+	}
+	for _, tt := range tests {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s at %d,%d is %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+// inTempDir runs the rest of the test in an empty directory.
+func inTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestSnapshotKeys(t *testing.T) {
+	dir := inTempDir(t)
+	env := defaultEnv()
+	env.Config.Global.SnapshotFrames = 3
+	h := newHarness(t, "snowflakes", env, 20, 6, 1)
+	h.Run(time.Second)
+
+	press := func(r rune) {
+		t.Helper()
+		if !h.session.handleSnapshotKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone), "snowflakes") {
+			t.Fatalf("%q not handled as a snapshot key", r)
+		}
+	}
+	press('s')
+	h.Run(time.Second)
+	data, err := os.ReadFile(filepath.Join(dir, "termsaver-snowflakes-20240101-000001.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 20*snapshotCellW || b.Dy() != 6*snapshotCellH {
+		t.Errorf("PNG is %v, want 20x6 cells", b)
+	}
+	if !strings.HasPrefix(h.session.status, "saved ") {
+		t.Errorf("status is %q after saving", h.session.status)
+	}
+
+	press('S')
+	h.Run(time.Second)
+	f, err := os.Open(filepath.Join(dir, "termsaver-snowflakes-20240101-000002.gif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 {
+		t.Errorf("GIF has %d frames, want 3", len(anim.Image))
+	}
+	for i, d := range anim.Delay {
+		if d < 1 {
+			t.Errorf("frame %d has delay %d", i, d)
+		}
+	}
+}
+
+// TestSnapshotCommand renders snapshots the way -snapshot does and checks a
+// seed always gives the same picture.
+func TestSnapshotCommand(t *testing.T) {
+	dir := t.TempDir()
+	render := func(name string) []byte {
+		t.Helper()
+		cfg, _, err := parseTestConfig("-mode", "lightning", "-snapshot", filepath.Join(dir, name), "-snapshot-size", "30x8", "-snapshot-after", "3s", "-snapshot-frames", "4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := snapshot(&Env{Config: cfg}, 7); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	a, b := render("a.png"), render("b.png")
+	if !bytes.Equal(a, b) {
+		t.Errorf("the same seed gave different pictures")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 30*snapshotCellW || cfg.Height != 8*snapshotCellH {
+		t.Errorf("PNG is %dx%d, want 30x8 cells", cfg.Width, cfg.Height)
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(render("c.gif")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 4 {
+		t.Errorf("GIF has %d frames, want 4", len(anim.Image))
+	}
+
+	for _, args := range [][]string{
+		{"-snapshot", "out.jpg"},
+		{"-snapshot", "out.png", "-snapshot-size", "80"},
+	} {
+		if _, _, err := parseTestConfig(args...); err == nil {
+			t.Errorf("%v was accepted", args)
+		}
+	}
+}
@@ -2,12 +2,26 @@ package main
 
 import (
 	"math/rand"
-	"os"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	registerMode(modeInfo{
+		name:        "snowflakes",
+		description: "falling snow that accumulates at the bottom and clears periodically",
+		step:        100 * time.Millisecond, // Snow falls slower than matrix
+		new:         func() Mode { return &Snowflakes{} },
+	})
+}
+
+// SnowflakesConfig is the [snowflakes] section of the config file.
+type SnowflakesConfig struct {
+	WindChangeTime float64 `json:"wind_change_time"` // Seconds between wind direction changes
+	WindStrength   float64 `json:"wind_strength"`    // Baseline wind strength, 0 to 1
+}
+
 type Snowflake struct {
 	x      float64
 	y      float64
@@ -16,231 +30,233 @@ type Snowflake struct {
 	active bool
 }
 
-func runSnowflakes(screen tcell.Screen, sigChan chan os.Signal, interactive bool, grayscale bool, windChangeTime float64, windStrength float64) bool {
-	w, h := screen.Size()
+type Snowflakes struct {
+	env  *Env
+	rng  *rand.Rand
+	w, h int
 
 	// Track ground level for each column (Y coordinate where next snowflake lands)
 	// Y=0 is top of screen, Y=h-1 is bottom
-	groundLevel := make([]int, w)
-	for i := range groundLevel {
-		groundLevel[i] = h - 1 // Start at bottom
-	}
+	groundLevel []int
 
 	// Snowflakes falling
-	snowflakes := make([]Snowflake, 0)
-	maxSnowflakes := w / 2 // Limit number of active snowflakes
+	snowflakes    []Snowflake
+	maxSnowflakes int
 
 	// Wind simulation - changes over time
-	globalWind := 0.0 // Global wind direction (-1 to 1, negative = left, positive = right)
-	windChangeTimer := 0
+	globalWind      float64 // Global wind direction (-1 to 1, negative = left, positive = right)
+	windChangeTimer int
+	windChangeTicks int
+	windStrength    float64
+	windRange       float64
+}
+
+func (s *Snowflakes) Init(env *Env, w, h int) {
+	s.env = env
+	s.rng = env.Rand
+	s.w, s.h = w, h
+
+	s.groundLevel = make([]int, w)
+	for i := range s.groundLevel {
+		s.groundLevel[i] = h - 1 // Start at bottom
+	}
+
+	s.snowflakes = make([]Snowflake, 0)
+	s.maxSnowflakes = w / 2 // Limit number of active snowflakes
+
+	s.setWind(env.Config.Snowflakes)
+}
+
+func (s *Snowflakes) Reconfigure(env *Env) {
+	s.setWind(env.Config.Snowflakes)
+}
+
+// setWind applies the wind settings, clamped to sensible values.
+func (s *Snowflakes) setWind(cfg SnowflakesConfig) {
 	tickInterval := 100 * time.Millisecond
-	windChangeTicks := int(windChangeTime * float64(time.Second) / float64(tickInterval))
-	if windChangeTicks < 1 {
-		windChangeTicks = 1
+	s.windChangeTicks = int(cfg.WindChangeTime * float64(time.Second) / float64(tickInterval))
+	if s.windChangeTicks < 1 {
+		s.windChangeTicks = 1
 	}
 	// Clamp wind strength to reasonable range
-	if windStrength < 0 {
-		windStrength = 0
+	s.windStrength = cfg.WindStrength
+	if s.windStrength < 0 {
+		s.windStrength = 0
 	}
-	if windStrength > 1.0 {
-		windStrength = 1.0
+	if s.windStrength > 1.0 {
+		s.windStrength = 1.0
 	}
-	windRange := windStrength * 2.0 // Total range from -windStrength to +windStrength
+	s.windRange = s.windStrength * 2.0 // Total range from -windStrength to +windStrength
+}
 
-	ticker := time.NewTicker(tickInterval) // Snow falls slower than matrix
-	defer ticker.Stop()
+func (s *Snowflakes) Resize(w, h int) {
+	// Adjust ground level array
+	newGroundLevel := make([]int, w)
+	for i := range newGroundLevel {
+		if i < len(s.groundLevel) {
+			// Scale the ground level proportionally
+			newGroundLevel[i] = int(float64(s.groundLevel[i]) * float64(h-1) / float64(s.h-1))
+		} else {
+			newGroundLevel[i] = h - 1
+		}
+	}
+	s.groundLevel = newGroundLevel
+	s.w, s.h = w, h
 
-	// Event handling for resize and exit
-	eventChan := make(chan tcell.Event, 10)
-	go func() {
-		for {
-			eventChan <- screen.PollEvent()
+	// Remove snowflakes that are out of bounds
+	validSnowflakes := make([]Snowflake, 0)
+	for _, flake := range s.snowflakes {
+		if int(flake.x) >= 0 && int(flake.x) < w && flake.y >= 0 && int(flake.y) < h {
+			validSnowflakes = append(validSnowflakes, flake)
 		}
-	}()
-
-	rand.Seed(time.Now().UnixNano())
-
-	for {
-		select {
-		case <-sigChan:
-			return false
-		case event := <-eventChan:
-			switch ev := event.(type) {
-			case *tcell.EventResize:
-				newW, newH := screen.Size()
-				// Adjust ground level array
-				newGroundLevel := make([]int, newW)
-				for i := range newGroundLevel {
-					if i < len(groundLevel) {
-						// Scale the ground level proportionally
-						newGroundLevel[i] = int(float64(groundLevel[i]) * float64(newH-1) / float64(h-1))
-					} else {
-						newGroundLevel[i] = newH - 1
-					}
-				}
-				groundLevel = newGroundLevel
-				w, h = newW, newH
-
-				// Remove snowflakes that are out of bounds
-				validSnowflakes := make([]Snowflake, 0)
-				for _, flake := range snowflakes {
-					if int(flake.x) >= 0 && int(flake.x) < w && flake.y >= 0 && int(flake.y) < h {
-						validSnowflakes = append(validSnowflakes, flake)
-					}
-				}
-				snowflakes = validSnowflakes
-				maxSnowflakes = w / 2
+	}
+	s.snowflakes = validSnowflakes
+	s.maxSnowflakes = w / 2
+}
 
-				screen.Sync()
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
-					return false
-				}
-				// Space cycles to next mode
-				if ev.Rune() == ' ' {
-					return true
-				}
-				// In non-interactive mode, any key exits
-				if !interactive {
-					return false
-				}
-			}
-		case <-ticker.C:
-			// Update wind over time (gradual changes)
-			windChangeTimer++
-			if windChangeTimer >= windChangeTicks {
-				// Wind gradually shifts between -windStrength and +windStrength
-				globalWind = (rand.Float64() - 0.5) * windRange
-				windChangeTimer = 0
-			} else {
-				// Smooth wind transitions
-				targetWind := (rand.Float64() - 0.5) * windRange
-				globalWind = globalWind*0.95 + targetWind*0.05
-			}
+func (s *Snowflakes) HandleEvent(ev tcell.Event) bool {
+	return false
+}
 
-			// Check if we should clear accumulated snow
-			// Clear if any column has accumulated more than 50% of screen height
-			// (ground level less than 50% means more than 50% is filled)
-			shouldClear := false
-			for _, level := range groundLevel {
-				if level < h/2 {
-					shouldClear = true
-					break
-				}
-			}
+func (s *Snowflakes) Update(dt time.Duration) {
+	w, h := s.w, s.h
+	groundLevel := s.groundLevel
+	windStrength := s.windStrength
 
-			if shouldClear {
-				// Clear all accumulated snow
-				for i := range groundLevel {
-					groundLevel[i] = h - 1
-				}
-			}
+	// Update wind over time (gradual changes)
+	s.windChangeTimer++
+	if s.windChangeTimer >= s.windChangeTicks {
+		// Wind gradually shifts between -windStrength and +windStrength
+		s.globalWind = (s.rng.Float64() - 0.5) * s.windRange
+		s.windChangeTimer = 0
+	} else {
+		// Smooth wind transitions
+		targetWind := (s.rng.Float64() - 0.5) * s.windRange
+		s.globalWind = s.globalWind*0.95 + targetWind*0.05
+	}
 
-			// Spawn new snowflakes
-			for len(snowflakes) < maxSnowflakes && rand.Float64() < 0.3 {
-				// Each snowflake has its own wind component (individual variation)
-				// plus the global wind effect
-				// Individual variation is proportional to wind strength
-				individualWind := (rand.Float64() - 0.5) * windStrength * 0.375 // Small individual variation (about 37.5% of baseline)
-				totalWind := globalWind + individualWind
-				
-				snowflakes = append(snowflakes, Snowflake{
-					x:      float64(rand.Intn(w)),
-					y:      0.0,
-					speed:  0.5 + rand.Float64()*0.5, // Speed between 0.5 and 1.0
-					windX:  totalWind,
-					active: true,
-				})
-			}
+	// Check if we should clear accumulated snow
+	// Clear if any column has accumulated more than 50% of screen height
+	// (ground level less than 50% means more than 50% is filled)
+	shouldClear := false
+	for _, level := range groundLevel {
+		if level < h/2 {
+			shouldClear = true
+			break
+		}
+	}
 
-			// Update falling snowflakes
-			for i := range snowflakes {
-				if !snowflakes[i].active {
-					continue
-				}
+	if shouldClear {
+		// Clear all accumulated snow
+		for i := range groundLevel {
+			groundLevel[i] = h - 1
+		}
+	}
 
-				// Move snowflake down
-				snowflakes[i].y += snowflakes[i].speed
-				if snowflakes[i].y < 0 {
-					snowflakes[i].y = 0
-				}
+	// Spawn new snowflakes
+	for len(s.snowflakes) < s.maxSnowflakes && s.rng.Float64() < 0.3 {
+		// Each snowflake has its own wind component (individual variation)
+		// plus the global wind effect
+		// Individual variation is proportional to wind strength
+		individualWind := (s.rng.Float64() - 0.5) * windStrength * 0.375 // Small individual variation (about 37.5% of baseline)
+		totalWind := s.globalWind + individualWind
 
-				// Apply wind effect (horizontal movement)
-				// Wind effect is stronger at higher altitudes (more realistic)
-				windStrength := 1.0 - (snowflakes[i].y / float64(h)) // Stronger at top
-				snowflakes[i].x += snowflakes[i].windX * windStrength
+		s.snowflakes = append(s.snowflakes, Snowflake{
+			x:      float64(s.rng.Intn(w)),
+			y:      0.0,
+			speed:  0.5 + s.rng.Float64()*0.5, // Speed between 0.5 and 1.0
+			windX:  totalWind,
+			active: true,
+		})
+	}
 
-				// Wrap around screen edges horizontally
-				if snowflakes[i].x < 0 {
-					snowflakes[i].x += float64(w)
-				} else if snowflakes[i].x >= float64(w) {
-					snowflakes[i].x -= float64(w)
-				}
+	// Update falling snowflakes
+	snowflakes := s.snowflakes
+	for i := range snowflakes {
+		if !snowflakes[i].active {
+			continue
+		}
 
-				// Check if snowflake has reached the ground (or accumulated snow)
-				xPos := int(snowflakes[i].x)
-				if xPos < 0 {
-					xPos = 0
-				}
-				if xPos >= w {
-					xPos = w - 1
-				}
-				groundY := float64(groundLevel[xPos])
-				if snowflakes[i].y >= groundY {
-					// Snowflake has landed, accumulate it
-					// Raise the ground level for this column (decrement Y to move up)
-					if groundLevel[xPos] > 0 {
-						groundLevel[xPos]--
-					}
-					// Remove this snowflake
-					snowflakes[i].active = false
-				}
-			}
+		// Move snowflake down
+		snowflakes[i].y += snowflakes[i].speed
+		if snowflakes[i].y < 0 {
+			snowflakes[i].y = 0
+		}
 
-			// Remove inactive snowflakes
-			activeSnowflakes := make([]Snowflake, 0)
-			for _, flake := range snowflakes {
-				if flake.active {
-					activeSnowflakes = append(activeSnowflakes, flake)
-				}
+		// Apply wind effect (horizontal movement)
+		// Wind effect is stronger at higher altitudes (more realistic)
+		windStrength := 1.0 - (snowflakes[i].y / float64(h)) // Stronger at top
+		snowflakes[i].x += snowflakes[i].windX * windStrength
+
+		// Wrap around screen edges horizontally
+		if snowflakes[i].x < 0 {
+			snowflakes[i].x += float64(w)
+		} else if snowflakes[i].x >= float64(w) {
+			snowflakes[i].x -= float64(w)
+		}
+
+		// Check if snowflake has reached the ground (or accumulated snow)
+		xPos := int(snowflakes[i].x)
+		if xPos < 0 {
+			xPos = 0
+		}
+		if xPos >= w {
+			xPos = w - 1
+		}
+		groundY := float64(groundLevel[xPos])
+		if snowflakes[i].y >= groundY {
+			// Snowflake has landed, accumulate it
+			// Raise the ground level for this column (decrement Y to move up)
+			if groundLevel[xPos] > 0 {
+				groundLevel[xPos]--
 			}
-			snowflakes = activeSnowflakes
+			// Remove this snowflake
+			snowflakes[i].active = false
+		}
+	}
 
-			// Draw
-			screen.Clear()
+	// Remove inactive snowflakes
+	activeSnowflakes := make([]Snowflake, 0)
+	for _, flake := range snowflakes {
+		if flake.active {
+			activeSnowflakes = append(activeSnowflakes, flake)
+		}
+	}
+	s.snowflakes = activeSnowflakes
+}
 
-			snowStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
-			accumStyle := tcell.StyleDefault.Foreground(toGrayscale(tcell.ColorWhite, grayscale)).Background(tcell.ColorBlack)
+func (s *Snowflakes) Draw(screen tcell.Screen) {
+	w, h := s.w, s.h
+	groundLevel := s.groundLevel
+	theme := s.env.Theme
 
-			// Draw accumulated snow (solid block from bottom up to ground level)
-			for x := 0; x < w; x++ {
-				groundY := groundLevel[x]
-				// Draw solid block from bottom (h-1) up to ground level
-				for y := h - 1; y >= groundY && y >= 0; y-- {
-					screen.SetContent(x, y, '█', nil, accumStyle)
-				}
-			}
+	snowStyle := theme.Style(RoleText)
+	accumStyle := theme.Style(RoleText)
+
+	// Draw accumulated snow (solid block from bottom up to ground level)
+	for x := 0; x < w; x++ {
+		groundY := groundLevel[x]
+		// Draw solid block from bottom (h-1) up to ground level
+		for y := h - 1; y >= groundY && y >= 0; y-- {
+			screen.SetContent(x, y, '█', nil, accumStyle)
+		}
+	}
 
-			// Draw falling snowflakes
-			snowflakeChars := []rune{'*', '.', '+', '·'}
-			for _, flake := range snowflakes {
-				if flake.active {
-					xPos := int(flake.x)
-					if xPos >= 0 && xPos < w {
-						yPos := int(flake.y)
-						if yPos >= 0 && yPos < h {
-							// Only draw if not in accumulated snow area
-							if yPos < groundLevel[xPos] {
-								char := snowflakeChars[xPos%len(snowflakeChars)]
-								screen.SetContent(xPos, yPos, char, nil, snowStyle)
-							}
-						}
+	// Draw falling snowflakes
+	snowflakeChars := []rune{'*', '.', '+', '·'}
+	for _, flake := range s.snowflakes {
+		if flake.active {
+			xPos := int(flake.x)
+			if xPos >= 0 && xPos < w {
+				yPos := int(flake.y)
+				if yPos >= 0 && yPos < h {
+					// Only draw if not in accumulated snow area
+					if yPos < groundLevel[xPos] {
+						char := snowflakeChars[xPos%len(snowflakeChars)]
+						screen.SetContent(xPos, yPos, char, nil, snowStyle)
 					}
 				}
 			}
-
-			screen.Show()
 		}
 	}
 }
-
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Role is what a color is used for. Modes ask the theme for a role instead of
+// naming colors, so every mode follows the selected theme.
+type Role int
+
+const (
+	RoleBackground    Role = iota // Behind everything
+	RoleText                      // Borders, labels and plain foreground objects
+	RoleBright                    // Highlights: leading glyphs, bolt cores, sparkles
+	RoleMuted                     // Faint background detail such as grids
+	RoleDim                       // Secondary shapes such as cloud bodies
+	RolePrimary                   // The main subject: rain, the snake, the ground
+	RolePrimaryBright             // Lighter shade of the primary color
+	RoleAccent                    // Things that should stand out: bases, bolts, scores
+	RoleAccentSoft                // Lighter shade of the accent color
+	RoleDanger                    // Hazards and targets: food, titles
+	RoleCool                      // Missiles, glows
+	RoleCoolDim                   // Darker shade of the cool color
+	numRoles
+)
+
+// roleNames maps the names roles go by in the config file to roles.
+var roleNames = map[string]Role{
+	"background":     RoleBackground,
+	"text":           RoleText,
+	"bright":         RoleBright,
+	"muted":          RoleMuted,
+	"dim":            RoleDim,
+	"primary":        RolePrimary,
+	"primary_bright": RolePrimaryBright,
+	"accent":         RoleAccent,
+	"accent_soft":    RoleAccentSoft,
+	"danger":         RoleDanger,
+	"cool":           RoleCool,
+	"cool_dim":       RoleCoolDim,
+}
+
+// Gradient is a named run of colors. Modes either pick stops from it by index
+// or blend smoothly along it.
+type Gradient int
+
+const (
+	GradientRainbow  Gradient = iota // Trails and stripes
+	GradientSpectrum                 // Many distinct colors, e.g. one per bar
+	GradientWater                    // From faint to strong water
+	numGradients
+)
+
+// themeSpec is a theme as 24-bit colors.
+type themeSpec struct {
+	roles     [numRoles]int32
+	gradients [numGradients][]int32
+}
+
+var themeSpecs = map[string]themeSpec{
+	"default": {
+		roles: [numRoles]int32{
+			RoleBackground:    0x000000,
+			RoleText:          0xffffff,
+			RoleBright:        0xffffff,
+			RoleMuted:         0xa9a9a9,
+			RoleDim:           0x808080,
+			RolePrimary:       0x008000,
+			RolePrimaryBright: 0x00ff00,
+			RoleAccent:        0xffff00,
+			RoleAccentSoft:    0xffffe0,
+			RoleDanger:        0xff0000,
+			RoleCool:          0x0000ff,
+			RoleCoolDim:       0x008b8b,
+		},
+		gradients: [numGradients][]int32{
+			GradientRainbow: {0xff0000, 0xffa500, 0xffff00, 0x008000, 0x0000ff, 0x800080},
+			GradientSpectrum: {
+				0xff0000, 0xffa500, 0xffff00, 0x00ff00, 0x008000, 0x00ffff, 0x0000ff,
+				0x800080, 0xff00ff, 0xffc0cb, 0xf08080, 0x90ee90, 0xadd8e6, 0x9370db,
+			},
+			GradientWater: {0x005f73, 0x008b8b, 0x40c8c8, 0xe0ffff},
+		},
+	},
+	"grayscale": {
+		roles: [numRoles]int32{
+			RoleBackground:    0x000000,
+			RoleText:          0xffffff,
+			RoleBright:        0xffffff,
+			RoleMuted:         0x4e4e4e,
+			RoleDim:           0x808080,
+			RolePrimary:       0x9e9e9e,
+			RolePrimaryBright: 0xd0d0d0,
+			RoleAccent:        0xe4e4e4,
+			RoleAccentSoft:    0xf0f0f0,
+			RoleDanger:        0xbcbcbc,
+			RoleCool:          0x8a8a8a,
+			RoleCoolDim:       0x5a5a5a,
+		},
+		gradients: [numGradients][]int32{
+			GradientRainbow:  {0xf0f0f0, 0xd0d0d0, 0xb2b2b2, 0x949494, 0x767676, 0x585858},
+			GradientSpectrum: {0xeeeeee, 0xc6c6c6, 0x9e9e9e, 0x767676, 0xdadada, 0xb2b2b2, 0x8a8a8a},
+			GradientWater:    {0x3a3a3a, 0x6c6c6c, 0xa8a8a8, 0xeeeeee},
+		},
+	},
+	"solarized": {
+		roles: [numRoles]int32{
+			RoleBackground:    0x002b36,
+			RoleText:          0x93a1a1,
+			RoleBright:        0xfdf6e3,
+			RoleMuted:         0x073642,
+			RoleDim:           0x586e75,
+			RolePrimary:       0x859900,
+			RolePrimaryBright: 0x2aa198,
+			RoleAccent:        0xb58900,
+			RoleAccentSoft:    0xeee8d5,
+			RoleDanger:        0xdc322f,
+			RoleCool:          0x268bd2,
+			RoleCoolDim:       0x6c71c4,
+		},
+		gradients: [numGradients][]int32{
+			GradientRainbow:  {0xdc322f, 0xcb4b16, 0xb58900, 0x859900, 0x268bd2, 0x6c71c4},
+			GradientSpectrum: {0xdc322f, 0xcb4b16, 0xb58900, 0x859900, 0x2aa198, 0x268bd2, 0x6c71c4, 0xd33682},
+			GradientWater:    {0x073642, 0x268bd2, 0x2aa198, 0xeee8d5},
+		},
+	},
+	"amber": {
+		roles: [numRoles]int32{
+			RoleBackground:    0x0c0700,
+			RoleText:          0xffb000,
+			RoleBright:        0xffe0a0,
+			RoleMuted:         0x4d3300,
+			RoleDim:           0x805800,
+			RolePrimary:       0xcc8c00,
+			RolePrimaryBright: 0xffc640,
+			RoleAccent:        0xffb000,
+			RoleAccentSoft:    0xffd080,
+			RoleDanger:        0xff8000,
+			RoleCool:          0xb37b00,
+			RoleCoolDim:       0x664600,
+		},
+		gradients: [numGradients][]int32{
+			GradientRainbow:  {0xffe0a0, 0xffc640, 0xffb000, 0xcc8c00, 0x996900, 0x664600},
+			GradientSpectrum: {0xffb000, 0xcc8c00, 0xffd080, 0x996900, 0xffc640, 0xb37b00},
+			GradientWater:    {0x4d3300, 0x805800, 0xcc8c00, 0xffe0a0},
+		},
+	},
+	"green": {
+		roles: [numRoles]int32{
+			RoleBackground:    0x000a00,
+			RoleText:          0x33ff33,
+			RoleBright:        0xc0ffc0,
+			RoleMuted:         0x0a4010,
+			RoleDim:           0x1a801a,
+			RolePrimary:       0x20c020,
+			RolePrimaryBright: 0x66ff66,
+			RoleAccent:        0x99ff99,
+			RoleAccentSoft:    0xccffcc,
+			RoleDanger:        0x80ff40,
+			RoleCool:          0x18a040,
+			RoleCoolDim:       0x106020,
+		},
+		gradients: [numGradients][]int32{
+			GradientRainbow:  {0xc0ffc0, 0x80ff80, 0x33ff33, 0x20c020, 0x1a801a, 0x106020},
+			GradientSpectrum: {0x33ff33, 0x20c020, 0x99ff99, 0x1a801a, 0x66ff66, 0x18a040},
+			GradientWater:    {0x0a4010, 0x1a801a, 0x33ff33, 0xc0ffc0},
+		},
+	},
+	"pastel": {
+		roles: [numRoles]int32{
+			RoleBackground:    0x1e1e2e,
+			RoleText:          0xcdd6f4,
+			RoleBright:        0xffffff,
+			RoleMuted:         0x45475a,
+			RoleDim:           0x6c7086,
+			RolePrimary:       0xa6e3a1,
+			RolePrimaryBright: 0xd4f5d0,
+			RoleAccent:        0xf9e2af,
+			RoleAccentSoft:    0xfff3d6,
+			RoleDanger:        0xf38ba8,
+			RoleCool:          0x89b4fa,
+			RoleCoolDim:       0x74c7ec,
+		},
+		gradients: [numGradients][]int32{
+			GradientRainbow:  {0xf38ba8, 0xfab387, 0xf9e2af, 0xa6e3a1, 0x89b4fa, 0xcba6f7},
+			GradientSpectrum: {0xf38ba8, 0xfab387, 0xf9e2af, 0xa6e3a1, 0x94e2d5, 0x89dceb, 0x89b4fa, 0xcba6f7, 0xf5c2e7, 0xeba0ac},
+			GradientWater:    {0x313244, 0x74c7ec, 0x89dceb, 0xf5f5ff},
+		},
+	},
+	"high-contrast": {
+		roles: [numRoles]int32{
+			RoleBackground:    0x000000,
+			RoleText:          0xffffff,
+			RoleBright:        0xffffff,
+			RoleMuted:         0x808080,
+			RoleDim:           0xc0c0c0,
+			RolePrimary:       0x00ff00,
+			RolePrimaryBright: 0xa0ffa0,
+			RoleAccent:        0xffff00,
+			RoleAccentSoft:    0xffffff,
+			RoleDanger:        0xff0000,
+			RoleCool:          0x00ffff,
+			RoleCoolDim:       0x0080ff,
+		},
+		gradients: [numGradients][]int32{
+			GradientRainbow:  {0xff0000, 0xff8000, 0xffff00, 0x00ff00, 0x0080ff, 0xff00ff},
+			GradientSpectrum: {0xff0000, 0xffff00, 0x00ff00, 0x00ffff, 0x0080ff, 0xff00ff, 0xffffff},
+			GradientWater:    {0x0000ff, 0x0080ff, 0x00ffff, 0xffffff},
+		},
+	},
+}
+
+// themeNames lists the available themes.
+func themeNames() []string {
+	names := make([]string, 0, len(themeSpecs))
+	for name := range themeSpecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Theme hands out colors by role, fitted to what the terminal can show.
+type Theme struct {
+	name   string
+	spec   themeSpec
+	colors int                         // Colors the terminal supports, as from Screen.Colors
+	fitted map[tcell.Color]tcell.Color // Cache of fit results
+}
+
+// newTheme returns the named theme for a terminal with the given number of
+// colors, or false if there is no such theme.
+func newTheme(name string, colors int) (*Theme, bool) {
+	spec, ok := themeSpecs[name]
+	if !ok {
+		return nil, false
+	}
+	return &Theme{name: name, spec: spec, colors: colors, fitted: map[tcell.Color]tcell.Color{}}, true
+}
+
+// Color returns the color for a role.
+func (t *Theme) Color(r Role) tcell.Color {
+	return t.fit(tcell.NewHexColor(t.spec.roles[r]))
+}
+
+// Style returns a style drawing role r over the theme's background.
+func (t *Theme) Style(r Role) tcell.Style {
+	return t.StyleColor(t.Color(r))
+}
+
+// StyleColor returns a style drawing c over the theme's background. c should
+// come from the theme.
+func (t *Theme) StyleColor(c tcell.Color) tcell.Style {
+	return tcell.StyleDefault.Foreground(c).Background(t.Color(RoleBackground))
+}
+
+// Stops returns the number of colors in a gradient.
+func (t *Theme) Stops(g Gradient) int {
+	return len(t.spec.gradients[g])
+}
+
+// Stop returns the ith color of a gradient, wrapping around at the end.
+func (t *Theme) Stop(g Gradient, i int) tcell.Color {
+	stops := t.spec.gradients[g]
+	return t.fit(tcell.NewHexColor(stops[i%len(stops)]))
+}
+
+// Blend returns the color pos (0 to 1) of the way along a gradient,
+// interpolating between its stops.
+func (t *Theme) Blend(g Gradient, pos float64) tcell.Color {
+	stops := t.spec.gradients[g]
+	if pos <= 0 || len(stops) == 1 {
+		return t.fit(tcell.NewHexColor(stops[0]))
+	}
+	if pos >= 1 {
+		return t.fit(tcell.NewHexColor(stops[len(stops)-1]))
+	}
+	pos *= float64(len(stops) - 1)
+	i := int(pos)
+	frac := pos - float64(i)
+	r1, g1, b1 := tcell.NewHexColor(stops[i]).RGB()
+	r2, g2, b2 := tcell.NewHexColor(stops[i+1]).RGB()
+	mix := func(a, b int32) int32 {
+		return a + int32(float64(b-a)*frac+0.5)
+	}
+	return t.fit(tcell.NewRGBColor(mix(r1, r2), mix(g1, g2), mix(b1, b2)))
+}
+
+// fit maps a 24-bit color to the closest one the terminal can show. Anything
+// with fewer than 8 colors is left to tcell.
+func (t *Theme) fit(c tcell.Color) tcell.Color {
+	if t.colors >= 1<<24 || t.colors < 8 {
+		return c
+	}
+	if fitted, ok := t.fitted[c]; ok {
+		return fitted
+	}
+	fitted := tcell.FindColor(c, fitPalette(t.colors))
+	t.fitted[c] = fitted
+	return fitted
+}
+
+// fitPalette returns the palette colors to fit to on a terminal with n
+// colors. With 256 colors the first 16 are skipped, since users often
+// redefine them; the rest are a fixed color cube and gray ramp.
+func fitPalette(n int) []tcell.Color {
+	first := 0
+	if n >= 256 {
+		first, n = 16, 256
+	} else if n >= 16 {
+		n = 16
+	} else {
+		n = 8
+	}
+	palette := make([]tcell.Color, 0, n-first)
+	for i := first; i < n; i++ {
+		palette = append(palette, tcell.PaletteColor(i))
+	}
+	return palette
+}
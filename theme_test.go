@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestThemeGoldenFrames(t *testing.T) {
+	for _, name := range themeNames() {
+		t.Run(name, func(t *testing.T) {
+			env := defaultEnv()
+			env.Theme = mustTheme(name, 1<<24)
+			h := newHarness(t, "spectrograph", env, 60, 20, 1)
+			h.Step(20)
+			checkGolden(t, "theme-"+name, h.Frame())
+		})
+	}
+}
+
+func TestThemeDownsampledGoldenFrames(t *testing.T) {
+	for _, colors := range []int{256, 16} {
+		name := "theme-waterripple-" + map[int]string{256: "256", 16: "16"}[colors]
+		t.Run(name, func(t *testing.T) {
+			env := defaultEnv()
+			env.Theme = mustTheme("default", colors)
+			h := newHarness(t, "waterripple", env, 60, 20, 1)
+			h.Step(40)
+			checkGolden(t, name, h.Frame())
+		})
+	}
+}
+
+func TestThemeFitsTerminalColors(t *testing.T) {
+	theme := mustTheme("default", 16)
+	if got := theme.Color(RolePrimary); got != tcell.ColorGreen {
+		t.Errorf("primary on 16 colors = %v, want ColorGreen", got)
+	}
+	for i := 0; i <= 10; i++ {
+		c := theme.Blend(GradientWater, float64(i)/10)
+		if c < tcell.ColorBlack || c > tcell.ColorWhite {
+			t.Errorf("water gradient at %d/10 is %v, outside the 16 color palette", i, c)
+		}
+	}
+
+	theme = mustTheme("default", 256)
+	if c := theme.Color(RoleAccentSoft); c < tcell.PaletteColor(16) || c > tcell.PaletteColor(255) {
+		t.Errorf("accent on 256 colors = %v, want one of palette colors 16-255", c)
+	}
+}
+
+func TestThemeBlend(t *testing.T) {
+	theme := mustTheme("default", 1<<24)
+	stops := theme.spec.gradients[GradientWater]
+	if got := theme.Blend(GradientWater, 0); got != tcell.NewHexColor(stops[0]) {
+		t.Errorf("blend at 0 = %06x, want the first stop", got.Hex())
+	}
+	if got := theme.Blend(GradientWater, 1); got != tcell.NewHexColor(stops[len(stops)-1]) {
+		t.Errorf("blend at 1 = %06x, want the last stop", got.Hex())
+	}
+	// Halfway between the two middle stops of four
+	r1, g1, b1 := tcell.NewHexColor(stops[1]).RGB()
+	r2, g2, b2 := tcell.NewHexColor(stops[2]).RGB()
+	r, g, b := theme.Blend(GradientWater, 0.5).RGB()
+	if r != (r1+r2+1)/2 || g != (g1+g2+1)/2 || b != (b1+b2+1)/2 {
+		t.Errorf("blend at 0.5 = %d,%d,%d, want the midpoint of the middle stops", r, g, b)
+	}
+}
+
+func TestEveryThemeDefinesEveryColor(t *testing.T) {
+	for name, spec := range themeSpecs {
+		if spec.roles[RoleText] == spec.roles[RoleBackground] {
+			t.Errorf("%s: text is the same color as the background", name)
+		}
+		for g, stops := range spec.gradients {
+			if len(stops) < 2 {
+				t.Errorf("%s: gradient %d has %d stops", name, g, len(stops))
+			}
+		}
+	}
+}
@@ -1,374 +1,956 @@
 package main
 
 import (
+	"container/heap"
 	"fmt"
+	"math"
 	"math/rand"
-	"os"
+	"slices"
 	"time"
 
+	"github.com/chorankates/termsaver/drawing"
 	"github.com/gdamore/tcell/v2"
 )
 
+func init() {
+	registerMode(modeInfo{
+		name:        "towerdefense",
+		description: "tower defense game where guns, cannons and snipers hold off ever stronger waves of enemies marching along a path (build them yourself with -interactive)",
+		step:        100 * time.Millisecond,
+		new:         func() Mode { return &TowerDefense{} },
+	})
+}
+
+// TowerDefenseConfig is the [towerdefense] section of the config file.
+type TowerDefenseConfig struct {
+	Lives     int     `json:"lives"`      // Enemies that can get through before the game is lost
+	WavePause float64 `json:"wave_pause"` // Seconds between waves
+	Gold      int     `json:"gold"`       // Gold the player starts with, with -interactive
+}
+
+// towerType is one kind of tower: how it looks, how far it reaches and how
+// hard and how often it hits.
+type towerType struct {
+	name     string
+	glyph    rune
+	role     Role
+	reach    float64 // Range in cells, see cellDist
+	damage   int
+	cooldown time.Duration // Between shots
+	splash   float64       // Enemies this close to the target are hit too
+	cost     int           // Gold to build one, and to take it from level 1 to 2
+}
+
+var towerTypes = []towerType{
+	{name: "gun", glyph: '▲', role: RoleCool, reach: 6, damage: 2, cooldown: 400 * time.Millisecond, cost: 40},
+	{name: "cannon", glyph: '■', role: RoleAccent, reach: 5, damage: 5, cooldown: 1200 * time.Millisecond, splash: 3, cost: 70},
+	{name: "sniper", glyph: '◆', role: RolePrimaryBright, reach: 14, damage: 12, cooldown: 2500 * time.Millisecond, cost: 90},
+}
+
+const (
+	shotTime     = 150 * time.Millisecond // How long a shot stays on screen
+	gameOverTime = 5 * time.Second        // How long the final score is shown
+	maxTowers    = 40                     // Most towers the automatic player builds
+	maxLevel     = 3                      // Towers can be upgraded twice
+	noticeTime   = 2 * time.Second        // How long a message for the player stays up
+)
+
 type Tower struct {
-	pos    Point
-	range_ int
-	damage int
-	cooldown int
-	lastShot int
+	pos      Point
+	kind     int           // Index into towerTypes
+	level    int           // From 1 to maxLevel
+	spent    int           // Gold paid for it so far, half of which selling it gives back
+	cooldown time.Duration // Until it can fire again
+	target   Point         // Last enemy shot at, for drawing the shot
+	flash    time.Duration // Left to show the last shot
 }
 
 type Enemy struct {
-	pos      Point
-	health   int
+	x, y      float64 // Position in cells, between two points of its route
+	route     int     // Index into the routes
+	health    int
 	maxHealth int
-	pathIndex int
-	alive    bool
+	progress  float64 // Route points covered
+	speed     float64 // Route points per second
+	reward    int
+	boss      bool
+	alive     bool
 }
 
 type TowerTerrain struct {
 	Pos Point
 }
 
-func runTowerDefense(screen tcell.Screen, sigChan chan os.Signal) {
-	w, h := screen.Size()
-	
-	// Game state
-	towers := []Tower{}
-	enemies := []Enemy{}
-	terrain := []TowerTerrain{}
-	path := []Point{}
-	wave := 0
-	score := 0
-	enemiesKilled := 0
-	
-	// Initialize path (simple zigzag path)
-	path = generatePath(w, h)
-	
-	// Initialize terrain and towers
-	towers, terrain = generateLayout(w, h, path)
-	
-	// Last randomization time
-	lastRandomize := time.Now()
-	randomizeInterval := time.Duration(30+rand.Intn(16)) * time.Second
-	
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-	
-	// Event handling
-	eventChan := make(chan tcell.Event, 10)
-	go func() {
-		for {
-			eventChan <- screen.PollEvent()
-		}
-	}()
-	
-	enemySpawnTimer := 0
-	enemySpawnDelay := 50 // frames
-	
-	for {
-		select {
-		case <-sigChan:
-			return
-		case event := <-eventChan:
-			switch ev := event.(type) {
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
-					return
-				}
-			case *tcell.EventResize:
-				w, h = screen.Size()
-				path = generatePath(w, h)
-				towers, terrain = generateLayout(w, h, path)
-				screen.Sync()
-			}
-		case <-ticker.C:
-			// Randomize layout every 30-45 seconds
-			if time.Since(lastRandomize) >= randomizeInterval {
-				towers, terrain = generateLayout(w, h, path)
-				lastRandomize = time.Now()
-				randomizeInterval = time.Duration(30+rand.Intn(16)) * time.Second
-				// Clear existing enemies when layout changes
-				enemies = []Enemy{}
-				wave = 0
-			}
-			
-			// Spawn enemies
-			enemySpawnTimer++
-			if enemySpawnTimer >= enemySpawnDelay && len(enemies) < 10 {
-				if len(path) > 0 {
-					enemies = append(enemies, Enemy{
-						pos:      path[0],
-						health:   10 + wave*2,
-						maxHealth: 10 + wave*2,
-						pathIndex: 0,
-						alive:    true,
-					})
-					enemySpawnTimer = 0
-					if len(enemies)%5 == 0 {
-						wave++
-					}
-				}
-			}
-			
-			// Move enemies along path
-			for i := range enemies {
-				if !enemies[i].alive {
-					continue
-				}
-				
-				enemies[i].pathIndex++
-				if enemies[i].pathIndex >= len(path) {
-					// Enemy reached the end - remove it
-					enemies[i].alive = false
-					continue
-				}
-				enemies[i].pos = path[enemies[i].pathIndex]
+// cell returns the cell e is mostly in.
+func (e Enemy) cell() Point {
+	return Point{int(math.Round(e.x)), int(math.Round(e.y))}
+}
+
+// distTo returns how far e is from cell p, as cellDist counts it.
+func (e Enemy) distTo(p Point) float64 {
+	return math.Hypot(e.x-float64(p.X), 2*(e.y-float64(p.Y)))
+}
+
+// reach returns how far t can shoot, which grows with its level.
+func (t Tower) reach() float64 {
+	return towerTypes[t.kind].reach * (1 + 0.15*float64(t.level-1))
+}
+
+// damage returns how hard t hits: half as hard again for each level.
+func (t Tower) damage() int {
+	return towerTypes[t.kind].damage * (t.level + 1) / 2
+}
+
+// upgradeCost returns the gold it takes to bring t up a level.
+func (t Tower) upgradeCost() int {
+	return towerTypes[t.kind].cost * t.level
+}
+
+// TowerDefense sends waves of enemies along a path past towers that shoot
+// them. Each wave is bigger, tougher and faster than the last, and the
+// automatic player builds a new tower after every wave it survives. With
+// -interactive the player builds, upgrades and sells towers instead, moving
+// a cursor with the arrow keys or the mouse and paying with gold from kills.
+// The game is lost once Lives enemies have reached the end of the path.
+//
+// The path is generated for each game: one to three spawn points on the
+// left feed corridors that wind their way to one or two exits on the right.
+// Each enemy follows one route from a spawn to an exit, gliding between
+// cells rather than jumping from one to the next.
+type TowerDefense struct {
+	env  *Env
+	rng  *rand.Rand
+	w, h int
+
+	path    []Point        // Every path cell
+	onPath  map[Point]bool // The same, for lookups
+	routes  [][]Point      // From each spawn point to its exit
+	towers  []Tower
+	terrain []TowerTerrain
+	enemies []Enemy
+
+	wave       int           // Current wave, from 1; 0 before the first
+	inWave     bool          // Spawning or fighting a wave, rather than waiting for the next
+	toSpawn    int           // Enemies of the current wave still to come
+	sinceSpawn time.Duration // Since the last enemy of the wave came on
+	nextWave   time.Duration // Until the next wave starts
+	gameOver   time.Duration // Since the game was lost
+
+	lives int
+	kills int
+	score int
+	best  int // Best score since the mode started
+
+	canvas *drawing.Canvas // For drawing enemies between cells
+
+	// Interactive play
+	gold     int
+	cursor   Point
+	selected int              // Tower type built next
+	buttons  tcell.ButtonMask // Mouse buttons held at the last mouse event
+	notice   string           // Message for the player, such as why a tower can't be built
+	noticeAt time.Duration    // Left to show notice
+}
+
+func (g *TowerDefense) Init(env *Env, w, h int) {
+	g.env = env
+	g.rng = env.Rand
+	g.w, g.h = w, h
+	g.reset()
+}
+
+// Resize starts a new game laid out for the new size, since the path and
+// towers can't be moved under a running wave.
+func (g *TowerDefense) Resize(w, h int) {
+	g.w, g.h = w, h
+	g.canvas = nil
+	g.reset()
+}
+
+func (g *TowerDefense) HandleEvent(ev tcell.Event) bool {
+	if !g.env.Interactive {
+		return false
+	}
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		return g.handleKey(ev)
+	case *tcell.EventMouse:
+		// Act when a button goes down, not for as long as it's held
+		x, y := ev.Position()
+		g.moveCursor(x, y)
+		pressed := ev.Buttons() &^ g.buttons
+		g.buttons = ev.Buttons()
+		if pressed&tcell.Button1 != 0 {
+			g.buildOrUpgrade()
+		}
+		if pressed&tcell.Button2 != 0 {
+			g.sell()
+		}
+		return true
+	}
+	return false
+}
+
+// handleKey moves the cursor with the arrow keys, picks the tower type with
+// 1-3 or Tab, builds or upgrades with Enter or space and sells with x,
+// Delete or Backspace.
+func (g *TowerDefense) handleKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		g.moveCursor(g.cursor.X, g.cursor.Y-1)
+	case tcell.KeyDown:
+		g.moveCursor(g.cursor.X, g.cursor.Y+1)
+	case tcell.KeyLeft:
+		g.moveCursor(g.cursor.X-1, g.cursor.Y)
+	case tcell.KeyRight:
+		g.moveCursor(g.cursor.X+1, g.cursor.Y)
+	case tcell.KeyTab:
+		g.selected = (g.selected + 1) % len(towerTypes)
+	case tcell.KeyEnter:
+		g.buildOrUpgrade()
+	case tcell.KeyDelete, tcell.KeyBackspace, tcell.KeyBackspace2:
+		g.sell()
+	case tcell.KeyRune:
+		switch r := ev.Rune(); {
+		case r == ' ':
+			g.buildOrUpgrade()
+		case r == 'x':
+			g.sell()
+		case r >= '1' && r < '1'+rune(len(towerTypes)):
+			g.selected = int(r - '1')
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// moveCursor puts the cursor at x, y, kept to the playing field between the
+// scoreboard and the legend.
+func (g *TowerDefense) moveCursor(x, y int) {
+	g.cursor = Point{min(max(x, 0), g.w-1), min(max(y, 1), g.h-2)}
+}
+
+// towerAt returns the index of the tower at p, or -1.
+func (g *TowerDefense) towerAt(p Point) int {
+	for i, t := range g.towers {
+		if t.pos == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// canBuild reports why nothing can be built at p, or "" if something can.
+// Towers never go on the path, so they can't block it.
+func (g *TowerDefense) canBuild(p Point) string {
+	if g.onPath[p] {
+		return "can't build on the path"
+	}
+	for _, t := range g.terrain {
+		if t.Pos == p {
+			return "can't build on rock"
+		}
+	}
+	return ""
+}
+
+// buildOrUpgrade builds a tower of the selected type under the cursor, or
+// upgrades the one that's there.
+func (g *TowerDefense) buildOrUpgrade() {
+	if g.lives <= 0 {
+		return
+	}
+	if i := g.towerAt(g.cursor); i >= 0 {
+		t := &g.towers[i]
+		switch cost := t.upgradeCost(); {
+		case t.level >= maxLevel:
+			g.tell("already at the top level")
+		case cost > g.gold:
+			g.tell(fmt.Sprintf("upgrade needs %d gold", cost))
+		default:
+			g.gold -= cost
+			t.spent += cost
+			t.level++
+		}
+		return
+	}
+	typ := towerTypes[g.selected]
+	if why := g.canBuild(g.cursor); why != "" {
+		g.tell(why)
+	} else if typ.cost > g.gold {
+		g.tell(fmt.Sprintf("%s needs %d gold", typ.name, typ.cost))
+	} else {
+		g.gold -= typ.cost
+		g.towers = append(g.towers, Tower{pos: g.cursor, kind: g.selected, level: 1, spent: typ.cost})
+	}
+}
+
+// sell removes the tower under the cursor for half of what it cost.
+func (g *TowerDefense) sell() {
+	i := g.towerAt(g.cursor)
+	if i < 0 || g.lives <= 0 {
+		return
+	}
+	g.gold += g.towers[i].spent / 2
+	g.towers = append(g.towers[:i], g.towers[i+1:]...)
+}
+
+// tell shows msg to the player for a moment.
+func (g *TowerDefense) tell(msg string) {
+	g.notice, g.noticeAt = msg, noticeTime
+}
+
+// reset starts a new game on a new layout.
+func (g *TowerDefense) reset() {
+	cfg := g.env.Config.TowerDefense
+	g.path, g.routes = generatePath(g.rng, g.w, g.h)
+	g.onPath = make(map[Point]bool, len(g.path))
+	for _, p := range g.path {
+		g.onPath[p] = true
+	}
+	g.towers, g.terrain = generateLayout(g.rng, g.w, g.h, g.path)
+	if g.env.Interactive {
+		// The player builds their own
+		g.towers = nil
+	}
+	g.enemies = nil
+	g.wave, g.inWave, g.toSpawn = 0, false, 0
+	g.nextWave = g.wavePause()
+	g.gameOver = 0
+	g.lives = cfg.Lives
+	g.kills, g.score = 0, 0
+	g.gold = cfg.Gold
+	g.cursor = Point{g.w / 2, g.h / 2}
+	g.moveCursor(g.cursor.X, g.cursor.Y)
+	g.notice, g.noticeAt = "", 0
+}
+
+func (g *TowerDefense) wavePause() time.Duration {
+	return time.Duration(g.env.Config.TowerDefense.WavePause * float64(time.Second))
+}
+
+// waveSize returns how many enemies wave n sends.
+func waveSize(n int) int {
+	return 4 + 2*n
+}
+
+// waveHealth returns the health of an ordinary enemy in wave n.
+func waveHealth(n int) int {
+	return int(8 * math.Pow(1.2, float64(n-1)))
+}
+
+// waveSpeed returns how many path points an ordinary enemy in wave n covers
+// each second.
+func waveSpeed(n int) float64 {
+	return math.Min(3+0.2*float64(n-1), 8)
+}
+
+// waveInterval returns the time between enemies in wave n.
+func waveInterval(n int) time.Duration {
+	return max(800*time.Millisecond-time.Duration(n)*40*time.Millisecond, 300*time.Millisecond)
+}
+
+func (g *TowerDefense) Update(dt time.Duration) {
+	g.noticeAt = max(g.noticeAt-dt, 0)
+	if g.lives <= 0 {
+		g.gameOver += dt
+		if g.gameOver >= gameOverTime {
+			g.reset()
+		}
+		return
+	}
+
+	// Waves: wait, send the enemies one at a time, then wait again once
+	// they're all gone
+	if g.inWave && g.toSpawn == 0 && len(g.enemies) == 0 {
+		g.inWave = false
+		g.nextWave = g.wavePause()
+		if g.env.Interactive {
+			g.gold += 10 + 5*g.wave
+		} else {
+			g.autoBuild()
+		}
+	}
+	if !g.inWave {
+		g.nextWave -= dt
+		if g.nextWave <= 0 {
+			g.wave++
+			g.inWave = true
+			g.toSpawn = waveSize(g.wave)
+			g.sinceSpawn = waveInterval(g.wave)
+		}
+	}
+	if g.toSpawn > 0 {
+		g.sinceSpawn += dt
+		if g.sinceSpawn >= waveInterval(g.wave) {
+			g.sinceSpawn = 0
+			g.spawnEnemy()
+		}
+	}
+
+	g.moveEnemies(dt)
+	g.shoot(dt)
+
+	// Remove dead enemies
+	alive := g.enemies[:0]
+	for _, e := range g.enemies {
+		if e.alive {
+			alive = append(alive, e)
+		}
+	}
+	g.enemies = alive
+
+	if g.lives <= 0 {
+		g.best = max(g.best, g.score)
+	}
+}
+
+// spawnEnemy puts the next enemy of the wave at the start of a random
+// route. The last one of every fifth wave is a slow, tough boss.
+func (g *TowerDefense) spawnEnemy() {
+	g.toSpawn--
+	if len(g.routes) == 0 {
+		return
+	}
+	route := g.rng.Intn(len(g.routes))
+	start := g.routes[route][0]
+	e := Enemy{
+		x:      float64(start.X),
+		y:      float64(start.Y),
+		route:  route,
+		health: waveHealth(g.wave),
+		speed:  waveSpeed(g.wave),
+		reward: 10 + g.wave,
+		alive:  true,
+	}
+	if g.wave%5 == 0 && g.toSpawn == 0 {
+		e.boss = true
+		e.health *= 8
+		e.speed *= 0.6
+		e.reward *= 10
+	}
+	e.maxHealth = e.health
+	g.enemies = append(g.enemies, e)
+}
+
+// moveEnemies moves every enemy along its route, taking a life for each one
+// that reaches the exit.
+func (g *TowerDefense) moveEnemies(dt time.Duration) {
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		if !e.alive {
+			continue
+		}
+		route := g.routes[e.route]
+		e.progress += e.speed * dt.Seconds()
+		if e.progress >= float64(len(route)-1) {
+			e.alive = false
+			g.lives = max(g.lives-1, 0)
+			continue
+		}
+		// Part way from one route point to the next
+		n := int(e.progress)
+		f := e.progress - float64(n)
+		a, b := route[n], route[n+1]
+		e.x = float64(a.X) + f*float64(b.X-a.X)
+		e.y = float64(a.Y) + f*float64(b.Y-a.Y)
+	}
+}
+
+// shoot fires every tower that's ready at the enemy in range furthest along
+// the path.
+func (g *TowerDefense) shoot(dt time.Duration) {
+	for i := range g.towers {
+		t := &g.towers[i]
+		t.flash = max(t.flash-dt, 0)
+		t.cooldown -= dt
+		if t.cooldown > 0 {
+			continue
+		}
+		t.cooldown = 0
+		typ := towerTypes[t.kind]
+
+		target := -1
+		for j, e := range g.enemies {
+			if e.alive && e.distTo(t.pos) <= t.reach() && (target < 0 || e.progress > g.enemies[target].progress) {
+				target = j
 			}
-			
-			// Towers shoot at enemies
-			for i := range towers {
-				towers[i].lastShot++
-				if towers[i].lastShot < towers[i].cooldown {
-					continue
-				}
-				
-				// Find closest enemy in range
-				closestEnemy := -1
-				closestDist := towers[i].range_ + 1
-				
-				for j := range enemies {
-					if !enemies[j].alive {
-						continue
-					}
-					
-					dist := abs(towers[i].pos.X-enemies[j].pos.X) + abs(towers[i].pos.Y-enemies[j].pos.Y)
-					if dist <= towers[i].range_ && dist < closestDist {
-						closestDist = dist
-						closestEnemy = j
-					}
-				}
-				
-				// Shoot at closest enemy
-				if closestEnemy >= 0 {
-					enemies[closestEnemy].health -= towers[i].damage
-					towers[i].lastShot = 0
-					
-					if enemies[closestEnemy].health <= 0 {
-						enemies[closestEnemy].alive = false
-						enemiesKilled++
-						score += 10
-					}
+		}
+		if target < 0 {
+			continue
+		}
+		t.cooldown = typ.cooldown
+		t.target = g.enemies[target].cell()
+		t.flash = shotTime
+
+		g.hit(target, t.damage())
+		if typ.splash > 0 {
+			for j, e := range g.enemies {
+				if j != target && e.alive && e.distTo(t.target) <= typ.splash {
+					g.hit(j, t.damage()/2)
 				}
 			}
-			
-			// Remove dead enemies
-			aliveEnemies := []Enemy{}
-			for i := range enemies {
-				if enemies[i].alive {
-					aliveEnemies = append(aliveEnemies, enemies[i])
-				}
+		}
+	}
+}
+
+// hit does damage to enemy i, scoring it if it dies.
+func (g *TowerDefense) hit(i, damage int) {
+	e := &g.enemies[i]
+	e.health -= damage
+	if e.health <= 0 && e.alive {
+		e.alive = false
+		g.kills++
+		g.score += e.reward
+		g.gold += e.reward
+	}
+}
+
+// cellDist returns the distance between two cells, counting rows double
+// since a cell is about twice as tall as it is wide.
+func cellDist(a, b Point) float64 {
+	return math.Hypot(float64(a.X-b.X), 2*float64(a.Y-b.Y))
+}
+
+// autoBuild has the automatic player build a tower of a random type where it
+// covers the most path, trying a handful of free cells.
+func (g *TowerDefense) autoBuild() {
+	if len(g.towers) >= maxTowers || g.w < 3 || g.h < 3 {
+		return
+	}
+	blocked := blockedCells(g.path)
+	for _, t := range g.towers {
+		blocked[t.pos] = true
+	}
+	for _, t := range g.terrain {
+		blocked[t.Pos] = true
+	}
+
+	kind := g.rng.Intn(len(towerTypes))
+	best, bestCover := Point{}, 0
+	for i := 0; i < 40; i++ {
+		p := Point{1 + g.rng.Intn(g.w-2), 1 + g.rng.Intn(g.h-2)}
+		if blocked[p] {
+			continue
+		}
+		cover := 0
+		for _, q := range g.path {
+			if cellDist(p, q) <= towerTypes[kind].reach {
+				cover++
 			}
-			enemies = aliveEnemies
-			
-			// Draw
-			screen.Clear()
-			
-			// Draw terrain
-			terrainStyle := tcell.StyleDefault.Foreground(tcell.ColorDarkGray).Background(tcell.ColorBlack)
-			for _, t := range terrain {
-				screen.SetContent(t.Pos.X, t.Pos.Y, '▓', nil, terrainStyle)
+		}
+		if cover > bestCover {
+			best, bestCover = p, cover
+		}
+	}
+	if bestCover > 0 {
+		g.towers = append(g.towers, Tower{pos: best, kind: kind, level: 1})
+	}
+}
+
+func (g *TowerDefense) Draw(screen tcell.Screen) {
+	theme := g.env.Theme
+
+	if g.env.Interactive && g.lives > 0 {
+		g.drawReach(screen)
+	}
+	for _, t := range g.terrain {
+		drawing.Set(screen, t.Pos.X, t.Pos.Y, '▓', theme.Style(RoleMuted))
+	}
+	for _, p := range g.path {
+		drawing.Set(screen, p.X, p.Y, '·', theme.Style(RoleDim))
+	}
+
+	// Shots under the towers and enemies, so they stay visible
+	for _, t := range g.towers {
+		if t.flash > 0 {
+			drawing.Line(screen, t.pos.X, t.pos.Y, t.target.X, t.target.Y, theme.Style(RoleBright))
+		}
+	}
+	for _, t := range g.towers {
+		typ := towerTypes[t.kind]
+		// Upgraded towers stand out
+		drawing.Set(screen, t.pos.X, t.pos.Y, typ.glyph, theme.Style(typ.role).Bold(t.level > 1))
+	}
+	// Enemies as blobs of Braille dots, so they move smoothly between
+	// cells, with a bigger blob for a boss
+	if g.canvas == nil {
+		g.canvas = drawing.NewCanvas(drawing.Braille, g.w, g.h)
+	}
+	g.canvas.Clear()
+	sx, sy := g.canvas.Scale()
+	for _, e := range g.enemies {
+		bw, bh := 2, 3
+		if e.boss {
+			bw, bh = 4, 6
+		}
+		px := int(math.Round((e.x+0.5)*float64(sx))) - bw/2
+		py := int(math.Round((e.y+0.5)*float64(sy))) - bh/2
+		for dy := 0; dy < bh; dy++ {
+			for dx := 0; dx < bw; dx++ {
+				g.canvas.Set(px+dx, py+dy, theme.Color(RoleDanger))
 			}
-			
-			// Draw path
-			pathStyle := tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack)
-			for _, p := range path {
-				if p.X >= 0 && p.X < w && p.Y >= 0 && p.Y < h {
-					screen.SetContent(p.X, p.Y, '·', nil, pathStyle)
-				}
+		}
+	}
+	g.canvas.Draw(screen, 0, 0, theme.Color(RoleBackground))
+	for _, e := range g.enemies {
+		g.drawHealthBar(screen, e)
+	}
+	for _, r := range g.routes {
+		// Where enemies come from, and where they're headed
+		drawing.Set(screen, r[0].X, r[0].Y, '»', theme.Style(RoleDanger))
+		drawing.Set(screen, r[len(r)-1].X, r[len(r)-1].Y, '»', theme.Style(RoleAccent))
+	}
+
+	// Scoreboard along the top, tower legend along the bottom
+	board := fmt.Sprintf("Wave %d  Lives %d  Kills %d  Score %d  Best %d",
+		g.wave, g.lives, g.kills, g.score, max(g.best, g.score))
+	if g.env.Interactive {
+		board += fmt.Sprintf("  Gold %d", g.gold)
+	}
+	drawing.Text(screen, 0, 0, board, theme.Style(RoleAccent))
+	if !g.inWave && g.lives > 0 {
+		msg := fmt.Sprintf("Wave %d in %ds", g.wave+1, int(math.Ceil(g.nextWave.Seconds())))
+		drawing.Text(screen, g.w-len(msg), 0, msg, theme.Style(RoleText))
+	}
+	x := 0
+	for i, typ := range towerTypes {
+		if g.env.Interactive {
+			// Short, to leave room for what's under the cursor
+			style := theme.Style(RoleText).Reverse(i == g.selected)
+			x = drawing.Text(screen, x, g.h-1, fmt.Sprint(i+1), style)
+			drawing.Set(screen, x, g.h-1, typ.glyph, theme.Style(typ.role).Reverse(i == g.selected))
+			x = drawing.Text(screen, x+1, g.h-1, fmt.Sprintf("%dg", typ.cost), style) + 2
+			continue
+		}
+		drawing.Set(screen, x, g.h-1, typ.glyph, theme.Style(typ.role))
+		x = drawing.Text(screen, x+2, g.h-1, typ.name, theme.Style(RoleText)) + 2
+	}
+	if g.env.Interactive && g.lives > 0 {
+		g.drawCursor(screen)
+	}
+
+	if g.lives <= 0 {
+		drawing.Banner(screen, []string{
+			"GAME OVER",
+			fmt.Sprintf("Wave %d, score %d", g.wave, g.score),
+			fmt.Sprintf("New game in %d", int(math.Ceil((gameOverTime - g.gameOver).Seconds()))),
+		}, theme.Style(RoleDanger))
+	}
+}
+
+// drawReach outlines the range of the tower under the cursor, or of the
+// tower that would be built there.
+func (g *TowerDefense) drawReach(screen tcell.Screen) {
+	t := Tower{pos: g.cursor, kind: g.selected, level: 1}
+	if i := g.towerAt(g.cursor); i >= 0 {
+		t = g.towers[i]
+	} else if g.canBuild(g.cursor) != "" {
+		return
+	}
+	reach := t.reach()
+	style := g.env.Theme.Style(RoleMuted)
+	for y := 1; y < g.h-1; y++ {
+		for x := 0; x < g.w; x++ {
+			if math.Abs(cellDist(t.pos, Point{x, y})-reach) < 0.5 {
+				drawing.Set(screen, x, y, '.', style)
 			}
-			
-			// Draw towers
-			towerStyle := tcell.StyleDefault.Foreground(tcell.ColorBlue).Background(tcell.ColorBlack)
-			for _, tower := range towers {
-				if tower.pos.X >= 0 && tower.pos.X < w && tower.pos.Y >= 0 && tower.pos.Y < h {
-					screen.SetContent(tower.pos.X, tower.pos.Y, '▲', nil, towerStyle)
-				}
+		}
+	}
+}
+
+// drawCursor shows the cursor by reversing the cell under it, and what's
+// there in the bottom right corner.
+func (g *TowerDefense) drawCursor(screen tcell.Screen) {
+	theme := g.env.Theme
+	r, _, style, _ := screen.GetContent(g.cursor.X, g.cursor.Y)
+	screen.SetContent(g.cursor.X, g.cursor.Y, r, nil, style.Reverse(true))
+
+	var info string
+	if i := g.towerAt(g.cursor); i >= 0 {
+		t := g.towers[i]
+		info = fmt.Sprintf("%s level %d  sell %dg", towerTypes[t.kind].name, t.level, t.spent/2)
+		if t.level < maxLevel {
+			info = fmt.Sprintf("%s level %d  upgrade %dg  sell %dg", towerTypes[t.kind].name, t.level, t.upgradeCost(), t.spent/2)
+		}
+	} else if why := g.canBuild(g.cursor); why == "" {
+		typ := towerTypes[g.selected]
+		info = fmt.Sprintf("build %s %dg", typ.name, typ.cost)
+	}
+	if g.noticeAt > 0 {
+		info = g.notice
+	}
+	drawing.Text(screen, g.w-len([]rune(info)), g.h-1, info, theme.Style(RoleText))
+}
+
+// drawHealthBar draws a three cell bar over an enemy showing the health it
+// has left.
+func (g *TowerDefense) drawHealthBar(screen tcell.Screen, e Enemy) {
+	theme := g.env.Theme
+	full := int(math.Ceil(3 * float64(e.health) / float64(e.maxHealth)))
+	role := RolePrimaryBright
+	if full == 1 {
+		role = RoleDanger
+	}
+	for i := 0; i < 3; i++ {
+		style := theme.Style(RoleMuted)
+		if i < full {
+			style = theme.Style(role)
+		}
+		c := e.cell()
+		drawing.Set(screen, c.X-1+i, c.Y-1, '▁', style)
+	}
+}
+
+// Paths are carved through a coarse grid of rooms, each roomW x roomH
+// cells, with corridors between the middles of neighbouring rooms. That
+// leaves gaps between neighbouring corridors to build towers in.
+const (
+	roomW = 4
+	roomH = 3
+)
+
+// generatePath lays out the path for a w x h screen: one to three spawn
+// points on the left edge, each with a winding corridor to one of one or two
+// exits on the right edge. Corridors can meet and share the way. It returns
+// every path cell and, for each spawn point, the route to its exit: the
+// shortest way through the corridors, found with A*, which also makes sure
+// every exit can be reached.
+func generatePath(rng *rand.Rand, w, h int) ([]Point, [][]Point) {
+	// Rooms sit between the scoreboard and the legend, with a row spare at
+	// the top for health bars
+	cols, rows := (w-5)/roomW+1, (h-5)/roomH+1
+	room := func(c Point) Point { return Point{2 + c.X*roomW, 2 + c.Y*roomH} }
+	if w < 8 || h < 5 || cols < 2 {
+		// Too small to wind about: straight across the middle
+		var line []Point
+		for x := 0; x < w; x++ {
+			line = append(line, Point{x, h / 2})
+		}
+		if len(line) == 0 {
+			// No room for a path at all, so no enemies either
+			return nil, nil
+		}
+		return line, [][]Point{line}
+	}
+
+	spawns := rng.Perm(rows)[:1+rng.Intn(min(3, rows))]
+	exits := rng.Perm(rows)[:1+rng.Intn(min(2, rows))]
+
+	var path []Point
+	onPath := map[Point]bool{}
+	carve := func(a, b Point) {
+		for p := a; ; {
+			if !onPath[p] {
+				onPath[p] = true
+				path = append(path, p)
 			}
-			
-			// Draw enemies
-			for _, enemy := range enemies {
-				if enemy.pos.X >= 0 && enemy.pos.X < w && enemy.pos.Y >= 0 && enemy.pos.Y < h {
-					enemyStyle := tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack)
-					healthPercent := float64(enemy.health) / float64(enemy.maxHealth)
-					var char rune
-					if healthPercent > 0.75 {
-						char = '█'
-					} else if healthPercent > 0.5 {
-						char = '▓'
-					} else if healthPercent > 0.25 {
-						char = '▒'
-					} else {
-						char = '░'
-					}
-					screen.SetContent(enemy.pos.X, enemy.pos.Y, char, nil, enemyStyle)
-				}
+			if p == b {
+				return
 			}
-			
-			// Draw projectiles (visual effect - bullets from towers to enemies)
-			for _, tower := range towers {
-				if tower.lastShot > tower.cooldown-5 && tower.lastShot < tower.cooldown {
-					// Find target
-					closestEnemy := -1
-					closestDist := tower.range_ + 1
-					
-					for j := range enemies {
-						if !enemies[j].alive {
-							continue
-						}
-						dist := abs(tower.pos.X-enemies[j].pos.X) + abs(tower.pos.Y-enemies[j].pos.Y)
-						if dist <= tower.range_ && dist < closestDist {
-							closestDist = dist
-							closestEnemy = j
-						}
-					}
-					
-					if closestEnemy >= 0 {
-						lineStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
-						drawLine(screen, tower.pos, enemies[closestEnemy].pos, lineStyle)
-					}
-				}
+			p.X += sign(b.X - p.X)
+			p.Y += sign(b.Y - p.Y)
+		}
+	}
+	var ends [][2]Point
+	for i, sy := range spawns {
+		ey := exits[i%len(exits)]
+		rooms := carveRooms(rng, cols, rows, Point{0, sy}, Point{cols - 1, ey})
+		start, end := room(rooms[0]), room(rooms[len(rooms)-1])
+		carve(Point{0, start.Y}, start)
+		for j := 1; j < len(rooms); j++ {
+			carve(room(rooms[j-1]), room(rooms[j]))
+		}
+		carve(end, Point{w - 1, end.Y})
+		ends = append(ends, [2]Point{{0, start.Y}, {w - 1, end.Y}})
+	}
+
+	routes := make([][]Point, len(ends))
+	for i, e := range ends {
+		routes[i] = findPath(e[0], e[1], func(p Point) bool { return onPath[p] })
+		if routes[i] == nil {
+			// Can't happen, as every corridor was carved end to end
+			panic(fmt.Sprintf("towerdefense: no route from %v to %v", e[0], e[1]))
+		}
+	}
+	return path, routes
+}
+
+// carveRooms walks from room from to room to on a cols x rows grid, never
+// visiting a room twice, and returns the rooms on the way. It's a depth-first
+// search that picks its next room at random, mostly heading right, and backs
+// up out of dead ends, which makes for maze-like winding paths.
+func carveRooms(rng *rand.Rand, cols, rows int, from, to Point) []Point {
+	visited := map[Point]bool{from: true}
+	stack := []Point{from}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		if cur == to {
+			return stack
+		}
+		var next []Point
+		var weights []float64
+		total := 0.0
+		for _, d := range []Point{{1, 0}, {0, -1}, {0, 1}, {-1, 0}} {
+			n := Point{cur.X + d.X, cur.Y + d.Y}
+			if n.X < 0 || n.Y < 0 || n.X >= cols || n.Y >= rows || visited[n] {
+				continue
 			}
-			
-			// Draw UI
-			scoreStr := fmt.Sprintf("Wave: %d | Killed: %d | Score: %d", wave, enemiesKilled, score)
-			uiStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
-			for i, char := range scoreStr {
-				if i < w {
-					screen.SetContent(i, 0, char, nil, uiStyle)
-				}
+			weight := 1.0
+			switch {
+			case d.X > 0:
+				weight = 3
+			case d.X < 0:
+				weight = 0.3
+			case n.X == to.X && sign(to.Y-cur.Y) == d.Y:
+				// Down the last column to the exit
+				weight = 3
 			}
-			
-			// Draw next randomization countdown
-			timeLeft := int(randomizeInterval.Seconds() - time.Since(lastRandomize).Seconds())
-			if timeLeft > 0 {
-				countdownStr := fmt.Sprintf("Next layout: %ds", timeLeft)
-				for i, char := range countdownStr {
-					if w-len(countdownStr)+i >= 0 && w-len(countdownStr)+i < w {
-						screen.SetContent(w-len(countdownStr)+i, h-1, char, nil, uiStyle)
-					}
-				}
+			next = append(next, n)
+			weights = append(weights, weight)
+			total += weight
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		pick := rng.Float64() * total
+		i := 0
+		for ; i < len(next)-1 && pick >= weights[i]; i++ {
+			pick -= weights[i]
+		}
+		visited[next[i]] = true
+		stack = append(stack, next[i])
+	}
+	// Every room on the grid can be reached, so this is never hit
+	return []Point{from}
+}
+
+// findPath returns the shortest 4-connected way from a to b through cells
+// open says can be walked, including both ends, or nil if there is none. It
+// is A* with the Manhattan distance as the heuristic.
+func findPath(a, b Point, open func(Point) bool) []Point {
+	h := func(p Point) int { return abs(p.X-b.X) + abs(p.Y-b.Y) }
+	from := map[Point]Point{}
+	cost := map[Point]int{a: 0}
+	frontier := &pointHeap{{a, h(a)}}
+	for frontier.Len() > 0 {
+		cur := heap.Pop(frontier).(pointCost).p
+		if cur == b {
+			path := []Point{b}
+			for p := b; p != a; {
+				p = from[p]
+				path = append(path, p)
 			}
-			
-			screen.Show()
-		}
-	}
-}
-
-func generatePath(w, h int) []Point {
-	path := []Point{}
-	
-	// Create a simple zigzag path from left to right
-	midY := h / 2
-	step := 2
-	
-	// Start from left edge
-	startX := 2
-	if startX >= w {
-		startX = 1
-	}
-	
-	for x := startX; x < w-2; x += step {
-		// Zigzag pattern
-		y := midY
-		if (x/step)%2 == 1 {
-			y = midY - 3
-			if y < 2 {
-				y = midY
+			slices.Reverse(path)
+			return path
+		}
+		for _, d := range []Point{{1, 0}, {0, -1}, {0, 1}, {-1, 0}} {
+			n := Point{cur.X + d.X, cur.Y + d.Y}
+			if !open(n) {
+				continue
 			}
-		} else {
-			y = midY + 3
-			if y >= h-2 {
-				y = midY
+			if c, seen := cost[n]; seen && c <= cost[cur]+1 {
+				continue
 			}
+			cost[n] = cost[cur] + 1
+			from[n] = cur
+			heap.Push(frontier, pointCost{n, cost[n] + h(n)})
 		}
-		path = append(path, Point{X: x, Y: y})
 	}
-	
-	// Ensure we have at least some path
-	if len(path) < 5 {
-		path = []Point{
-			{2, h / 2},
-			{w / 4, h / 2},
-			{w / 2, h / 2},
-			{3 * w / 4, h / 2},
-			{w - 3, h / 2},
-		}
+	return nil
+}
+
+// pointCost is a cell on the A* frontier with its estimated total cost.
+type pointCost struct {
+	p    Point
+	cost int
+}
+
+// pointHeap is a min-heap of pointCosts for container/heap.
+type pointHeap []pointCost
+
+func (h pointHeap) Len() int           { return len(h) }
+func (h pointHeap) Less(i, j int) bool { return h[i].cost < h[j].cost }
+func (h pointHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *pointHeap) Push(x any)        { *h = append(*h, x.(pointCost)) }
+func (h *pointHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// sign returns -1, 0 or 1 for negative, zero or positive x.
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
 	}
-	
-	return path
+	return 0
 }
 
-func generateLayout(w, h int, path []Point) ([]Tower, []TowerTerrain) {
-	towers := []Tower{}
-	terrain := []TowerTerrain{}
-	
-	// Create a set of path points to avoid placing towers/terrain on path
-	pathSet := make(map[Point]bool)
+// blockedCells returns the cells of path and those next to them, where
+// nothing may be built.
+func blockedCells(path []Point) map[Point]bool {
+	blocked := make(map[Point]bool)
 	for _, p := range path {
-		pathSet[p] = true
-		// Also block adjacent cells
 		for dx := -1; dx <= 1; dx++ {
 			for dy := -1; dy <= 1; dy++ {
-				pathSet[Point{p.X + dx, p.Y + dy}] = true
+				blocked[Point{p.X + dx, p.Y + dy}] = true
 			}
 		}
 	}
-	
+	return blocked
+}
+
+func generateLayout(rng *rand.Rand, w, h int, path []Point) ([]Tower, []TowerTerrain) {
+	towers := []Tower{}
+	terrain := []TowerTerrain{}
+	if w < 3 || h < 3 {
+		return towers, terrain
+	}
+
+	// Keep towers and terrain off the path and the cells next to it, and off
+	// the scoreboard and legend rows
+	pathSet := blockedCells(path)
+
 	// Place some terrain (obstacles)
 	numTerrain := (w * h) / 30
 	for i := 0; i < numTerrain; i++ {
-		attempts := 0
-		for attempts < 50 {
-			x := 1 + rand.Intn(w-2)
-			y := 1 + rand.Intn(h-2)
-			pos := Point{x, y}
-			
+		for attempts := 0; attempts < 50; attempts++ {
+			pos := Point{1 + rng.Intn(w-2), 1 + rng.Intn(h-2)}
 			if !pathSet[pos] {
-				terrain = append(terrain, TowerTerrain{
-					Pos: pos,
-				})
+				terrain = append(terrain, TowerTerrain{Pos: pos})
 				pathSet[pos] = true
 				break
 			}
-			attempts++
 		}
 	}
-	
-	// Place towers (defenders)
-	numTowers := 5 + rand.Intn(8) // 5-12 towers
+
+	// Place a few towers of random types to start with
+	numTowers := 4 + rng.Intn(3)
 	for i := 0; i < numTowers; i++ {
-		attempts := 0
-		for attempts < 50 {
-			x := 1 + rand.Intn(w-2)
-			y := 1 + rand.Intn(h-2)
-			pos := Point{x, y}
-			
+		for attempts := 0; attempts < 50; attempts++ {
+			pos := Point{1 + rng.Intn(w-2), 1 + rng.Intn(h-2)}
 			if !pathSet[pos] {
+				kind := rng.Intn(len(towerTypes))
 				towers = append(towers, Tower{
-					pos:     pos,
-					range_:  5 + rand.Intn(5), // Range 5-9
-					damage:  2 + rand.Intn(3), // Damage 2-4
-					cooldown: 10 + rand.Intn(10), // Cooldown 10-19 frames
-					lastShot: rand.Intn(10),
+					pos:      pos,
+					kind:     kind,
+					level:    1,
+					cooldown: time.Duration(rng.Int63n(int64(towerTypes[kind].cooldown))),
 				})
 				pathSet[pos] = true
 				break
 			}
-			attempts++
 		}
 	}
-	
+
 	return towers, terrain
 }
-
-
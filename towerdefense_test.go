@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newInteractiveTowerDefense(t *testing.T) *harness {
+	t.Helper()
+	env := defaultEnv()
+	env.Interactive = true
+	return newHarness(t, "towerdefense", env, 60, 20, 1)
+}
+
+func key(k tcell.Key, r rune) *tcell.EventKey {
+	return tcell.NewEventKey(k, r, tcell.ModNone)
+}
+
+func TestTowerDefenseInteractiveGoldenFrame(t *testing.T) {
+	h := newInteractiveTowerDefense(t)
+	g := h.mode.(*TowerDefense)
+	g.HandleEvent(tcell.NewEventMouse(20, 4, tcell.Button1, tcell.ModNone))
+	g.HandleEvent(tcell.NewEventMouse(20, 4, tcell.ButtonNone, tcell.ModNone))
+	g.HandleEvent(key(tcell.KeyRune, '3'))
+	g.HandleEvent(tcell.NewEventMouse(40, 12, tcell.Button1, tcell.ModNone))
+	h.Step(80)
+	checkGolden(t, "towerdefense-interactive", h.Frame())
+}
+
+func TestTowerDefensePlacement(t *testing.T) {
+	h := newInteractiveTowerDefense(t)
+	g := h.mode.(*TowerDefense)
+	if len(g.towers) != 0 {
+		t.Fatalf("interactive game started with %d towers", len(g.towers))
+	}
+
+	// Not on the path, whichever way the cursor gets there
+	g.moveCursor(g.path[10].X, g.path[10].Y)
+	g.HandleEvent(key(tcell.KeyEnter, 0))
+	g.HandleEvent(tcell.NewEventMouse(g.path[20].X, g.path[20].Y, tcell.Button1, tcell.ModNone))
+	if len(g.towers) != 0 || g.gold != 150 {
+		t.Fatalf("built on the path: %d towers, %d gold", len(g.towers), g.gold)
+	}
+
+	// Find a free cell and build a gun there
+	free := Point{-1, -1}
+	for y := 1; y < g.h-1 && free.X < 0; y++ {
+		for x := 0; x < g.w; x++ {
+			if g.canBuild(Point{x, y}) == "" {
+				free = Point{x, y}
+				break
+			}
+		}
+	}
+	g.moveCursor(free.X, free.Y)
+	g.HandleEvent(key(tcell.KeyRune, '1'))
+	g.HandleEvent(key(tcell.KeyRune, ' '))
+	if len(g.towers) != 1 || g.gold != 110 {
+		t.Fatalf("after building a gun: %d towers, %d gold", len(g.towers), g.gold)
+	}
+
+	// Upgrade it twice, which costs 40 then 80, the second of which is more
+	// than is left
+	g.HandleEvent(key(tcell.KeyEnter, 0))
+	g.HandleEvent(key(tcell.KeyEnter, 0))
+	if tw := g.towers[0]; tw.level != 2 || g.gold != 70 || g.notice == "" {
+		t.Fatalf("after upgrading: level %d, %d gold, notice %q", tw.level, g.gold, g.notice)
+	}
+	if tw := g.towers[0]; tw.damage() != 3 || tw.reach() <= towerTypes[0].reach {
+		t.Errorf("level 2 gun does %d damage at range %g", tw.damage(), tw.reach())
+	}
+
+	// Selling gives half of the 80 spent back
+	g.HandleEvent(key(tcell.KeyRune, 'x'))
+	if len(g.towers) != 0 || g.gold != 110 {
+		t.Fatalf("after selling: %d towers, %d gold", len(g.towers), g.gold)
+	}
+
+	// Other keys still reach the runner
+	if g.HandleEvent(key(tcell.KeyRune, 's')) {
+		t.Errorf("s was taken from the runner")
+	}
+}
+
+func TestTowerDefenseCursorStaysOnField(t *testing.T) {
+	h := newInteractiveTowerDefense(t)
+	g := h.mode.(*TowerDefense)
+	for i := 0; i < 100; i++ {
+		g.HandleEvent(key(tcell.KeyUp, 0))
+		g.HandleEvent(key(tcell.KeyLeft, 0))
+	}
+	if g.cursor != (Point{0, 1}) {
+		t.Errorf("cursor at %v, want the top left of the field", g.cursor)
+	}
+	g.HandleEvent(tcell.NewEventMouse(500, 500, tcell.ButtonNone, tcell.ModNone))
+	if g.cursor != (Point{59, 18}) {
+		t.Errorf("cursor at %v, want the bottom right of the field", g.cursor)
+	}
+}
+
+func TestTowerDefensePaths(t *testing.T) {
+	for _, size := range []Point{{80, 24}, {60, 20}, {120, 40}, {20, 8}, {7, 4}} {
+		for seed := int64(1); seed <= 20; seed++ {
+			path, routes := generatePath(rand.New(rand.NewSource(seed)), size.X, size.Y)
+			onPath := map[Point]bool{}
+			for _, p := range path {
+				if p.X < 0 || p.Y < 0 || p.X >= size.X || p.Y >= size.Y {
+					t.Fatalf("%v seed %d: path cell %v off the screen", size, seed, p)
+				}
+				onPath[p] = true
+			}
+			if len(routes) == 0 || len(routes) > 3 {
+				t.Fatalf("%v seed %d: %d routes", size, seed, len(routes))
+			}
+			for _, route := range routes {
+				if route[0].X != 0 || route[len(route)-1].X != size.X-1 {
+					t.Fatalf("%v seed %d: route runs from %v to %v, not edge to edge", size, seed, route[0], route[len(route)-1])
+				}
+				for i, p := range route {
+					if !onPath[p] {
+						t.Fatalf("%v seed %d: route leaves the path at %v", size, seed, p)
+					}
+					if i > 0 && abs(route[i-1].X-p.X)+abs(route[i-1].Y-p.Y) != 1 {
+						t.Fatalf("%v seed %d: route jumps from %v to %v", size, seed, route[i-1], p)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTowerDefenseNoRoomForPath(t *testing.T) {
+	path, routes := generatePath(rand.New(rand.NewSource(1)), 0, 0)
+	if len(path) != 0 || len(routes) != 0 {
+		t.Errorf("path %v and routes %v on an empty screen, want none", path, routes)
+	}
+}
+
+func TestFindPath(t *testing.T) {
+	// A wall down the middle with a gap at the bottom
+	open := func(p Point) bool {
+		return p.X >= 0 && p.Y >= 0 && p.X < 5 && p.Y < 5 && (p.X != 2 || p.Y == 4)
+	}
+	path := findPath(Point{0, 0}, Point{4, 0}, open)
+	if len(path) != 13 || path[0] != (Point{0, 0}) || path[12] != (Point{4, 0}) {
+		t.Errorf("path around the wall: %v", path)
+	}
+	if path := findPath(Point{0, 0}, Point{4, 0}, func(p Point) bool { return open(p) && p != (Point{2, 4}) }); path != nil {
+		t.Errorf("path through a solid wall: %v", path)
+	}
+}
+
+func TestTowerDefenseEnemiesMoveSmoothly(t *testing.T) {
+	h := newInteractiveTowerDefense(t)
+	g := h.mode.(*TowerDefense)
+	g.spawnEnemy()
+	e := &g.enemies[len(g.enemies)-1]
+	route := g.routes[e.route]
+	g.moveEnemies(time.Duration(float64(time.Second) / e.speed * 1.5))
+	a, b := route[1], route[2]
+	wantX := float64(a.X) + 0.5*float64(b.X-a.X)
+	wantY := float64(a.Y) + 0.5*float64(b.Y-a.Y)
+	if math.Abs(e.x-wantX) > 1e-6 || math.Abs(e.y-wantY) > 1e-6 {
+		t.Errorf("enemy at %g,%g after 1.5 cells, want %g,%g", e.x, e.y, wantX, wantY)
+	}
+}
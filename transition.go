@@ -0,0 +1,136 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// transitionFunc draws the point p (0 to 1) of the way from one frame to
+// another. At 0 the screen shows from, at 1 it shows to.
+type transitionFunc func(screen tcell.Screen, from, to *frame, p float64)
+
+// transitions are the effects used when moving from one mode to the next.
+// "random" picks one of the others each time.
+var transitions = map[string]transitionFunc{
+	"none":     nil,
+	"dissolve": dissolveTransition,
+	"wipe":     wipeTransition,
+	"melt":     meltTransition,
+	"scroll":   scrollTransition,
+}
+
+// transitionNames lists the selectable transitions, including "random".
+func transitionNames() []string {
+	names := []string{"random"}
+	for name := range transitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pickTransition returns the named transition, choosing one with rng for
+// "random".
+func pickTransition(name string, rng *rand.Rand) transitionFunc {
+	if name != "random" {
+		return transitions[name]
+	}
+	var names []string
+	for name, fn := range transitions {
+		if fn != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return transitions[names[rng.Intn(len(names))]]
+}
+
+// cellNoise returns a fixed pseudo random value in [0, 1) for a position, so
+// effects can vary per cell without keeping state between frames.
+func cellNoise(x, y int) float64 {
+	return tickNoise(x, y, 0)
+}
+
+// tickNoise is cellNoise that changes with t, so a mode can flicker from one
+// step to the next and still draw the same frame however often it's drawn.
+func tickNoise(x, y, t int) float64 {
+	h := uint32(x)*374761393 + uint32(y)*668265263 + uint32(t)*2246822519
+	h = (h ^ (h >> 13)) * 1274126177
+	h ^= h >> 16
+	return float64(h) / (1 << 32)
+}
+
+// dissolveTransition swaps cells over to the new frame in random order.
+func dissolveTransition(screen tcell.Screen, from, to *frame, p float64) {
+	w, h := screen.Size()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if cellNoise(x, y) < p {
+				to.at(x, y).set(screen, x, y)
+			} else {
+				from.at(x, y).set(screen, x, y)
+			}
+		}
+	}
+}
+
+// wipeTransition sweeps the new frame in from the left behind a bright edge.
+func wipeTransition(screen tcell.Screen, from, to *frame, p float64) {
+	w, h := screen.Size()
+	edge := int(p * float64(w+1))
+	edgeStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			switch {
+			case x < edge-1:
+				to.at(x, y).set(screen, x, y)
+			case x == edge-1 && p < 1:
+				screen.SetContent(x, y, '▐', nil, edgeStyle)
+			default:
+				from.at(x, y).set(screen, x, y)
+			}
+		}
+	}
+}
+
+// meltTransition lets the old frame drip down off the screen column by
+// column, like matrix rain, uncovering the new one.
+func meltTransition(screen tcell.Screen, from, to *frame, p float64) {
+	w, h := screen.Size()
+	for x := 0; x < w; x++ {
+		// Columns start falling at different times but all finish by p = 1
+		delay := cellNoise(x, 0) * 0.5
+		fall := (p - delay) / (1 - delay)
+		if fall < 0 {
+			fall = 0
+		}
+		shift := int(fall * fall * float64(h))
+		if p >= 1 {
+			shift = h
+		}
+		for y := 0; y < h; y++ {
+			if y < shift {
+				to.at(x, y).set(screen, x, y)
+			} else {
+				from.at(x, y-shift).set(screen, x, y)
+			}
+		}
+	}
+}
+
+// scrollTransition scrolls the old frame up and the new one in from below.
+func scrollTransition(screen tcell.Screen, from, to *frame, p float64) {
+	w, h := screen.Size()
+	offset := int(p * float64(h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if y+offset < h {
+				from.at(x, y+offset).set(screen, x, y)
+			} else {
+				to.at(x, y+offset-h).set(screen, x, y)
+			}
+		}
+	}
+}
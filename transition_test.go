@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// transitionFrames returns the last frames of two modes run at the same size.
+func transitionFrames(t *testing.T) (*frame, *frame) {
+	from := newHarness(t, "matrix", defaultEnv(), 60, 20, 1)
+	from.Step(30)
+	to := newHarness(t, "waterripple", defaultEnv(), 60, 20, 1)
+	to.Step(40)
+	return captureFrame(from.screen), captureFrame(to.screen)
+}
+
+func TestTransitionGoldenFrames(t *testing.T) {
+	from, to := transitionFrames(t)
+	for _, name := range transitionNames() {
+		fn := transitions[name]
+		if fn == nil {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			h := newHarness(t, "matrix", defaultEnv(), 60, 20, 1)
+			fn(h.screen, from, to, 0.5)
+			checkGolden(t, "transition-"+name, h.Frame())
+		})
+	}
+}
+
+func TestTransitionEnds(t *testing.T) {
+	from, to := transitionFrames(t)
+	for _, name := range transitionNames() {
+		fn := transitions[name]
+		if fn == nil {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			h := newHarness(t, "matrix", defaultEnv(), 60, 20, 1)
+			fn(h.screen, from, to, 0)
+			if got := captureFrame(h.screen); !sameFrame(got, from) {
+				t.Errorf("transition at 0 doesn't show the old frame")
+			}
+			fn(h.screen, from, to, 1)
+			if got := captureFrame(h.screen); !sameFrame(got, to) {
+				t.Errorf("transition at 1 doesn't show the new frame")
+			}
+		})
+	}
+}
+
+func sameFrame(a, b *frame) bool {
+	if a.w != b.w || a.h != b.h {
+		return false
+	}
+	for i := range a.cells {
+		if a.cells[i].r != b.cells[i].r || a.cells[i].style != b.cells[i].style {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSessionTransitionsIntoNextMode(t *testing.T) {
+	old := newHarness(t, "matrix", defaultEnv(), 40, 12, 1)
+	old.Step(20)
+
+	// Leave the old mode on screen, as the runner does when moving on
+	h := newHarness(t, "spectrograph", defaultEnv(), 40, 12, 1)
+	old.mode.Draw(h.screen)
+	h.session.startTransition(wipeTransition, time.Second)
+	if h.session.from == nil {
+		t.Fatalf("no transition started")
+	}
+
+	plain := newHarness(t, "spectrograph", defaultEnv(), 40, 12, 1)
+	h.Run(500 * time.Millisecond)
+	plain.Run(500 * time.Millisecond)
+	if h.Frame() == plain.Frame() {
+		t.Errorf("halfway through the transition the new mode is already fully shown")
+	}
+
+	h.Run(time.Second)
+	plain.Run(time.Second)
+	if h.session.from != nil {
+		t.Errorf("transition still running after it should have finished")
+	}
+	if h.Frame() != plain.Frame() {
+		t.Errorf("after the transition the new mode doesn't look as it would have without one")
+	}
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// vterm is a small terminal emulator. It interprets what a program wrote to
+// its terminal, cursor movement, erasing and colors included, and keeps the
+// resulting grid of cells. Escape sequences it doesn't know are skipped.
+// Sequences may be split across writes.
+type vterm struct {
+	w, h     int
+	cells    []cell // A wide rune's right half is a cell with width 0
+	x, y     int
+	wrapNext bool // The last column was just written; the next rune wraps
+	style    tcell.Style
+	savedX   int
+	savedY   int
+	top      int // Scrolling region, first and last row
+	bottom   int
+
+	// While a program such as an editor is on the alternate screen, main
+	// keeps the cells of the normal one
+	main  []cell
+	modes map[int]bool // Other private modes set (h) or reset (l), by number
+
+	state  vtState
+	params strings.Builder // Parameters of the escape sequence being read
+}
+
+type vtState int
+
+const (
+	vtGround vtState = iota
+	vtEscape
+	vtCSI
+	vtOSC
+	vtOSCEscape
+	vtCharset
+)
+
+func newVterm(w, h int) *vterm {
+	t := &vterm{style: tcell.StyleDefault}
+	t.Resize(w, h)
+	return t
+}
+
+// Resize changes the terminal size, keeping what fits from the top left.
+func (t *vterm) Resize(w, h int) {
+	w, h = max(w, 1), max(h, 1)
+	if t.main != nil {
+		t.main = resizeCells(t.main, t.w, t.h, w, h)
+	}
+	t.cells = resizeCells(t.cells, t.w, t.h, w, h)
+	t.w, t.h = w, h
+	t.x, t.y = min(t.x, w-1), min(t.y, h-1)
+	t.top, t.bottom = 0, h-1
+	t.wrapNext = false
+}
+
+// resizeCells copies what fits of a w x h grid into a new nw x nh one.
+func resizeCells(cells []cell, w, h, nw, nh int) []cell {
+	resized := make([]cell, nw*nh)
+	for y := 0; y < nh; y++ {
+		for x := 0; x < nw; x++ {
+			if x < w && y < h {
+				resized[y*nw+x] = cells[y*w+x]
+			} else {
+				resized[y*nw+x] = blankCell(tcell.StyleDefault)
+			}
+		}
+	}
+	return resized
+}
+
+// clone returns a copy of the terminal, parser state and all.
+func (t *vterm) clone() *vterm {
+	c := *t
+	c.cells = append([]cell(nil), t.cells...)
+	if t.main != nil {
+		c.main = append([]cell(nil), t.main...)
+	}
+	c.modes = make(map[int]bool, len(t.modes))
+	for m, on := range t.modes {
+		c.modes[m] = on
+	}
+	c.params = strings.Builder{}
+	c.params.WriteString(t.params.String())
+	return &c
+}
+
+// at returns the cell at x, y.
+func (t *vterm) at(x, y int) cell {
+	if x < 0 || y < 0 || x >= t.w || y >= t.h {
+		return blankCell(tcell.StyleDefault)
+	}
+	return t.cells[y*t.w+x]
+}
+
+func blankCell(style tcell.Style) cell {
+	return cell{r: ' ', style: style, width: 1}
+}
+
+// blank is what erased cells become: empty, keeping the current background.
+func (t *vterm) blank() cell {
+	_, bg, _ := t.style.Decompose()
+	return blankCell(tcell.StyleDefault.Background(bg))
+}
+
+// Write interprets output written to the terminal.
+func (t *vterm) Write(s string) {
+	for _, r := range s {
+		switch t.state {
+		case vtGround:
+			t.ground(r)
+		case vtEscape:
+			t.escape(r)
+		case vtCSI:
+			if r >= 0x40 && r <= 0x7e {
+				t.state = vtGround
+				t.csi(t.params.String(), r)
+			} else {
+				t.params.WriteRune(r)
+			}
+		case vtOSC:
+			// Window titles and the like, ended by BEL or ESC \
+			if r == 0x07 {
+				t.state = vtGround
+			} else if r == 0x1b {
+				t.state = vtOSCEscape
+			}
+		case vtOSCEscape:
+			t.state = vtGround
+			if r != '\\' {
+				t.escape(r)
+			}
+		case vtCharset:
+			t.state = vtGround
+		}
+	}
+}
+
+func (t *vterm) ground(r rune) {
+	switch r {
+	case 0x1b:
+		t.state = vtEscape
+	case '\r':
+		t.x, t.wrapNext = 0, false
+	case '\n', '\v', '\f':
+		t.lineFeed()
+	case '\b':
+		t.x, t.wrapNext = max(t.x-1, 0), false
+	case '\t':
+		t.x, t.wrapNext = min((t.x/8+1)*8, t.w-1), false
+	default:
+		if r >= 0x20 && r != 0x7f {
+			t.put(r)
+		}
+	}
+}
+
+func (t *vterm) escape(r rune) {
+	t.state = vtGround
+	switch r {
+	case '[':
+		t.state = vtCSI
+		t.params.Reset()
+	case ']':
+		t.state = vtOSC
+	case '(', ')', '*', '+':
+		t.state = vtCharset
+	case '7':
+		t.savedX, t.savedY = t.x, t.y
+	case '8':
+		t.x, t.y, t.wrapNext = t.savedX, t.savedY, false
+	case 'D':
+		t.lineFeed()
+	case 'E':
+		t.x = 0
+		t.lineFeed()
+	case 'M':
+		if t.y == t.top {
+			t.scroll(-1)
+		} else if t.y > 0 {
+			t.y--
+		}
+	case 'c':
+		*t = *newVterm(t.w, t.h)
+	}
+}
+
+// put writes a rune at the cursor and moves past it.
+func (t *vterm) put(r rune) {
+	width := runewidth.RuneWidth(r)
+	if width == 0 {
+		// Combining character: it belongs with the cell before the cursor
+		x := t.x - 1
+		if t.wrapNext {
+			x = t.x
+		}
+		if x >= 0 {
+			if c := &t.cells[t.y*t.w+x]; c.width == 0 && x > 0 {
+				t.cells[t.y*t.w+x-1].comb = append(t.cells[t.y*t.w+x-1].comb, r)
+			} else {
+				c.comb = append(c.comb, r)
+			}
+		}
+		return
+	}
+	if t.wrapNext || (width == 2 && t.x == t.w-1) {
+		t.x = 0
+		t.lineFeed()
+	}
+	t.unpair(t.x)
+	if width == 2 {
+		if t.w < 2 {
+			return
+		}
+		t.unpair(t.x + 1)
+		t.cells[t.y*t.w+t.x+1] = cell{style: t.style}
+	}
+	t.cells[t.y*t.w+t.x] = cell{r: r, style: t.style, width: width}
+	t.x += width
+	t.wrapNext = false
+	if t.x >= t.w {
+		t.x, t.wrapNext = t.w-1, true
+	}
+}
+
+// unpair blanks the other half of a wide rune at x on the cursor's row, as
+// writing over either half of one erases it.
+func (t *vterm) unpair(x int) {
+	row := t.cells[t.y*t.w : (t.y+1)*t.w]
+	if row[x].width == 0 && x > 0 {
+		row[x-1] = blankCell(row[x-1].style)
+	}
+	if row[x].width == 2 && x+1 < t.w {
+		row[x+1] = blankCell(row[x+1].style)
+	}
+}
+
+// lineFeed moves the cursor down a line, scrolling at the bottom of the
+// scrolling region.
+func (t *vterm) lineFeed() {
+	t.wrapNext = false
+	if t.y == t.bottom {
+		t.scroll(1)
+	} else if t.y < t.h-1 {
+		t.y++
+	}
+}
+
+// scroll moves the contents of the scrolling region up n lines, or down for
+// negative n.
+func (t *vterm) scroll(n int) {
+	t.deleteLines(t.top, n)
+}
+
+// deleteLines removes n lines starting at row y, moving the lines below it
+// in the scrolling region up and blanking the bottom. Negative n inserts
+// blank lines at y instead.
+func (t *vterm) deleteLines(y, n int) {
+	if y < t.top || y > t.bottom {
+		return
+	}
+	rows := t.bottom + 1 - y
+	if n > rows {
+		n = rows
+	}
+	if n < -rows {
+		n = -rows
+	}
+	region := t.cells[y*t.w : (t.bottom+1)*t.w]
+	if n > 0 {
+		copy(region, region[n*t.w:])
+		t.fill(region[(rows-n)*t.w:])
+	} else if n < 0 {
+		copy(region[-n*t.w:], region[:(rows+n)*t.w])
+		t.fill(region[:-n*t.w])
+	}
+}
+
+func (t *vterm) fill(cells []cell) {
+	b := t.blank()
+	for i := range cells {
+		cells[i] = b
+	}
+}
+
+// csi carries out a control sequence with the given parameters and final
+// character.
+func (t *vterm) csi(params string, final rune) {
+	if strings.HasPrefix(params, "?") && (final == 'h' || final == 'l') {
+		for _, m := range parseParams(params[1:]) {
+			t.setMode(m, final == 'h')
+		}
+		return
+	}
+	if strings.HasPrefix(params, "?") || strings.HasPrefix(params, ">") {
+		return
+	}
+	args := parseParams(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+	row := t.cells[t.y*t.w : (t.y+1)*t.w]
+	t.wrapNext = false
+	switch final {
+	case 'A':
+		t.y = max(t.y-arg(0, 1), 0)
+	case 'B', 'e':
+		t.y = min(t.y+arg(0, 1), t.h-1)
+	case 'C', 'a':
+		t.x = min(t.x+arg(0, 1), t.w-1)
+	case 'D':
+		t.x = max(t.x-arg(0, 1), 0)
+	case 'E':
+		t.x, t.y = 0, min(t.y+arg(0, 1), t.h-1)
+	case 'F':
+		t.x, t.y = 0, max(t.y-arg(0, 1), 0)
+	case 'G', '`':
+		t.x = min(arg(0, 1), t.w) - 1
+	case 'd':
+		t.y = min(arg(0, 1), t.h) - 1
+	case 'H', 'f':
+		t.y = min(arg(0, 1), t.h) - 1
+		t.x = min(arg(1, 1), t.w) - 1
+	case 'J':
+		switch arg(0, 0) {
+		case 0:
+			t.fill(t.cells[t.y*t.w+t.x:])
+		case 1:
+			t.fill(t.cells[:t.y*t.w+t.x+1])
+		default:
+			t.fill(t.cells)
+		}
+	case 'K':
+		switch arg(0, 0) {
+		case 0:
+			t.fill(row[t.x:])
+		case 1:
+			t.fill(row[:t.x+1])
+		default:
+			t.fill(row)
+		}
+	case 'X':
+		t.fill(row[t.x:min(t.x+arg(0, 1), t.w)])
+	case 'P':
+		n := min(arg(0, 1), t.w-t.x)
+		copy(row[t.x:], row[t.x+n:])
+		t.fill(row[t.w-n:])
+	case '@':
+		n := min(arg(0, 1), t.w-t.x)
+		copy(row[t.x+n:], row[t.x:])
+		t.fill(row[t.x : t.x+n])
+	case 'L':
+		t.deleteLines(t.y, -arg(0, 1))
+	case 'M':
+		t.deleteLines(t.y, arg(0, 1))
+	case 'S':
+		t.scroll(arg(0, 1))
+	case 'T':
+		t.scroll(-arg(0, 1))
+	case 'r':
+		top, bottom := arg(0, 1)-1, min(arg(1, t.h), t.h)-1
+		if top < bottom {
+			t.top, t.bottom = top, bottom
+			t.x, t.y = 0, 0
+		}
+	case 's':
+		t.savedX, t.savedY = t.x, t.y
+	case 'u':
+		t.x, t.y = t.savedX, t.savedY
+	case 'm':
+		t.sgr(args)
+	}
+}
+
+// setMode sets or resets a private mode. The alternate screen modes switch
+// screens; the rest don't change the cells and are only remembered.
+func (t *vterm) setMode(m int, on bool) {
+	switch m {
+	case 47, 1047, 1049:
+		if on == (t.main != nil) {
+			return
+		}
+		if on {
+			if m == 1049 {
+				t.savedX, t.savedY = t.x, t.y
+			}
+			t.main = t.cells
+			t.cells = make([]cell, t.w*t.h)
+			t.fill(t.cells)
+		} else {
+			t.cells, t.main = t.main, nil
+			if m == 1049 {
+				t.x, t.y = t.savedX, t.savedY
+			}
+		}
+	default:
+		if t.modes == nil {
+			t.modes = map[int]bool{}
+		}
+		t.modes[m] = on
+	}
+}
+
+// repaint returns output that brings a terminal of the same size to the
+// state this one is in: its screen, cursor, scrolling region and modes.
+func (t *vterm) repaint() string {
+	var b strings.Builder
+	if t.main != nil {
+		b.WriteString("\x1b[?1049h")
+	}
+	b.WriteString("\x1b[r\x1b[0m\x1b[H\x1b[2J")
+	style := tcell.StyleDefault
+	for y := 0; y < t.h; y++ {
+		fmt.Fprintf(&b, "\x1b[%d;1H", y+1)
+		for x := 0; x < t.w; x++ {
+			c := t.at(x, y)
+			if c.width == 0 {
+				continue
+			}
+			if c.style != style {
+				b.WriteString(sgr(c.style))
+				style = c.style
+			}
+			b.WriteRune(c.r)
+			for _, r := range c.comb {
+				b.WriteRune(r)
+			}
+		}
+	}
+	modes := make([]int, 0, len(t.modes))
+	for m := range t.modes {
+		modes = append(modes, m)
+	}
+	sort.Ints(modes)
+	for _, m := range modes {
+		if t.modes[m] {
+			fmt.Fprintf(&b, "\x1b[?%dh", m)
+		} else {
+			fmt.Fprintf(&b, "\x1b[?%dl", m)
+		}
+	}
+	if t.top != 0 || t.bottom != t.h-1 {
+		fmt.Fprintf(&b, "\x1b[%d;%dr", t.top+1, t.bottom+1)
+	}
+	fmt.Fprintf(&b, "\x1b[%d;%dH%s", t.y+1, t.x+1, sgr(t.style))
+	return b.String()
+}
+
+// parseParams splits "1;2;3" into numbers, with missing ones as 0.
+func parseParams(params string) []int {
+	if params == "" {
+		return nil
+	}
+	fields := strings.FieldsFunc(params, func(r rune) bool { return r == ';' || r == ':' })
+	args := make([]int, len(fields))
+	for i, f := range fields {
+		args[i], _ = strconv.Atoi(f)
+	}
+	return args
+}
+
+// sgr sets the style for the text that follows.
+func (t *vterm) sgr(args []int) {
+	if len(args) == 0 {
+		args = []int{0}
+	}
+	s := t.style
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == 0:
+			s = tcell.StyleDefault
+		case a == 1:
+			s = s.Bold(true)
+		case a == 2:
+			s = s.Dim(true)
+		case a == 3:
+			s = s.Italic(true)
+		case a == 4:
+			s = s.Underline(true)
+		case a == 5:
+			s = s.Blink(true)
+		case a == 7:
+			s = s.Reverse(true)
+		case a == 9:
+			s = s.StrikeThrough(true)
+		case a == 22:
+			s = s.Bold(false).Dim(false)
+		case a == 23:
+			s = s.Italic(false)
+		case a == 24:
+			s = s.Underline(false)
+		case a == 25:
+			s = s.Blink(false)
+		case a == 27:
+			s = s.Reverse(false)
+		case a == 29:
+			s = s.StrikeThrough(false)
+		case a >= 30 && a <= 37:
+			s = s.Foreground(tcell.PaletteColor(a - 30))
+		case a >= 90 && a <= 97:
+			s = s.Foreground(tcell.PaletteColor(a - 90 + 8))
+		case a == 39:
+			s = s.Foreground(tcell.ColorDefault)
+		case a >= 40 && a <= 47:
+			s = s.Background(tcell.PaletteColor(a - 40))
+		case a >= 100 && a <= 107:
+			s = s.Background(tcell.PaletteColor(a - 100 + 8))
+		case a == 49:
+			s = s.Background(tcell.ColorDefault)
+		case a == 38 || a == 48:
+			var c tcell.Color
+			c, i = extendedColor(args, i)
+			if a == 38 {
+				s = s.Foreground(c)
+			} else {
+				s = s.Background(c)
+			}
+		}
+	}
+	t.style = s
+}
+
+// extendedColor reads a 38 or 48 color at args[i], as either 5;n from the
+// palette or 2;r;g;b. It returns the color and the index of its last
+// parameter.
+func extendedColor(args []int, i int) (tcell.Color, int) {
+	if i+2 < len(args) && args[i+1] == 5 {
+		return tcell.PaletteColor(args[i+2]), i + 2
+	}
+	if i+4 < len(args) && args[i+1] == 2 {
+		return tcell.NewRGBColor(int32(args[i+2]), int32(args[i+3]), int32(args[i+4])), i + 4
+	}
+	return tcell.ColorDefault, len(args)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// fileStamp is what watchFile compares to notice a file has changed.
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// watchFile polls path every interval and signals on the returned channel
+// when the file is created, removed or modified. Changes that happen before
+// the last one was picked up are folded together. It stops when stop is
+// closed.
+func watchFile(path string, interval time.Duration, stop <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := statFile(path)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if stamp := statFile(path); stamp != last {
+				last = stamp
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changed
+}